@@ -13,24 +13,38 @@ governing permissions and limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	_ "net/http/pprof"
 	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/adobe/butler/internal/config"
 	"github.com/adobe/butler/internal/environment"
 	"github.com/adobe/butler/internal/methods"
+	"github.com/adobe/butler/internal/metrics"
 	"github.com/adobe/butler/internal/monitor"
 
 	"github.com/jasonlvhit/gocron"
 	log "github.com/sirupsen/logrus"
 )
 
+// Exit codes for the validate and render subcommands
+const (
+	exitOK      = 0
+	exitFailed  = 1
+	exitNoInput = 2
+)
+
 const (
 	defaultButlerConfigInterval = 300
 	defaultHTTPRetryWaitMin     = 5
@@ -40,11 +54,8 @@ const (
 )
 
 var (
-	version        string
-	ConfigCache    map[string][]byte
-	AllConfigFiles []string
-	MustacheSubs   map[string]string
-	butlerTesting  = false
+	version       string
+	butlerTesting = false
 )
 
 func SetLogLevel(l string) log.Level {
@@ -67,10 +78,178 @@ func SetLogLevel(l string) log.Level {
 	}
 }
 
+// SetLogFormat returns the log formatter for the log format l. The log
+// formats are: text, json.
+func SetLogFormat(l string) log.Formatter {
+	switch strings.ToLower(l) {
+	case "text":
+		return &log.TextFormatter{FullTimestamp: true}
+	case "json":
+		return &log.JSONFormatter{}
+	default:
+		log.Warn(fmt.Sprintf("Unknown log format \"%s\". Defaulting to text", l))
+		return &log.TextFormatter{FullTimestamp: true}
+	}
+}
+
+// PrintPlan writes the human readable outcome of a dry run to w.
+func PrintPlan(w io.Writer, res *config.RunResult) {
+	var names []string
+	for name := range res.Managers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		mr := res.Managers[name]
+		switch {
+		case mr.Paused:
+			fmt.Fprintf(w, "manager %v: paused, skipped\n", name)
+			continue
+		case !mr.Success:
+			fmt.Fprintf(w, "manager %v: error: %v\n", name, mr.Error)
+		case mr.WouldReload:
+			fmt.Fprintf(w, "manager %v: would reload\n", name)
+		default:
+			fmt.Fprintf(w, "manager %v: no changes\n", name)
+		}
+
+		var repos []string
+		for repo := range mr.Files {
+			repos = append(repos, repo)
+		}
+		sort.Strings(repos)
+		for _, repo := range repos {
+			var files []string
+			for file := range mr.Files[repo] {
+				files = append(files, file)
+			}
+			sort.Strings(files)
+			for _, file := range files {
+				if f := mr.Files[repo][file]; !f.Success {
+					fmt.Fprintf(w, "  %v/%v: %v\n", repo, file, f.Error)
+				}
+			}
+		}
+
+		for _, change := range mr.Changes {
+			fmt.Fprintf(w, "  %v %v\n", change.Action, change.Path)
+			fmt.Fprint(w, change.Diff)
+		}
+	}
+}
+
+// RunValidate lints the butler configuration in data, writes the issues
+// found to w, and returns the exit code for the validate subcommand. Warnings
+// only fail the validation when strict is set.
+func RunValidate(w io.Writer, name string, data []byte, strict bool) int {
+	var errs, warns int
+	for _, i := range config.LintConfig(data) {
+		fmt.Fprintln(w, i)
+		if i.Level == config.LintError {
+			errs++
+		} else {
+			warns++
+		}
+	}
+	fmt.Fprintf(w, "%v: %v error(s), %v warning(s)\n", name, errs, warns)
+	if errs > 0 || (strict && warns > 0) {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// PrintRender writes the outcome of a render to w.
+func PrintRender(w io.Writer, res *config.RunResult) {
+	for name, mr := range res.Managers {
+		for _, change := range mr.Changes {
+			fmt.Fprintf(w, "%v %v\n", change.Action, change.Path)
+		}
+		if mr.Success {
+			continue
+		}
+
+		var repos []string
+		for repo := range mr.Files {
+			repos = append(repos, repo)
+		}
+		sort.Strings(repos)
+		for _, repo := range repos {
+			var files []string
+			for file := range mr.Files[repo] {
+				files = append(files, file)
+			}
+			sort.Strings(files)
+			for _, file := range files {
+				if f := mr.Files[repo][file]; !f.Success {
+					fmt.Fprintf(w, "  %v/%v: %v\n", repo, file, f.Error)
+				}
+			}
+		}
+		fmt.Fprintf(w, "manager %v: error: %v\n", name, mr.Error)
+	}
+}
+
+// mustacheSubsFlag collects the repeated -render.sub flags.
+type mustacheSubsFlag []string
+
+func (f *mustacheSubsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *mustacheSubsFlag) Set(v string) error {
+	if len(strings.Split(v, "=")) != 2 {
+		return fmt.Errorf("%v is not in the form of mustache=substitution", v)
+	}
+	*f = append(*f, v)
+	return nil
+}
+
+// runRender renders the files of manager into outDir, and returns the exit
+// code for the render subcommand.
+func runRender(bc *config.ButlerConfig, manager string, outDir string, subs []string) int {
+	if manager == "" {
+		fmt.Fprintf(os.Stderr, "usage: butler render [flags] <path or -config.path URL> <manager>\n")
+		return exitNoInput
+	}
+	// Like the dry run, rendering leaves the globals unapplied
+	if err := bc.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot retrieve butler configuration. err=%v\n", err)
+		return exitNoInput
+	}
+	overrides, _ := config.ParseMustacheSubs(subs)
+	res, err := bc.Render(manager, outDir, overrides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot render manager %v. err=%v\n", manager, err)
+		return exitNoInput
+	}
+	PrintRender(os.Stdout, res)
+	if !res.Success {
+		return exitFailed
+	}
+	return exitOK
+}
+
 func main() {
+	// "butler validate" lints the butler configuration, and "butler render"
+	// renders the files of a manager, rather than running the daemon. They
+	// take the same flags as the daemon.
+	butlerValidate := len(os.Args) > 1 && os.Args[1] == "validate"
+	butlerRender := len(os.Args) > 1 && os.Args[1] == "render"
+	if butlerValidate || butlerRender {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
+	var renderSubs mustacheSubsFlag
+	flag.Var(&renderSubs, "render.sub", "Override a mustache substitution of the manager when rendering, eg: -render.sub env=dev. May be repeated.")
+
 	var (
 		butlerTest                  = flag.Bool("test", false, "Are we testing butler? (probably not!)")
+		butlerRenderOut             = flag.String("render.out", "rendered", "The directory to write the rendered files of the manager to.")
+		butlerValidateStrict        = flag.Bool("validate.strict", false, "Treat warnings as errors when validating the butler configuration.")
+		butlerDryRun                = flag.Bool("dry-run", false, "Show the changes butler would make to the managed files, then exit. Nothing is written or reloaded.")
 		configEtcdEndpoints         = flag.String("etcd.endpoints", "", "The endpoints to connect to etcd.")
+		configEtcdWatch             = flag.Bool("etcd.watch", false, "Watch the etcd butler configuration for changes, rather than only polling for it.")
 		configBlobAccountKey        = flag.String("blob.account-key", "", "The Azure Blob storage account key (Should probably use the environment variable ACCOUNT_KEY).")
 		configBlobAccountName       = flag.String("blob.account-name", "", "The Azure Blob storage account name (Should probably use the environment variable ACCOUNT_NAME).")
 		configHTTPTimeout           = flag.String("http.timeout", fmt.Sprintf("%v", defaultHTTPTimeout), "The http timeout, in seconds, for GET requests to obtain the butler configuration file.")
@@ -78,23 +257,28 @@ func main() {
 		configHTTPRetryWaitMin      = flag.String("http.retry_wait_min", fmt.Sprintf("%v", defaultHTTPRetryWaitMin), "The minimum amount of time to wait before attemping to retry the http config get operation.")
 		configHTTPRetryWaitMax      = flag.String("http.retry_wait_max", fmt.Sprintf("%v", defaultHTTPRetryWaitMax), "The maximum amount of time to wait before attemping to retry the http config get operation.")
 		configHTTPAuthToken         = flag.String("http.auth_token", "", "HTTP auth token to use for HTTP authentication.")
-		configHTTPAuthType          = flag.String("http.auth_type", "", "HTTP auth type (eg: basic / digest / token-key) to use. If empty (by default) do not use HTTP authentication.")
+		configHTTPAuthType          = flag.String("http.auth_type", "", "HTTP auth type (eg: basic / bearer / digest / token-key) to use. If empty (by default) do not use HTTP authentication.")
 		configHTTPAuthUser          = flag.String("http.auth_user", "", "HTTP auth user to use for HTTP authentication")
 		configInterval              = flag.String("config.retrieve-interval", fmt.Sprintf("%v", defaultButlerConfigInterval), "The interval, in seconds, to retrieve new butler configuration files.")
+		configLogFormat             = flag.String("log.format", "text", "The butler log format. Log formats are: text, json.")
 		configLogLevel              = flag.String("log.level", "info", "The butler log level. Log levels are: debug, info, warn, error, fatal, panic.")
 		configPath                  = flag.String("config.path", "", "Full remote path to butler configuration file (eg: full URL scheme://path).")
 		configS3Region              = flag.String("s3.region", "", "The S3 Region that the config file resides.")
 		configS3AccessKeyID         = flag.String("s3.access-key-id", "", "The AWS Access Key ID (Should probably use environment variable AWS_ACCESS_KEY_ID).")
 		configS3SecretAccessKey     = flag.String("s3.secret-access-key", "", "The AWS Secret Access Key (Should probably use environment variable AWS_SECRET_ACCESS_KEY).")
 		configS3SessionToken        = flag.String("s3.session-token", "", "(Optional) The AWS Session Token (Should probably use environment variable AWS_SESSION_TOKEN).")
+		configTLSCAFile             = flag.String("tls.ca-file", "", "PEM bundle of the CAs to verify the etcd or https butler configuration server against.")
+		configTLSClientCert         = flag.String("tls.client-cert", "", "PEM client certificate to present to the etcd or https butler configuration server.")
+		configTLSClientKey          = flag.String("tls.client-key", "", "PEM key of the -tls.client-cert.")
 		configTLSInsecureSkipVerify = flag.Bool("tls.insecure-skip-verify", false, "Disable SSL verification for etcd and https.")
+		configTLSServerName         = flag.String("tls.server-name", "", "Server name to verify the etcd or https butler configuration server certificate against, and to send as SNI.")
 		err                         error
 		versionFlag                 = flag.Bool("version", false, "Print version information.")
 	)
 	flag.Parse()
 	newConfigLogLevel := environment.GetVar(*configLogLevel)
 	log.SetLevel(SetLogLevel(newConfigLogLevel))
-	log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	log.SetFormatter(SetLogFormat(environment.GetVar(*configLogFormat)))
 
 	if *versionFlag {
 		fmt.Fprintf(os.Stdout, "butler %s\n", version)
@@ -108,21 +292,62 @@ func main() {
 		butlerTesting = true
 	}
 
+	// The configuration to validate or render may also be given as an
+	// argument, which may be a local path. The manager to render is always
+	// the last argument.
+	args := flag.Args()
+	renderManager := ""
+	if butlerRender && len(args) > 0 {
+		renderManager = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+	if (butlerValidate || butlerRender) && len(args) > 0 {
+		*configPath = args[0]
+		if !strings.Contains(*configPath, "://") {
+			abs, err := filepath.Abs(*configPath)
+			if err != nil {
+				log.Fatalf("Cannot find butler configuration %v. err=%v", *configPath, err)
+			}
+			*configPath = fmt.Sprintf("file://%v", abs)
+		}
+	}
+
 	if *configPath == "" {
+		if butlerValidate {
+			fmt.Fprintf(os.Stderr, "usage: butler validate [flags] <path or -config.path URL>\n")
+			os.Exit(exitNoInput)
+		}
+		if butlerRender {
+			fmt.Fprintf(os.Stderr, "usage: butler render [flags] <path or -config.path URL> <manager>\n")
+			os.Exit(exitNoInput)
+		}
 		log.Fatal("You must provide a -config.path for a path to the butler configuration.")
 	}
 
 	log.Infof("Starting Butler CMS version %s", version)
+	metrics.SetButlerBuildInfo(version)
 
 	newURL, err := url.Parse(environment.GetVar(*configPath))
 	if err != nil || newURL.Scheme == "" {
 		log.Fatalf("Cannot properly parse -config.path. -config.path must be in URL form. -config.path=%v", environment.GetVar(*configPath))
 	}
 
+	// The root context is done on SIGTERM or SIGINT, which abandons the run in
+	// flight, and shuts butler down.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
 	opts := &config.ButlerConfigOpts{
+		Context:            ctx,
 		InsecureSkipVerify: *configTLSInsecureSkipVerify,
 		LogLevel:           SetLogLevel(newConfigLogLevel),
-		URL:                newURL,
+		TLSOpts: methods.TLSOpts{
+			CAFile:     environment.GetVar(*configTLSCAFile),
+			ClientCert: environment.GetVar(*configTLSClientCert),
+			ClientKey:  environment.GetVar(*configTLSClientKey),
+			ServerName: environment.GetVar(*configTLSServerName),
+		},
+		URL: newURL,
 	}
 	bc, err := config.NewButlerConfig(opts)
 	if err != nil {
@@ -134,12 +359,11 @@ func main() {
 		opts := methods.HTTPMethodOpts{Scheme: bc.Scheme()}
 		newConfigHTTPAuthType := strings.ToLower(environment.GetVar(*configHTTPAuthType))
 		if newConfigHTTPAuthType != "" {
-			if environment.GetVar(*configHTTPAuthUser) != "" && environment.GetVar(*configHTTPAuthToken) != "" {
-			} else {
+			if environment.GetVar(*configHTTPAuthToken) == "" || (newConfigHTTPAuthType != "bearer" && environment.GetVar(*configHTTPAuthUser) == "") {
 				log.Fatalf("HTTP Authentication enabled, but insufficient authentication details provided.")
 			}
 			switch newConfigHTTPAuthType {
-			case "basic", "digest", "token-key":
+			case "basic", "bearer", "digest", "token-key":
 				opts.HTTPAuthType = newConfigHTTPAuthType
 				opts.HTTPAuthToken = *configHTTPAuthToken
 				opts.HTTPAuthUser = *configHTTPAuthUser
@@ -235,6 +459,7 @@ func main() {
 		newConfigEtcdEndpoints := environment.GetVar(*configEtcdEndpoints)
 		log.Debugf("main(): setting etcd endpoints=%v", newConfigEtcdEndpoints)
 		opts.Endpoints = strings.Split(newConfigEtcdEndpoints, ",")
+		opts.Watch = *configEtcdWatch
 		bc.SetMethodOpts(opts)
 	case "file":
 		opts := methods.FileMethodOpts{Scheme: bc.Scheme()}
@@ -257,6 +482,37 @@ func main() {
 		log.Fatalf("Cannot initialize butler config. err=%s", err.Error())
 	}
 
+	if butlerValidate {
+		data, err := bc.Fetch()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot retrieve butler configuration. err=%v\n", err)
+			os.Exit(exitNoInput)
+		}
+		os.Exit(RunValidate(os.Stdout, bc.URL().String(), data, *butlerValidateStrict))
+	}
+
+	if butlerRender {
+		os.Exit(runRender(bc, renderManager, *butlerRenderOut, renderSubs))
+	}
+
+	// In dry run mode, we only report what would change, and leave everything
+	// as is. The globals are left unapplied, so that no audit log, notifier,
+	// exporter or watch is set up either.
+	if *butlerDryRun {
+		if err := bc.Load(); err != nil {
+			log.Fatalf("Cannot retrieve butler configuration. err=%s", err.Error())
+		}
+		res, err := bc.Plan()
+		if err != nil {
+			log.Fatalf("Cannot do butler dry run. err=%s", err.Error())
+		}
+		PrintPlan(os.Stdout, res)
+		if !res.Success {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Do initial grab of butler configuration file.
 	// Going to do this in an endless loop until we initially
 	// grab a configuration file.
@@ -270,7 +526,13 @@ func main() {
 				log.Fatalf("Cannot retrieve butler configuration. err=%s butlerTesting=%#v", err.Error(), butlerTesting)
 			}
 			log.Warnf("main(): Sleeping 5 seconds.")
-			time.Sleep(5 * time.Second)
+			select {
+			case <-ctx.Done():
+				log.Infof("main(): caught signal. exiting.")
+				bc.Shutdown()
+				os.Exit(0)
+			case <-time.After(5 * time.Second):
+			}
 		} else {
 			log.Infof("main(): Loaded initial butler configuration.")
 			break
@@ -281,6 +543,9 @@ func main() {
 	monitor := monitor.NewMonitor().WithOpts(&monitor.Opts{Config: bc, Version: version})
 	monitor.Start()
 
+	// Changes to the listener in the globals restart the monitor
+	bc.OnGlobalsChange(monitor.GlobalsChanged)
+
 	sched := gocron.NewScheduler()
 	log.Debugf("main(): starting scheduler...")
 
@@ -293,9 +558,25 @@ func main() {
 	log.Debugf("main(): doing initial run of butler configuration management handler")
 	bc.RunCMHandler()
 
+	// If the butler configuration supports it, pick up upstream changes as
+	// they happen. The scheduler above remains as a safety net.
+	bc.StartConfigWatch()
+
 	if butlerTesting {
 		os.Exit(0)
-	} else {
-		<-sched.Start()
 	}
+
+	stopped := sched.Start()
+	<-ctx.Done()
+	stop()
+	log.Infof("main(): caught signal. shutting down.")
+
+	// Stop scheduling new runs, then wait for the run in flight to finish or
+	// abort before cleaning up.
+	stopped <- true
+	if err := monitor.Stop(); err != nil {
+		log.Errorf("main(): could not stop the monitor. err=%v", err)
+	}
+	bc.Shutdown()
+	log.Infof("main(): butler has shut down.")
 }
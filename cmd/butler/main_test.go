@@ -15,8 +15,12 @@ package main
 import (
 	. "gopkg.in/check.v1"
 
+	"bytes"
+	"errors"
 	"testing"
 
+	"github.com/adobe/butler/internal/config"
+
 	log "github.com/sirupsen/logrus"
 )
 
@@ -50,3 +54,79 @@ func (s *ButlerTestSuite) TestSetLogLevel(c *C) {
 		c.Assert(logLevel, Equals, entry.level)
 	}
 }
+
+func (s *ButlerTestSuite) TestSetLogFormat(c *C) {
+	_, ok := SetLogFormat("json").(*log.JSONFormatter)
+	c.Assert(ok, Equals, true)
+	_, ok = SetLogFormat("JSON").(*log.JSONFormatter)
+	c.Assert(ok, Equals, true)
+	_, ok = SetLogFormat("text").(*log.TextFormatter)
+	c.Assert(ok, Equals, true)
+	_, ok = SetLogFormat("breakme!").(*log.TextFormatter)
+	c.Assert(ok, Equals, true)
+}
+
+func (s *ButlerTestSuite) TestPrintPlan(c *C) {
+	res := config.NewRunResult(1)
+	res.DryRun = true
+	res.AddManager("alertmanager").Paused = true
+	res.AddManager("consul")
+	prom := res.AddManager("prometheus")
+	prom.WouldReload = true
+	prom.Changes = []config.FileChange{
+		{Path: "/opt/prometheus/prometheus.yml", Action: "update", Diff: "--- a\n+++ b\n@@ -1,1 +1,1 @@\n-old\n+new\n"},
+		{Path: "/opt/prometheus/stale.yml", Action: "remove"},
+	}
+	broken := res.AddManager("broken")
+	rfe := &config.RepoFileEvent{Success: map[string]bool{}, Error: map[string]error{}, TmpFile: map[string]string{}}
+	rfe.SetFailure("alerts.yml", errors.New("could not validate file"))
+	broken.AddFiles(&config.ConfigChanEvent{Repo: map[string]*config.RepoFileEvent{"repo.domain.com": rfe}})
+	broken.SetError("could not retrieve all configuration files")
+
+	var buf bytes.Buffer
+	PrintPlan(&buf, res)
+	c.Assert(buf.String(), Equals, `manager alertmanager: paused, skipped
+manager broken: error: could not retrieve all configuration files
+  repo.domain.com/alerts.yml: could not validate file
+manager consul: no changes
+manager prometheus: would reload
+  update /opt/prometheus/prometheus.yml
+--- a
++++ b
+@@ -1,1 +1,1 @@
+-old
++new
+  remove /opt/prometheus/stale.yml
+`)
+}
+
+func (s *ButlerTestSuite) TestRunValidate(c *C) {
+	var out bytes.Buffer
+	c.Assert(RunValidate(&out, "butler.toml", []byte("#butlerstart\n[globals]\n  config-managers = [\"foo\"]\n#butlerend\n"), false), Equals, exitFailed)
+	c.Assert(out.String(), Equals, "error: foo: is listed in globals.config-managers, but there is no [foo] section\nbutler.toml: 1 error(s), 0 warning(s)\n")
+
+	// warnings only fail the validation in strict mode
+	warn := []byte("#butlerstart\n[globals]\n  config-managers = [\"foo\"]\n[foo]\n  repos = [\"repo\"]\n  dest-path = \"" + c.MkDir() + "\"\n  primary-config-name = \"foo.yml\"\n[foo.repo]\n  method = \"file\"\n  primary-config = [\"foo.yml\"]\n[foo.repo.file]\n  path = \"/butler\"\n[bar]\n  repos = []\n#butlerend\n")
+	out.Reset()
+	c.Assert(RunValidate(&out, "butler.toml", warn, false), Equals, exitOK)
+	c.Check(out.String(), Matches, "(?s).*butler.toml: 0 error\\(s\\), [1-9] warning\\(s\\)\n")
+	out.Reset()
+	c.Assert(RunValidate(&out, "butler.toml", warn, true), Equals, exitFailed)
+}
+
+func (s *ButlerTestSuite) TestPrintRender(c *C) {
+	res := config.NewRunResult(1)
+	mr := res.AddManager("prometheus")
+	mr.Changes = []config.FileChange{{Path: "rendered/prometheus.yml", Action: "render"}}
+
+	var out bytes.Buffer
+	PrintRender(&out, res)
+	c.Assert(out.String(), Equals, "render rendered/prometheus.yml\n")
+
+	mr.Changes = nil
+	mr.Files = map[string]map[string]config.FileResult{"repo.domain.com": {"prometheus.yml": {Error: "could not download file"}}}
+	mr.SetError("could not retrieve all configuration files")
+	out.Reset()
+	PrintRender(&out, res)
+	c.Assert(out.String(), Equals, "  repo.domain.com/prometheus.yml: could not download file\nmanager prometheus: error: could not retrieve all configuration files\n")
+}
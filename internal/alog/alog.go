@@ -13,9 +13,13 @@ governing permissions and limitations under the License.
 package alog
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/adobe/butler/internal/config"
@@ -25,29 +29,128 @@ import (
 
 const (
 	ApacheFormatPattern = "%s - - [%s] \"%s %d %d\" %f\n"
+	// ButlerTimeFormat is the time format of the butler format.
+	ButlerTimeFormat = "02/Jan/2006 03:04:05"
+	// CommonFormatPattern is the Apache Common Log Format.
+	CommonFormatPattern = "%s - %s [%s] \"%s\" %d %s\n"
+	// CombinedFormatPattern is the Apache Combined Log Format, which is the
+	// Common Log Format along with the referer and user agent.
+	CombinedFormatPattern = "%s - %s [%s] \"%s\" %d %s \"%s\" \"%s\"\n"
+	// CommonTimeFormat is the time format of the Common and Combined Log
+	// Formats.
+	CommonTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+	// The access log formats of the globals.http-log-format.
+	FormatButler   = "butler"
+	FormatCommon   = "common"
+	FormatCombined = "combined"
+	FormatJSON     = "json"
 )
 
 type ApacheLogRecord struct {
 	http.ResponseWriter
 	log                   bool
+	format                string
+	out                   io.Writer
 	ip                    string
+	user                  string
 	time                  time.Time
 	method, uri, protocol string
+	referer, userAgent    string
 	status                int
 	responseBytes         int64
 	elapsedTime           time.Duration
 }
 
-func (r *ApacheLogRecord) Log() {
-	timeFormatted := r.time.Format("02/Jan/2006 03:04:05")
+// accessLogEntry is an access log record in the json format.
+type accessLogEntry struct {
+	Time      string  `json:"time"`
+	IP        string  `json:"ip"`
+	User      string  `json:"user,omitempty"`
+	Method    string  `json:"method"`
+	URI       string  `json:"uri"`
+	Protocol  string  `json:"protocol"`
+	Status    int     `json:"status"`
+	Bytes     int64   `json:"bytes"`
+	Duration  float64 `json:"duration-seconds"`
+	Referer   string  `json:"referer,omitempty"`
+	UserAgent string  `json:"user-agent,omitempty"`
+}
+
+// dash returns s, or "-" when it is empty, as the Apache log formats do.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Format returns the record in its access log format, without a trailing
+// newline.
+func (r *ApacheLogRecord) Format() string {
 	requestLine := fmt.Sprintf("%s %s %s", r.method, r.uri, r.protocol)
-	msg := fmt.Sprintf(ApacheFormatPattern, r.ip, timeFormatted, requestLine, r.status, r.responseBytes,
-		r.elapsedTime.Seconds())
-	if r.log {
-		log.Infof(strings.TrimSpace(msg))
+	bytes := "-"
+	if r.responseBytes > 0 {
+		bytes = fmt.Sprintf("%d", r.responseBytes)
+	}
+
+	var msg string
+	switch r.format {
+	case FormatCommon:
+		msg = fmt.Sprintf(CommonFormatPattern, r.ip, dash(r.user), r.time.Format(CommonTimeFormat), requestLine, r.status, bytes)
+	case FormatCombined:
+		msg = fmt.Sprintf(CombinedFormatPattern, r.ip, dash(r.user), r.time.Format(CommonTimeFormat), requestLine, r.status, bytes,
+			dash(r.referer), dash(r.userAgent))
+	case FormatJSON:
+		data, _ := json.Marshal(r.entry())
+		msg = string(data)
+	default:
+		msg = fmt.Sprintf(ApacheFormatPattern, r.ip, r.time.Format(ButlerTimeFormat), requestLine, r.status, r.responseBytes,
+			r.elapsedTime.Seconds())
+	}
+	return strings.TrimSpace(msg)
+}
+
+func (r *ApacheLogRecord) entry() accessLogEntry {
+	return accessLogEntry{
+		Time:      r.time.Format(time.RFC3339Nano),
+		IP:        r.ip,
+		User:      r.user,
+		Method:    r.method,
+		URI:       r.uri,
+		Protocol:  r.protocol,
+		Status:    r.status,
+		Bytes:     r.responseBytes,
+		Duration:  r.elapsedTime.Seconds(),
+		Referer:   r.referer,
+		UserAgent: r.userAgent,
 	}
 }
 
+// Log writes the record to its access log file, if it has one, or else to
+// the butler log. The json format goes to the butler log as fields, so that
+// it fits in with the -log.format.
+func (r *ApacheLogRecord) Log() {
+	if !r.log {
+		return
+	}
+	if r.out != nil {
+		if _, err := fmt.Fprintln(r.out, r.Format()); err != nil {
+			log.Errorf("ApacheLogRecord::Log(): could not write access log. err=%v", err)
+		}
+		return
+	}
+	if r.format == FormatJSON {
+		e := r.entry()
+		log.WithFields(log.Fields{
+			"ip": e.IP, "user": e.User, "method": e.Method, "uri": e.URI, "protocol": e.Protocol, "status": e.Status,
+			"bytes": e.Bytes, "duration-seconds": e.Duration, "referer": e.Referer, "user-agent": e.UserAgent,
+		}).Info("access")
+		return
+	}
+	log.Info(r.Format())
+}
+
 func (r *ApacheLogRecord) Write(p []byte) (int, error) {
 	written, err := r.ResponseWriter.Write(p)
 	r.responseBytes += int64(written)
@@ -62,6 +165,11 @@ func (r *ApacheLogRecord) WriteHeader(status int) {
 type ApacheLoggingHandler struct {
 	handler http.Handler
 	config  *config.ButlerConfig
+	// lock guards the access log file, which is reopened whenever the
+	// http-log-file changes.
+	lock sync.Mutex
+	path string
+	file *os.File
 }
 
 func NewApacheLoggingHandler(handler http.Handler, config *config.ButlerConfig) http.Handler {
@@ -71,29 +179,83 @@ func NewApacheLoggingHandler(handler http.Handler, config *config.ButlerConfig)
 	}
 }
 
+// output returns the access log file at path, opening it if need be, or nil
+// to log to the butler log.
+func (h *ApacheLoggingHandler) output(path string) io.Writer {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if path != h.path {
+		if h.file != nil {
+			h.file.Close()
+			h.file = nil
+		}
+		h.path = path
+		if path != "" {
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+			if err != nil {
+				log.Errorf("ApacheLoggingHandler::output(): could not open access log %v. logging to the butler log. err=%v", path, err)
+			} else {
+				h.file = f
+			}
+		}
+	}
+	if h.file == nil {
+		return nil
+	}
+	return h.file
+}
+
+// Close closes the access log file, if there is one.
+func (h *ApacheLoggingHandler) Close() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.path = ""
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
+
 func (h *ApacheLoggingHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	clientIP := r.RemoteAddr
 	if colon := strings.LastIndex(clientIP, ":"); colon != -1 {
 		clientIP = clientIP[:colon]
 	}
+	user, _, _ := r.BasicAuth()
+	startTime := time.Now()
 
+	g := h.config.Config.Globals
 	record := &ApacheLogRecord{
 		ResponseWriter: rw,
-		log:            h.config.Config.Globals.EnableHTTPLog,
+		log:            g.EnableHTTPLog,
+		format:         g.HTTPLogFormat,
 		ip:             clientIP,
-		time:           time.Time{},
+		user:           user,
+		time:           startTime.UTC(),
 		method:         r.Method,
 		uri:            r.RequestURI,
 		protocol:       r.Proto,
+		referer:        r.Referer(),
+		userAgent:      r.UserAgent(),
 		status:         http.StatusOK,
 		elapsedTime:    time.Duration(0),
 	}
+	if record.log {
+		record.out = h.output(g.HTTPLogFile)
+	}
 
-	startTime := time.Now()
 	h.handler.ServeHTTP(record, r)
 	finishTime := time.Now()
 
-	record.time = finishTime.UTC()
+	// The Common, Combined and JSON formats have the time the request was
+	// received, as Apache does, and the butler format the time it finished.
+	switch record.format {
+	case FormatCommon, FormatCombined, FormatJSON:
+	default:
+		record.time = finishTime.UTC()
+	}
 	record.elapsedTime = finishTime.Sub(startTime)
 
 	record.Log()
@@ -14,9 +14,12 @@ package alog
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -228,3 +231,131 @@ func (s *AlogTestSuite) TestApacheFormatPattern(c *C) {
 	// Verify the format pattern is correct
 	c.Assert(ApacheFormatPattern, Equals, "%s - - [%s] \"%s %d %d\" %f\n")
 }
+
+// testRecord returns a record of a request from a client which passed a user,
+// referer and user agent.
+func testRecord(format string) *ApacheLogRecord {
+	return &ApacheLogRecord{
+		log:           true,
+		format:        format,
+		ip:            "192.168.1.1",
+		user:          "ops",
+		time:          time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		method:        "GET",
+		uri:           "/metrics",
+		protocol:      "HTTP/1.1",
+		referer:       "http://grafana/",
+		userAgent:     "Prometheus/2.45.0",
+		status:        http.StatusOK,
+		responseBytes: 256,
+		elapsedTime:   time.Millisecond * 100,
+	}
+}
+
+func (s *AlogTestSuite) TestApacheLogRecordFormat(c *C) {
+	c.Assert(testRecord(FormatButler).Format(), Equals, `192.168.1.1 - - [15/Jan/2024 10:30:00] "GET /metrics HTTP/1.1 200 256" 0.100000`)
+	c.Assert(testRecord("").Format(), Equals, testRecord(FormatButler).Format())
+	c.Assert(testRecord(FormatCommon).Format(), Equals, `192.168.1.1 - ops [15/Jan/2024:10:30:00 +0000] "GET /metrics HTTP/1.1" 200 256`)
+	c.Assert(testRecord(FormatCombined).Format(), Equals, `192.168.1.1 - ops [15/Jan/2024:10:30:00 +0000] "GET /metrics HTTP/1.1" 200 256 "http://grafana/" "Prometheus/2.45.0"`)
+
+	// The missing fields are dashes
+	r := testRecord(FormatCombined)
+	r.user, r.referer, r.userAgent, r.responseBytes = "", "", "", 0
+	c.Assert(r.Format(), Equals, `192.168.1.1 - - [15/Jan/2024:10:30:00 +0000] "GET /metrics HTTP/1.1" 200 - "-" "-"`)
+
+	var e map[string]interface{}
+	c.Assert(json.Unmarshal([]byte(testRecord(FormatJSON).Format()), &e), IsNil)
+	c.Assert(e, DeepEquals, map[string]interface{}{
+		"time": "2024-01-15T10:30:00Z", "ip": "192.168.1.1", "user": "ops", "method": "GET", "uri": "/metrics",
+		"protocol": "HTTP/1.1", "status": float64(200), "bytes": float64(256), "duration-seconds": 0.1,
+		"referer": "http://grafana/", "user-agent": "Prometheus/2.45.0",
+	})
+}
+
+func (s *AlogTestSuite) TestApacheLogRecordLogOut(c *C) {
+	var out bytes.Buffer
+	r := testRecord(FormatCommon)
+	r.out = &out
+	r.Log()
+	c.Assert(out.String(), Equals, r.Format()+"\n")
+
+	// Nothing is written with logging disabled
+	out.Reset()
+	r.log = false
+	r.Log()
+	c.Assert(out.String(), Equals, "")
+}
+
+func (s *AlogTestSuite) TestApacheLoggingHandlerLogFile(c *C) {
+	innerHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+
+	u, _ := url.Parse("http://localhost")
+	bc, _ := config.NewButlerConfig(&config.ButlerConfigOpts{URL: u})
+	bc.Config = config.NewConfigSettings()
+	bc.Config.Globals.EnableHTTPLog = true
+	bc.Config.Globals.HTTPLogFormat = FormatCombined
+	bc.Config.Globals.HTTPLogFile = c.MkDir() + "/access.log"
+
+	loggingHandler := NewApacheLoggingHandler(innerHandler, bc)
+	defer loggingHandler.(*ApacheLoggingHandler).Close()
+	serve := func() {
+		req := httptest.NewRequest("GET", "/health-check", nil)
+		req.RemoteAddr = "10.0.0.1:8080"
+		req.SetBasicAuth("ops", "secret")
+		req.Header.Set("Referer", "http://grafana/")
+		req.Header.Set("User-Agent", "curl/8.0")
+		loggingHandler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	serve()
+	serve()
+
+	data, err := os.ReadFile(bc.Config.Globals.HTTPLogFile)
+	c.Assert(err, IsNil)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	c.Assert(lines, HasLen, 2)
+	c.Assert(lines[0], Matches, `10\.0\.0\.1 - ops \[.*\] "GET /health-check HTTP/1\.1" 200 2 "http://grafana/" "curl/8\.0"`)
+	c.Assert(lines[0], Matches, `.* \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} \+0000\] .*`)
+
+	// The handler moves on to the new file when the http-log-file changes
+	prev := bc.Config.Globals.HTTPLogFile
+	bc.Config.Globals.HTTPLogFile = c.MkDir() + "/access.log"
+	serve()
+	data, err = os.ReadFile(bc.Config.Globals.HTTPLogFile)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Count(string(data), "\n"), Equals, 1)
+	data, err = os.ReadFile(prev)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Count(string(data), "\n"), Equals, 2)
+}
+
+func (s *AlogTestSuite) TestApacheLoggingHandlerRequestTime(c *C) {
+	var called time.Time
+	innerHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = time.Now()
+		time.Sleep(10 * time.Millisecond)
+	})
+
+	u, _ := url.Parse("http://localhost")
+	bc, _ := config.NewButlerConfig(&config.ButlerConfigOpts{URL: u})
+	bc.Config = config.NewConfigSettings()
+	bc.Config.Globals.EnableHTTPLog = true
+	bc.Config.Globals.HTTPLogFormat = FormatJSON
+	bc.Config.Globals.HTTPLogFile = c.MkDir() + "/access.log"
+
+	loggingHandler := NewApacheLoggingHandler(innerHandler, bc)
+	defer loggingHandler.(*ApacheLoggingHandler).Close()
+	before := time.Now()
+	loggingHandler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health-check", nil))
+
+	// The time is when the request was received, not when it finished
+	data, err := os.ReadFile(bc.Config.Globals.HTTPLogFile)
+	c.Assert(err, IsNil)
+	var e accessLogEntry
+	c.Assert(json.Unmarshal(data, &e), IsNil)
+	t, err := time.Parse(time.RFC3339Nano, e.Time)
+	c.Assert(err, IsNil)
+	c.Assert(t.Before(before), Equals, false)
+	c.Assert(t.After(called), Equals, false)
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+// Package audit writes an append-only log of the changes butler applies, as
+// one JSON record per line.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ActionWrite is a file which butler wrote.
+	ActionWrite = "write"
+	// ActionRemove is a file which butler removed.
+	ActionRemove = "remove"
+
+	// ReloadSuccess and the others are the outcomes of a reload.
+	ReloadSuccess  = "success"
+	ReloadFailure  = "failure"
+	ReloadDeferred = "deferred"
+	ReloadHeldBack = "held-back"
+	ReloadSkipped  = "skipped"
+	// ReloadTimeout is a reload which timed out, with manager-timeout-ok set.
+	ReloadTimeout = "timeout"
+
+	// DefaultMaxSize is the size, in megabytes, at which the log is rotated.
+	DefaultMaxSize = 100
+	// DefaultMaxBackups is the number of rotated logs which are kept.
+	DefaultMaxBackups = 5
+)
+
+// Record is what happened to a single manager during a single run.
+type Record struct {
+	Time       time.Time `json:"time"`
+	Host       string    `json:"host"`
+	Count      int       `json:"count"`
+	ConfigHash string    `json:"config-hash"`
+	Manager    string    `json:"manager"`
+	Success    bool      `json:"success"`
+	Paused     bool      `json:"paused,omitempty"`
+	Files      []File    `json:"files"`
+	Reload     *Reload   `json:"reload,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// File is a file which butler wrote or removed. OldSha256 is empty for a new
+// file, and NewSha256 and Bytes are empty for a removed file.
+type File struct {
+	Path      string   `json:"path"`
+	Action    string   `json:"action"`
+	OldSha256 string   `json:"old-sha256,omitempty"`
+	NewSha256 string   `json:"new-sha256,omitempty"`
+	Bytes     int64    `json:"bytes"`
+	Sources   []Source `json:"sources,omitempty"`
+}
+
+// Source is where a file came from. The primary configuration file is merged
+// from the files of every repository of the manager, so it has several.
+type Source struct {
+	Repo string `json:"repo"`
+	URL  string `json:"url"`
+}
+
+// Reload is the outcome of the reload of a manager, and how long it took.
+type Reload struct {
+	Outcome  string  `json:"outcome"`
+	Duration float64 `json:"duration-seconds"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Log is an append-only JSON-lines log, which is rotated once it grows past
+// MaxSize bytes. The last MaxBackups rotated logs are kept as Path.1,
+// Path.2 and so on, Path.1 being the most recent.
+type Log struct {
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+	host       string
+	lock       sync.Mutex
+	file       *os.File
+	size       int64
+}
+
+// New opens the log at path for appending, creating it, and its directory,
+// if need be. maxSize is in megabytes. Defaults are used for a maxSize or
+// maxBackups of 0.
+func New(path string, maxSize int, maxBackups int) (*Log, error) {
+	if path == "" {
+		return nil, fmt.Errorf("no audit log path has been defined")
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = DefaultMaxBackups
+	}
+	l := &Log{Path: path, MaxSize: int64(maxSize) * 1024 * 1024, MaxBackups: maxBackups}
+	l.host, _ = os.Hostname()
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+// Write appends r to the log, rotating it first if r would take it past its
+// maximum size. It is safe to call on a nil Log, which does nothing.
+func (l *Log) Write(r Record) error {
+	if l == nil {
+		return nil
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if r.Host == "" {
+		r.Host = l.host
+	}
+	if r.Files == nil {
+		r.Files = []File{}
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.file == nil {
+		return fmt.Errorf("audit log %v is closed", l.Path)
+	}
+	if l.size > 0 && l.size+int64(len(data)) > l.MaxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(data)
+	l.size += int64(n)
+	return err
+}
+
+// rotate moves the current log to Path.1, after shifting the older rotated
+// logs along and dropping the oldest, and starts a new one.
+func (l *Log) rotate() error {
+	log.Debugf("Log::rotate(): rotating audit log %v.", l.Path)
+	l.file.Close()
+	l.file = nil
+	os.Remove(fmt.Sprintf("%v.%d", l.Path, l.MaxBackups))
+	for i := l.MaxBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%v.%d", l.Path, i), fmt.Sprintf("%v.%d", l.Path, i+1))
+	}
+	if err := os.Rename(l.Path, l.Path+".1"); err != nil {
+		log.Errorf("Log::rotate(): could not rotate audit log %v. err=%v", l.Path, err)
+	}
+	return l.open()
+}
+
+// Close closes the log. It is safe to call on a nil Log.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
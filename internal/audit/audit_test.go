@@ -0,0 +1,111 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&AuditTestSuite{})
+
+type AuditTestSuite struct{}
+
+// readRecords returns the records in the log at path.
+func readRecords(c *C, path string) []Record {
+	f, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	var res []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		c.Assert(json.Unmarshal(scanner.Bytes(), &r), IsNil)
+		res = append(res, r)
+	}
+	return res
+}
+
+func (s *AuditTestSuite) TestNew(c *C) {
+	_, err := New("", 0, 0)
+	c.Assert(err, NotNil)
+
+	path := c.MkDir() + "/audit/butler.log"
+	l, err := New(path, 0, 0)
+	c.Assert(err, IsNil)
+	defer l.Close()
+	c.Assert(l.MaxSize, Equals, int64(DefaultMaxSize*1024*1024))
+	c.Assert(l.MaxBackups, Equals, DefaultMaxBackups)
+	_, err = os.Stat(path)
+	c.Assert(err, IsNil)
+}
+
+func (s *AuditTestSuite) TestWrite(c *C) {
+	// A nil log does nothing
+	var l *Log
+	c.Assert(l.Write(Record{Manager: "prometheus"}), IsNil)
+	c.Assert(l.Close(), IsNil)
+
+	path := c.MkDir() + "/butler.log"
+	l, err := New(path, 0, 0)
+	c.Assert(err, IsNil)
+	c.Assert(l.Write(Record{Count: 1, Manager: "alertmanager", Success: true}), IsNil)
+	c.Assert(l.Write(Record{Count: 1, Manager: "prometheus", Success: true,
+		Files:  []File{{Path: "/opt/prometheus/prometheus.yml", Action: ActionWrite, NewSha256: "abc", Bytes: 12, Sources: []Source{{Repo: "repo1", URL: "http://repo1/prometheus.yml"}}}},
+		Reload: &Reload{Outcome: ReloadSuccess, Duration: 0.5}}), IsNil)
+	c.Assert(l.Close(), IsNil)
+	c.Assert(l.Write(Record{}), NotNil)
+
+	// The log is appended to when it is opened again
+	l, err = New(path, 0, 0)
+	c.Assert(err, IsNil)
+	c.Assert(l.Write(Record{Count: 2, Manager: "prometheus"}), IsNil)
+	l.Close()
+
+	records := readRecords(c, path)
+	c.Assert(records, HasLen, 3)
+	c.Assert(records[0].Manager, Equals, "alertmanager")
+	c.Assert(records[0].Files, HasLen, 0)
+	c.Assert(records[0].Reload, IsNil)
+	c.Assert(records[0].Time.IsZero(), Equals, false)
+	hostname, _ := os.Hostname()
+	c.Assert(records[0].Host, Equals, hostname)
+	c.Assert(records[1].Files[0].Sources[0].URL, Equals, "http://repo1/prometheus.yml")
+	c.Assert(records[1].Reload.Outcome, Equals, ReloadSuccess)
+	c.Assert(records[2].Count, Equals, 2)
+}
+
+func (s *AuditTestSuite) TestRotate(c *C) {
+	path := c.MkDir() + "/butler.log"
+	l, err := New(path, 1, 2)
+	c.Assert(err, IsNil)
+	defer l.Close()
+	// Every record goes to a log of its own
+	l.MaxSize = 10
+	for i := 0; i < 4; i++ {
+		c.Assert(l.Write(Record{Count: i}), IsNil)
+	}
+
+	c.Assert(readRecords(c, path)[0].Count, Equals, 3)
+	c.Assert(readRecords(c, path+".1")[0].Count, Equals, 2)
+	c.Assert(readRecords(c, path+".2")[0].Count, Equals, 1)
+	_, err = os.Stat(path + ".3")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
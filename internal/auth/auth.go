@@ -0,0 +1,179 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+// Package auth provides the authentication shared by the http retrieval
+// methods and the http reloader.
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExpiryDelta is how long before its expiry an oauth2 token is refreshed.
+var ExpiryDelta = 30 * time.Second
+
+// Authorizer returns the value of the Authorization header of a request.
+type Authorizer interface {
+	Authorization(ctx context.Context) (string, error)
+}
+
+// Basic is http basic authentication.
+type Basic struct {
+	User     string
+	Password string
+}
+
+func NewBasic(user string, password string) (*Basic, error) {
+	if user == "" || password == "" {
+		return nil, errors.New("basic auth needs auth-user and auth-token")
+	}
+	return &Basic{User: user, Password: password}, nil
+}
+
+func (b *Basic) Authorization(ctx context.Context) (string, error) {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(b.User+":"+b.Password)), nil
+}
+
+// Bearer is a static bearer token, or a token which is read from a file on
+// every request, so that rotated tokens (eg: projected service account
+// tokens) are picked up.
+type Bearer struct {
+	Token string
+	File  string
+}
+
+func NewBearer(token string, file string) (*Bearer, error) {
+	if (token == "") == (file == "") {
+		return nil, errors.New("bearer auth needs exactly one of auth-token and auth-token-file")
+	}
+	return &Bearer{Token: token, File: file}, nil
+}
+
+func (b *Bearer) Authorization(ctx context.Context) (string, error) {
+	token := b.Token
+	if b.File != "" {
+		data, err := os.ReadFile(b.File)
+		if err != nil {
+			return "", fmt.Errorf("could not read auth-token-file %v. err=%v", b.File, err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		return "", errors.New("bearer token is empty")
+	}
+	return "Bearer " + token, nil
+}
+
+// ClientCredentials fetches bearer tokens from an oauth2 token endpoint using
+// the client credentials grant. Tokens are cached until shortly before they
+// expire.
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Client is used to talk to the token endpoint.
+	Client *http.Client
+
+	lock   sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func NewClientCredentials(tokenURL string, clientID string, clientSecret string, scopes []string, client *http.Client) (*ClientCredentials, error) {
+	if tokenURL == "" || clientID == "" || clientSecret == "" {
+		return nil, errors.New("oauth2-client-credentials auth needs token-url, client-id and client-secret")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &ClientCredentials{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		Client:       client,
+	}, nil
+}
+
+func (c *ClientCredentials) Authorization(ctx context.Context) (string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.token != "" && time.Now().Add(ExpiryDelta).Before(c.expiry) {
+		return "Bearer " + c.token, nil
+	}
+
+	token, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token = token.AccessToken
+	// Tokens without an expiry are not cached.
+	c.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return "Bearer " + c.token, nil
+}
+
+func (c *ClientCredentials) fetch(ctx context.Context) (*tokenResponse, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch oauth2 token. err=%v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("could not read oauth2 token. err=%v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oauth2 token endpoint returned http_code=%d", resp.StatusCode)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("could not parse oauth2 token. err=%v", err)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("oauth2 token endpoint returned no access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return nil, fmt.Errorf("unsupported oauth2 token_type %v", token.TokenType)
+	}
+	return &token, nil
+}
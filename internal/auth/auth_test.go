@@ -0,0 +1,147 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&AuthTestSuite{})
+
+type AuthTestSuite struct{}
+
+func (s *AuthTestSuite) TestBasic(c *C) {
+	_, err := NewBasic("jenkins", "")
+	c.Assert(err, NotNil)
+	b, err := NewBasic("testing", "testing")
+	c.Assert(err, IsNil)
+	authorization, err := b.Authorization(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(authorization, Equals, "Basic dGVzdGluZzp0ZXN0aW5n")
+}
+
+func (s *AuthTestSuite) TestBearer(c *C) {
+	_, err := NewBearer("", "")
+	c.Assert(err, NotNil)
+	_, err = NewBearer("token", "/some/file")
+	c.Assert(err, NotNil)
+
+	b, err := NewBearer("token", "")
+	c.Assert(err, IsNil)
+	authorization, err := b.Authorization(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(authorization, Equals, "Bearer token")
+}
+
+func (s *AuthTestSuite) TestBearerFile(c *C) {
+	file := c.MkDir() + "/token"
+	c.Assert(os.WriteFile(file, []byte("first\n"), 0600), IsNil)
+	b, err := NewBearer("", file)
+	c.Assert(err, IsNil)
+	authorization, err := b.Authorization(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(authorization, Equals, "Bearer first")
+
+	// The file is read again on every request
+	c.Assert(os.WriteFile(file, []byte("second"), 0600), IsNil)
+	authorization, err = b.Authorization(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(authorization, Equals, "Bearer second")
+
+	c.Assert(os.WriteFile(file, []byte(""), 0600), IsNil)
+	_, err = b.Authorization(context.Background())
+	c.Assert(err, ErrorMatches, "bearer token is empty")
+	c.Assert(os.Remove(file), IsNil)
+	_, err = b.Authorization(context.Background())
+	c.Assert(err, ErrorMatches, "could not read auth-token-file .*")
+}
+
+// tokenServer is an oauth2 token endpoint which hands out numbered tokens,
+// valid for expiresIn seconds.
+func tokenServer(c *C, expiresIn int, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "butler" || pass != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.FormValue("grant_type"), Equals, "client_credentials")
+		c.Check(r.FormValue("scope"), Equals, "read reload")
+		*requests++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, *requests, expiresIn)
+	}))
+}
+
+func (s *AuthTestSuite) TestClientCredentials(c *C) {
+	_, err := NewClientCredentials("", "butler", "s3cr3t", nil, nil)
+	c.Assert(err, NotNil)
+
+	requests := 0
+	server := tokenServer(c, 3600, &requests)
+	defer server.Close()
+	cc, err := NewClientCredentials(server.URL, "butler", "s3cr3t", []string{"read", "reload"}, nil)
+	c.Assert(err, IsNil)
+
+	for i := 0; i < 3; i++ {
+		authorization, err := cc.Authorization(context.Background())
+		c.Assert(err, IsNil)
+		c.Assert(authorization, Equals, "Bearer token-1")
+	}
+	c.Assert(requests, Equals, 1)
+}
+
+func (s *AuthTestSuite) TestClientCredentialsRefresh(c *C) {
+	requests := 0
+	// Tokens which expire within ExpiryDelta are refreshed straight away
+	server := tokenServer(c, 10, &requests)
+	defer server.Close()
+	cc, err := NewClientCredentials(server.URL, "butler", "s3cr3t", []string{"read", "reload"}, nil)
+	c.Assert(err, IsNil)
+
+	authorization, err := cc.Authorization(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(authorization, Equals, "Bearer token-1")
+	authorization, err = cc.Authorization(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(authorization, Equals, "Bearer token-2")
+}
+
+func (s *AuthTestSuite) TestClientCredentialsErrors(c *C) {
+	requests := 0
+	server := tokenServer(c, 3600, &requests)
+	defer server.Close()
+	cc, err := NewClientCredentials(server.URL, "butler", "wrong", nil, nil)
+	c.Assert(err, IsNil)
+	_, err = cc.Authorization(context.Background())
+	c.Assert(err, ErrorMatches, "oauth2 token endpoint returned http_code=401")
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token":"abc","token_type":"mac"}`)
+	}))
+	defer bad.Close()
+	cc, err = NewClientCredentials(bad.URL, "butler", "s3cr3t", nil, nil)
+	c.Assert(err, IsNil)
+	_, err = cc.Authorization(context.Background())
+	c.Assert(err, ErrorMatches, "unsupported oauth2 token_type mac")
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sort"
+
+	"github.com/adobe/butler/internal/audit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// getAuditLog returns the audit log for the globals.audit-log. It is nil when
+// there is none.
+func (bc *ButlerConfig) getAuditLog() *audit.Log {
+	bc.globalsLock.Lock()
+	defer bc.globalsLock.Unlock()
+	return bc.auditLog
+}
+
+// setAuditLog replaces the audit log with the one of the globals, closing the
+// previous one.
+func (bc *ButlerConfig) setAuditLog(g ConfigGlobals) {
+	var l *audit.Log
+	if g.AuditLog != "" {
+		var err error
+		l, err = audit.New(g.AuditLog, g.AuditLogMaxSize, g.AuditLogMaxBackups)
+		if err != nil {
+			log.Errorf("ButlerConfig::setAuditLog(): could not open audit log %v. err=%v", g.AuditLog, err)
+		}
+	}
+	bc.globalsLock.Lock()
+	prev := bc.auditLog
+	bc.auditLog = l
+	bc.globalsLock.Unlock()
+	prev.Close()
+}
+
+// writeAudit records the outcome of the run for each of its managers in the
+// audit log, if there is one.
+func (bc *ButlerConfig) writeAudit(result *RunResult) {
+	l := bc.getAuditLog()
+	if l == nil {
+		return
+	}
+	sum := sha256.Sum256(bc.RawConfig)
+	hash := hex.EncodeToString(sum[:])
+
+	var names []string
+	for name := range result.Managers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mr := result.Managers[name]
+		r := audit.Record{
+			Count:      result.Count,
+			ConfigHash: hash,
+			Manager:    name,
+			Success:    mr.Success,
+			Paused:     mr.Paused,
+			Files:      mr.applied,
+			Reload:     mr.reload,
+			Error:      mr.Error,
+		}
+		if err := l.Write(r); err != nil {
+			logEntry(result.Count, name).Errorf("Config::RunCMHandler(): could not write to audit log %v. err=%v", l.Path, err)
+		}
+	}
+}
+
+// fileSources returns the repositories, and urls, which each of the local
+// configuration files of the manager come from.
+func (bm *Manager) fileSources() map[string][]audit.Source {
+	res := make(map[string][]audit.Source)
+	var keys []string
+	for k := range bm.ManagerOpts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		o := bm.ManagerOpts[k]
+		for _, f := range o.PrimaryConfigsFullLocalPaths {
+			for _, u := range o.PrimaryConfigsFullURLs {
+				res[f] = append(res[f], audit.Source{Repo: o.Repo, URL: u})
+			}
+		}
+		for i, f := range o.AdditionalConfigsFullLocalPaths {
+			if i < len(o.AdditionalConfigsFullURLs) {
+				res[f] = append(res[f], audit.Source{Repo: o.Repo, URL: o.AdditionalConfigsFullURLs[i]})
+			}
+		}
+	}
+	return res
+}
+
+// auditFiles returns the files whose hashes differ between before and after,
+// in the order of their names, as written or removed.
+func auditFiles(before map[string]string, after map[string]string, sources map[string][]audit.Source) []audit.File {
+	var res []audit.File
+	for _, f := range changedFiles(before, after) {
+		af := audit.File{Path: f.Path, Action: audit.ActionWrite, OldSha256: before[f.Path], NewSha256: f.Hash, Sources: sources[f.Path]}
+		if info, err := os.Stat(f.Path); err == nil {
+			af.Bytes = info.Size()
+		} else {
+			af.Action = audit.ActionRemove
+		}
+		res = append(res, af)
+	}
+	return res
+}
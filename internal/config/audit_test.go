@@ -0,0 +1,128 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adobe/butler/internal/audit"
+
+	. "gopkg.in/check.v1"
+)
+
+// auditRecords returns the records in the audit log at path.
+func auditRecords(c *C, path string) []audit.Record {
+	f, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	var res []audit.Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r audit.Record
+		c.Assert(json.Unmarshal(scanner.Bytes(), &r), IsNil)
+		res = append(res, r)
+	}
+	return res
+}
+
+func (s *ConfigTestSuite) TestParseConfigAuditLog(c *C) {
+	config := fmt.Sprintf(TestLintConfig, c.MkDir())
+	cs := NewConfigSettings()
+	c.Assert(cs.ParseConfig([]byte(config)), IsNil)
+	c.Assert(cs.Globals.AuditLog, Equals, "")
+	c.Assert(cs.Globals.AuditLogMaxSize, Equals, audit.DefaultMaxSize)
+	c.Assert(cs.Globals.AuditLogMaxBackups, Equals, audit.DefaultMaxBackups)
+
+	config = strings.Replace(config, `  scheduler-interval = "300"`, `  scheduler-interval = "300"
+  audit-log = "/var/log/butler/audit.log"
+  audit-log-max-size = "10"
+  audit-log-max-backups = "3"`, 1)
+	c.Assert(cs.ParseConfig([]byte(config)), IsNil)
+	c.Assert(cs.Globals.AuditLog, Equals, "/var/log/butler/audit.log")
+	c.Assert(cs.Globals.AuditLogMaxSize, Equals, 10)
+	c.Assert(cs.Globals.AuditLogMaxBackups, Equals, 3)
+	c.Assert(lintIssues([]byte(config)), HasLen, 0)
+}
+
+func (s *ConfigTestSuite) TestRunAuditLog(c *C) {
+	repo, dest := c.MkDir(), c.MkDir()
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {}\n#butlerend\n"), 0644), IsNil)
+	config := strings.Replace(fmt.Sprintf(TestLintConfig, dest), "/butler", repo, -1)
+	config = strings.Replace(config, "repo1.domain.com", "localhost", -1)
+	bc := &ButlerConfig{Config: NewConfigSettings(), RawConfig: []byte(config)}
+	c.Assert(bc.Config.ParseConfig([]byte(config)), IsNil)
+	bc.Config.Globals.StatusFile = c.MkDir() + "/status"
+	bc.Config.Globals.AuditLog = c.MkDir() + "/audit.log"
+	bc.setAuditLog(bc.Config.Globals)
+	defer bc.getAuditLog().Close()
+	m := bc.GetManager("prometheus")
+	m.Reloader = &testReloader{}
+	m.CleanFiles = true
+	c.Assert(os.WriteFile(dest+"/stale.yml", []byte("stale\n"), 0644), IsNil)
+	staleHash, _ := ComputeFileHash(dest + "/stale.yml")
+
+	// The first run writes the primary configuration file, and removes the
+	// file which butler does not know about
+	_, err := bc.Run()
+	c.Assert(err, IsNil)
+	records := auditRecords(c, bc.Config.Globals.AuditLog)
+	c.Assert(records, HasLen, 1)
+	r := records[0]
+	sum := sha256.Sum256([]byte(config))
+	c.Assert(r.ConfigHash, Equals, hex.EncodeToString(sum[:]))
+	c.Assert(r.Manager, Equals, "prometheus")
+	c.Assert(r.Success, Equals, true)
+	c.Assert(r.Files, HasLen, 2)
+	c.Assert(r.Files[0], DeepEquals, audit.File{Path: dest + "/stale.yml", Action: audit.ActionRemove, OldSha256: staleHash})
+	newHash, _ := ComputeFileHash(dest + "/prometheus.yml")
+	c.Assert(r.Files[1].Path, Equals, dest+"/prometheus.yml")
+	c.Assert(r.Files[1].Action, Equals, audit.ActionWrite)
+	c.Assert(r.Files[1].OldSha256, Equals, "")
+	c.Assert(r.Files[1].NewSha256, Equals, newHash)
+	c.Assert(r.Files[1].Bytes > 0, Equals, true)
+	c.Assert(r.Files[1].Sources, DeepEquals, []audit.Source{{Repo: "localhost", URL: "file://localhost/" + strings.TrimPrefix(repo, "/") + "/prometheus.yml"}})
+	c.Assert(r.Reload.Outcome, Equals, audit.ReloadSuccess)
+
+	// Nothing changes on the second run
+	_, err = bc.Run()
+	c.Assert(err, IsNil)
+	records = auditRecords(c, bc.Config.Globals.AuditLog)
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[1].Count, Equals, records[0].Count+1)
+	c.Assert(records[1].Files, HasLen, 0)
+	c.Assert(records[1].Reload, IsNil)
+
+	// The update is recorded along with the hash it replaced, and the failed
+	// reload
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {scrape_interval: 30s}\n#butlerend\n"), 0644), IsNil)
+	m.Reloader = &testReloader{err: fmt.Errorf("connection refused")}
+	_, err = bc.Run()
+	c.Assert(err, IsNil)
+	records = auditRecords(c, bc.Config.Globals.AuditLog)
+	c.Assert(records, HasLen, 3)
+	r = records[2]
+	c.Assert(r.Success, Equals, false)
+	c.Assert(r.Files, HasLen, 1)
+	c.Assert(r.Files[0].OldSha256, Equals, newHash)
+	c.Assert(r.Files[0].NewSha256, Not(Equals), newHash)
+	c.Assert(r.Reload.Outcome, Equals, audit.ReloadFailure)
+	c.Assert(r.Reload.Error, Equals, "connection refused")
+	c.Assert(r.Error, Equals, "connection refused")
+}
@@ -30,10 +30,17 @@ type ChanEvent interface {
 	CanCopyFiles() bool
 	CleanTmpFiles() error
 	GetTmpFileMap() []TmpFile
+	GetRepoFileEvents() map[string]*RepoFileEvent
 	SetSuccess(string, string, error) error
 	SetTmpFile(string, string, string) error
 	CopyPrimaryConfigFiles(map[string]*ManagerOpts) bool
 	CopyAdditionalConfigFiles(string) bool
+	// Dry run methods - report the changes which would be made without writing files
+	PlanPrimaryConfigFiles(map[string]*ManagerOpts) ([]FileChange, error)
+	PlanAdditionalConfigFiles(string) ([]FileChange, error)
+	// Render methods - write the files whether or not they have changed
+	RenderPrimaryConfigFiles(map[string]*ManagerOpts) (string, error)
+	RenderAdditionalConfigFiles(string) ([]string, error)
 	// Watch-only mode methods - compare hashes without writing files
 	ComparePrimaryConfigHashes(map[string]*ManagerOpts, map[string]string) (bool, map[string]string)
 	CompareAdditionalConfigHashes(map[string]string) (bool, map[string]string)
@@ -47,6 +54,7 @@ type ConfigChanEvent struct {
 	TmpFile    *os.File
 	ConfigFile *string
 	Manager    string
+	Count      int
 	Repo       map[string]*RepoFileEvent
 }
 
@@ -119,6 +127,12 @@ func (c *ConfigChanEvent) GetTmpFileMap() []TmpFile {
 	return res
 }
 
+// GetRepoFileEvents returns the per repository file events, which hold
+// whether or not each of the files was successfully processed.
+func (c *ConfigChanEvent) GetRepoFileEvents() map[string]*RepoFileEvent {
+	return c.Repo
+}
+
 // SetSuccess sets the value for the file argument in the repo argument to true
 func (c *ConfigChanEvent) SetSuccess(repo string, file string, err error) error {
 	// If c.Repo has not been initialized, do so.
@@ -161,6 +175,15 @@ func (c *ConfigChanEvent) SetTmpFile(repo string, file string, tmpfile string) e
 }
 
 func (c *ConfigChanEvent) CopyPrimaryConfigFiles(opts map[string]*ManagerOpts) bool {
+	if !c.mergePrimaryConfigFiles(opts) {
+		return false
+	}
+	return CompareAndCopy(c.TmpFile.Name(), *c.ConfigFile, c.Count, c.Manager)
+}
+
+// mergePrimaryConfigFiles merges the primary config files, in order, into
+// the temporary file for the primary configuration.
+func (c *ConfigChanEvent) mergePrimaryConfigFiles(opts map[string]*ManagerOpts) bool {
 	var (
 		primaryConfigs []string
 	)
@@ -207,7 +230,7 @@ func (c *ConfigChanEvent) CopyPrimaryConfigFiles(opts map[string]*ManagerOpts) b
 	}
 	out.Sync()
 	out.Close()
-	return CompareAndCopy(c.TmpFile.Name(), *c.ConfigFile, c.Manager)
+	return true
 }
 
 func (c *ConfigChanEvent) CopyAdditionalConfigFiles(destDir string) bool {
@@ -219,13 +242,67 @@ func (c *ConfigChanEvent) CopyAdditionalConfigFiles(destDir string) bool {
 
 	for _, f := range c.GetTmpFileMap() {
 		destFile := fmt.Sprintf("%s/%s", destDir, f.Name)
-		if CompareAndCopy(f.File, destFile, c.Manager) {
+		if CompareAndCopy(f.File, destFile, c.Count, c.Manager) {
 			IsModified = true
 		}
 	}
 	return IsModified
 }
 
+// PlanPrimaryConfigFiles merges the primary config files, and returns the
+// change which copying the result into place would make. Nothing is written
+// to the destination.
+func (c *ConfigChanEvent) PlanPrimaryConfigFiles(opts map[string]*ManagerOpts) ([]FileChange, error) {
+	if !c.mergePrimaryConfigFiles(opts) {
+		return nil, fmt.Errorf("could not merge %v", *c.ConfigFile)
+	}
+	change, err := planFileChange(c.TmpFile.Name(), *c.ConfigFile)
+	if err != nil || change == nil {
+		return nil, err
+	}
+	return []FileChange{*change}, nil
+}
+
+// PlanAdditionalConfigFiles returns the changes which copying the additional
+// config files into destDir would make. Nothing is written to destDir.
+func (c *ConfigChanEvent) PlanAdditionalConfigFiles(destDir string) ([]FileChange, error) {
+	var changes []FileChange
+	for _, f := range c.GetTmpFileMap() {
+		change, err := planFileChange(f.File, fmt.Sprintf("%s/%s", destDir, f.Name))
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	return changes, nil
+}
+
+// RenderPrimaryConfigFiles merges the primary config files, and writes the
+// result into place, whether or not it has changed. It returns the path of
+// the primary configuration.
+func (c *ConfigChanEvent) RenderPrimaryConfigFiles(opts map[string]*ManagerOpts) (string, error) {
+	if !c.mergePrimaryConfigFiles(opts) {
+		return "", fmt.Errorf("could not merge %v", *c.ConfigFile)
+	}
+	return *c.ConfigFile, renderFile(c.TmpFile.Name(), *c.ConfigFile)
+}
+
+// RenderAdditionalConfigFiles writes the additional config files into
+// destDir, whether or not they have changed. It returns their paths.
+func (c *ConfigChanEvent) RenderAdditionalConfigFiles(destDir string) ([]string, error) {
+	var paths []string
+	for _, f := range c.GetTmpFileMap() {
+		dest := fmt.Sprintf("%s/%s", destDir, f.Name)
+		if err := renderFile(f.File, dest); err != nil {
+			return paths, err
+		}
+		paths = append(paths, dest)
+	}
+	return paths, nil
+}
+
 // ComparePrimaryConfigHashes compares hashes of primary config files without writing to disk.
 // This is used in watch-only mode. Returns true if any file has changed, along with updated hashes.
 func (c *ConfigChanEvent) ComparePrimaryConfigHashes(opts map[string]*ManagerOpts, storedHashes map[string]string) (bool, map[string]string) {
@@ -254,7 +331,7 @@ func (c *ConfigChanEvent) ComparePrimaryConfigHashes(opts map[string]*ManagerOpt
 				hashKey := fmt.Sprintf("primary:%s", f)
 				storedHash := storedHashes[hashKey]
 
-				changed, newHash, err := CompareHashOnly(t.File, storedHash, c.Manager)
+				changed, newHash, err := CompareHashOnly(t.File, storedHash, c.Count, c.Manager)
 				if err != nil {
 					log.Errorf("ConfigChanEvent::ComparePrimaryConfigHashes(): error computing hash for %s: %v", f, err)
 					continue
@@ -290,7 +367,7 @@ func (c *ConfigChanEvent) CompareAdditionalConfigHashes(storedHashes map[string]
 		hashKey := fmt.Sprintf("additional:%s", f.Name)
 		storedHash := storedHashes[hashKey]
 
-		changed, newHash, err := CompareHashOnly(f.File, storedHash, c.Manager)
+		changed, newHash, err := CompareHashOnly(f.File, storedHash, c.Count, c.Manager)
 		if err != nil {
 			log.Errorf("ConfigChanEvent::CompareAdditionalConfigHashes(): error computing hash for %s: %v", f.Name, err)
 			continue
@@ -14,6 +14,7 @@ package config
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -21,8 +22,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/adobe/butler/internal/audit"
 	"github.com/adobe/butler/internal/environment"
 	"github.com/adobe/butler/internal/methods"
+	"github.com/adobe/butler/internal/notifier"
+	"github.com/adobe/butler/internal/tracing"
 
 	"github.com/hashicorp/go-retryablehttp"
 	log "github.com/sirupsen/logrus"
@@ -44,9 +48,15 @@ const (
 )
 
 type ButlerConfigOpts struct {
+	// Context is the root context of butler. Runs in flight are abandoned
+	// when it is done.
+	Context            context.Context
 	InsecureSkipVerify bool
 	LogLevel           log.Level
-	URL                *url.URL
+	// TLSOpts are the client TLS options used to retrieve the butler
+	// configuration over https or etcd.
+	TLSOpts methods.TLSOpts
+	URL     *url.URL
 }
 
 type ConfigClient struct {
@@ -85,13 +95,15 @@ func (c *ConfigClient) SetRetryWaitMax(val int) {
 	}
 }
 
-func (c *ConfigClient) Get(val *url.URL) (*methods.Response, error) {
+func (c *ConfigClient) Get(ctx context.Context, val *url.URL) (*methods.Response, error) {
 	var (
 		response *methods.Response
 		err      error
 	)
 	if IsValidScheme(val.Scheme) {
-		response, err = c.Method.Get(val)
+		ctx, span := tracing.Start(ctx, "Method.Get", tracing.MethodKey.String(c.Scheme), tracing.URLKey.String(val.Redacted()))
+		response, err = c.Method.Get(ctx, val)
+		tracing.End(span, err)
 	} else {
 		response = &methods.Response{}
 		err = errors.New("unsupported scheme")
@@ -106,21 +118,23 @@ func (c *ConfigSettings) ParseConfig(config []byte) error {
 		path    string
 	)
 	log.Debugf("ConfigSettings::ParseConfig(): entering.")
-	// The  configuration is in TOML format
-	viper.SetConfigType("toml")
+	// The  configuration is in TOML format. Each parse gets its own viper
+	// instance, so that several configurations may be parsed side by side.
+	v := viper.New()
+	v.SetConfigType("toml")
 
 	// We grab the config from a remote repo so it's in []byte format. let's see
 	// if we can process it.
-	err := viper.ReadConfig(bytes.NewBuffer(config))
+	err := v.ReadConfig(bytes.NewBuffer(config))
 	if err != nil {
 		log.Debugf("ConfigSettings::ParseConfig(): could not parse config. err=%v", err)
 		return err
 	}
 
-	Config = ConfigSettings{}
+	Config = ConfigSettings{source: v}
 
 	// Let's start piecing together the globals
-	err = viper.UnmarshalKey("globals", &Globals)
+	err = v.UnmarshalKey("globals", &Globals)
 	if err != nil {
 		log.Fatalf("Unable to decode into struct, %v", err)
 	}
@@ -161,6 +175,22 @@ func (c *ConfigSettings) ParseConfig(config []byte) error {
 		// enable http logging
 	}
 
+	// The http log goes to the butler log unless http-log-file is set
+	Config.Globals.HTTPLogFile = environment.GetVar(Config.Globals.CfgHTTPLogFile)
+	Config.Globals.HTTPLogFormat = strings.ToLower(environment.GetVar(Config.Globals.CfgHTTPLogFormat))
+	switch Config.Globals.HTTPLogFormat {
+	case "":
+		Config.Globals.HTTPLogFormat = "butler"
+	case "butler", "common", "combined", "json":
+	default:
+		if Config.Globals.ExitOnFailure {
+			log.Fatalf("ConfigSettings::ParseConfig(): globals.http-log-format=%v is not a valid http log format! exiting...", Config.Globals.HTTPLogFormat)
+		} else {
+			log.Debugf("ConfigSettings::ParseConfig(): globals.http-log-format=%v is not a valid http log format", Config.Globals.HTTPLogFormat)
+			return fmt.Errorf("globals.http-log-format=%v is not a valid http log format", Config.Globals.HTTPLogFormat)
+		}
+	}
+
 	// Let's determine the http proto and the port
 	envHTTPPort, _ := strconv.Atoi(environment.GetVar(Config.Globals.CfgHTTPPort))
 	if envHTTPPort == 0 {
@@ -177,6 +207,7 @@ func (c *ConfigSettings) ParseConfig(config []byte) error {
 	if Config.Globals.HTTPProto == "https" {
 		Config.Globals.HTTPTLSCert = environment.GetVar(Config.Globals.CfgHTTPTLSCert)
 		Config.Globals.HTTPTLSKey = environment.GetVar(Config.Globals.CfgHTTPTLSKey)
+		Config.Globals.HTTPTLSClientCA = environment.GetVar(Config.Globals.CfgHTTPTLSClientCA)
 		if (Config.Globals.HTTPTLSCert == "") || (Config.Globals.HTTPTLSKey == "") {
 			if Config.Globals.ExitOnFailure {
 				log.Fatalf("ConfigSetings::ParseConfig(): globlals.http-proto set to \"https\" but no cert and/or key defined! exiting...")
@@ -187,6 +218,58 @@ func (c *ConfigSettings) ParseConfig(config []byte) error {
 		}
 	}
 
+	// The admin api is only enabled when there is a token to authenticate against
+	Config.Globals.AdminToken = environment.GetVar(Config.Globals.CfgAdminToken)
+
+	// The log level overrides the -log.level flag when it is set
+	Config.Globals.LogLevel = strings.ToLower(environment.GetVar(Config.Globals.CfgLogLevel))
+	if Config.Globals.LogLevel != "" {
+		if _, err := log.ParseLevel(Config.Globals.LogLevel); err != nil {
+			if Config.Globals.ExitOnFailure {
+				log.Fatalf("ConfigSettings::ParseConfig(): globals.log-level=%v is not a valid log level! exiting...", Config.Globals.LogLevel)
+			} else {
+				log.Debugf("ConfigSettings::ParseConfig(): globals.log-level=%v is not a valid log level", Config.Globals.LogLevel)
+				return fmt.Errorf("globals.log-level=%v is not a valid log level", Config.Globals.LogLevel)
+			}
+		}
+	}
+
+	// The audit log is rotated once it grows past audit-log-max-size megabytes
+	Config.Globals.AuditLog = environment.GetVar(Config.Globals.CfgAuditLog)
+	Config.Globals.AuditLogMaxSize, _ = strconv.Atoi(environment.GetVar(Config.Globals.CfgAuditLogMaxSize))
+	if Config.Globals.AuditLogMaxSize <= 0 {
+		Config.Globals.AuditLogMaxSize = audit.DefaultMaxSize
+	}
+	Config.Globals.AuditLogMaxBackups, _ = strconv.Atoi(environment.GetVar(Config.Globals.CfgAuditLogMaxBackups))
+	if Config.Globals.AuditLogMaxBackups <= 0 {
+		Config.Globals.AuditLogMaxBackups = audit.DefaultMaxBackups
+	}
+
+	// Spans are exported over OTLP once there is a tracing-endpoint
+	Config.Globals.TracingEndpoint = environment.GetVar(Config.Globals.CfgTracingEndpoint)
+	Config.Globals.TracingInsecure = strings.ToLower(environment.GetVar(Config.Globals.CfgTracingInsecure)) == "true"
+	Config.Globals.TracingProtocol = strings.ToLower(environment.GetVar(Config.Globals.CfgTracingProtocol))
+	if Config.Globals.TracingProtocol == "" {
+		Config.Globals.TracingProtocol = tracing.ProtocolGRPC
+	}
+	if !tracing.IsValidProtocol(Config.Globals.TracingProtocol) {
+		if Config.Globals.ExitOnFailure {
+			log.Fatalf("ConfigSettings::ParseConfig(): globals.tracing-protocol=%v is not a valid tracing protocol! exiting...", Config.Globals.TracingProtocol)
+		} else {
+			log.Debugf("ConfigSettings::ParseConfig(): globals.tracing-protocol=%v is not a valid tracing protocol", Config.Globals.TracingProtocol)
+			return fmt.Errorf("globals.tracing-protocol=%v is not a valid tracing protocol", Config.Globals.TracingProtocol)
+		}
+	}
+
+	if _, err := notifier.New(Config.Globals.Notifiers); err != nil {
+		if Config.Globals.ExitOnFailure {
+			log.Fatalf("ConfigSettings::ParseConfig(): globals.notifiers: %v! exiting...", err)
+		} else {
+			log.Debugf("ConfigSettings::ParseConfig(): globals.notifiers: %v", err)
+			return fmt.Errorf("globals.notifiers: %v", err)
+		}
+	}
+
 	// If there are no entries for config-managers, then the Unmarshal will create an empty array
 	if len(Config.Globals.Managers) < 1 {
 		if Config.Globals.ExitOnFailure {
@@ -201,7 +284,7 @@ func (c *ConfigSettings) ParseConfig(config []byte) error {
 	// Now let's start processing the managers. This is going
 	for _, entry := range Config.Globals.Managers {
 		log.Debugf("ConfigSettings::ParseConfig(): checking config entry=%s", entry)
-		if !viper.IsSet(entry) {
+		if !v.IsSet(entry) {
 			if Config.Globals.ExitOnFailure {
 				log.Fatalf("ConfigSettings::ParseConfig(): %v is not in the configuration as a manager! exiting...", entry)
 			} else {
@@ -223,9 +306,19 @@ func (c *ConfigSettings) ParseConfig(config []byte) error {
 		}
 	}
 
+	if err := checkDependencies(Config.Managers); err != nil {
+		if Config.Globals.ExitOnFailure {
+			log.Fatalf("ConfigSettings::ParseConfig(): %v! exiting...", err.Error())
+		} else {
+			log.Debugf("ConfigSettings::ParseConfig(): %v", err.Error())
+			return err
+		}
+	}
+
 	// Set the values in the config structure
 	c.Managers = Config.Managers
 	c.Globals = Config.Globals
+	c.source = Config.source
 
 	// Let's get the path arrays dialed in
 	for _, m := range c.Managers {
@@ -20,12 +20,14 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/adobe/butler/internal/methods"
 	"github.com/adobe/butler/internal/reloaders"
 
 	"github.com/bouk/monkey"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -313,6 +315,28 @@ func (s *ConfigTestSuite) TestNewConfigClientHttp(c *C) {
 	log.Debugf("c1=%#v c2=%#v\n", c1, c2)
 }
 
+func (s *ConfigTestSuite) TestNewConfigClientTLS(c *C) {
+	u, err := url.Parse("https://localhost")
+	c.Assert(err, IsNil)
+	opts := &ButlerConfigOpts{
+		InsecureSkipVerify: true,
+		LogLevel:           log.DebugLevel,
+		TLSOpts:            methods.TLSOpts{ServerName: "config.example.com"},
+		URL:                u}
+	bc, err := NewButlerConfig(opts)
+	c.Assert(err, IsNil)
+	bc.SetMethodOpts(methods.HTTPMethodOpts{Scheme: u.Scheme})
+	client, err := NewConfigClient(bc)
+	c.Assert(err, IsNil)
+	m := client.Method.(methods.HTTPMethod)
+	c.Assert(m.ServerName, Equals, "config.example.com")
+	c.Assert(m.InsecureSkipVerify, Equals, true)
+
+	bc.TLSOpts.CAFile = "/nonexistent/ca.crt"
+	_, err = NewConfigClient(bc)
+	c.Assert(err, ErrorMatches, "could not read ca-file /nonexistent/ca.crt.*")
+}
+
 func (s *ConfigTestSuite) TestNewConfigClientDefault(c *C) {
 	u, err := url.Parse("hiya://localhost")
 	c.Assert(err, IsNil)
@@ -366,7 +390,7 @@ func (s *ConfigTestSuite) TestConfigConfigHandler_NotFound(c *C) {
 
 func (s *ConfigTestSuite) TestParseConfigEmpty(c *C) {
 	var err error
-	err = ParseConfig(TestConfigEmpty)
+	_, err = ParseConfig(TestConfigEmpty)
 	log.Infof("err=%#v\n", err.Error())
 	c.Assert(err, NotNil)
 	//c.Assert(err.Error(), Matches, "No globals.config-managers in butler.*")
@@ -375,7 +399,7 @@ func (s *ConfigTestSuite) TestParseConfigEmpty(c *C) {
 
 func (s *ConfigTestSuite) TestParseConfigBrokenNoHandlersNoExit(c *C) {
 	var err error
-	err = ParseConfig(TestConfigNoHandlers)
+	_, err = ParseConfig(TestConfigNoHandlers)
 	log.Infof("err=%#v\n", err)
 	c.Assert(err, NotNil)
 	//c.Assert(err.Error(), Matches, "No globals.config-managers in butler.*")
@@ -389,7 +413,7 @@ func (s *ConfigTestSuite) TestParseConfigBrokenNoHandlersExit(c *C) {
 		ExitTest = 5
 	})
 	defer patch.Unpatch()
-	err = ParseConfig(TestConfigNoHandlersExit)
+	_, err = ParseConfig(TestConfigNoHandlersExit)
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Matches, "globals.config-managers has no entries.*")
 	c.Assert(ExitTest, Equals, 0)
@@ -402,7 +426,7 @@ func (s *ConfigTestSuite) TestParseConfigBrokenNoHandlersNoExit2(c *C) {
 		ExitTest = 5
 	})
 	defer patch.Unpatch()
-	err = ParseConfig(TestConfigNoHandlersNoExit)
+	_, err = ParseConfig(TestConfigNoHandlersNoExit)
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Matches, "globals.config-managers has no entries.*")
 	c.Assert(ExitTest, Equals, 0)
@@ -410,7 +434,7 @@ func (s *ConfigTestSuite) TestParseConfigBrokenNoHandlersNoExit2(c *C) {
 
 func (s *ConfigTestSuite) TestParseConfigBrokenEmptyHandlersNoExit(c *C) {
 	var err error
-	err = ParseConfig(TestConfigEmptyHandlers)
+	_, err = ParseConfig(TestConfigEmptyHandlers)
 	log.Infof("err=%#v\n", err)
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Matches, "globals.config-managers has no entries.*")
@@ -423,14 +447,14 @@ func (s *ConfigTestSuite) TestParseConfigBrokenEmptyHandlersExit(c *C) {
 		ExitTest = 0
 	})
 	defer patch.Unpatch()
-	err = ParseConfig(TestConfigEmptyHandlersExit)
+	_, err = ParseConfig(TestConfigEmptyHandlersExit)
 	c.Assert(err, NotNil)
 	c.Assert(ExitTest, Equals, 0)
 }
 
 func (s *ConfigTestSuite) TestParseConfigBrokenIncompleteHandlerNoExit(c *C) {
 	var err error
-	err = ParseConfig(TestConfigBrokenIncompleteHandler)
+	_, err = ParseConfig(TestConfigBrokenIncompleteHandler)
 	log.Infof("err=%#v\n", err)
 	c.Assert(err, NotNil)
 	// stegen
@@ -444,7 +468,7 @@ func (s *ConfigTestSuite) TestParseConfigBrokenIncompleteHandlerExit(c *C) {
 		ExitTest = 5
 	})
 	defer patch.Unpatch()
-	err = ParseConfig(TestConfigBrokenIncompleteHandlerExit)
+	_, err = ParseConfig(TestConfigBrokenIncompleteHandlerExit)
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Matches, "could not retrieve config options for test-handler.*")
 	c.Assert(ExitTest, Equals, 0)
@@ -453,7 +477,7 @@ func (s *ConfigTestSuite) TestParseConfigBrokenIncompleteHandlerExit(c *C) {
 /*
 func (s *ConfigTestSuite) TestParseConfigCompleteNoExit(c *C) {
 	var err error
-	err = ParseConfig(TestConfigCompleteNoExit)
+	_, err = ParseConfig(TestConfigCompleteNoExit)
 	log.Infof("err=%#v\n", err)
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Matches, "Cannot find handler for test-handler2")
@@ -464,9 +488,9 @@ func (s *ConfigTestSuite) TestGetConfigManagerNoURLs(c *C) {
 	var err error
 
 	// Load the config initially
-	err = ParseConfig(TestManagerNoURLs)
+	cs, err := ParseConfig(TestManagerNoURLs)
 	c.Assert(err, NotNil)
-	err = GetConfigManager("testing", &ConfigSettings{})
+	err = GetConfigManager("testing", cs)
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Matches, "No repos configured for manager testing.*")
 }
@@ -475,9 +499,9 @@ func (s *ConfigTestSuite) TestGetConfigManagerURLs(c *C) {
 	var err error
 
 	// Load the config initially
-	err = ParseConfig(TestManagerURLs)
+	cs, err := ParseConfig(TestManagerURLs)
 	c.Assert(err, NotNil)
-	err = GetConfigManager("testing", &ConfigSettings{})
+	err = GetConfigManager("testing", cs)
 	c.Assert(err, NotNil)
 	// stegen
 	//c.Assert(err.Error(), Matches, "No urls configured for manager testing.*")
@@ -490,9 +514,9 @@ func (s *ConfigTestSuite) TestGetManagerOptsNoConfig(c *C) {
 	)
 
 	// Load the config initially
-	err = ParseConfig(TestManagerOptsEmpty)
+	cs, err := ParseConfig(TestManagerOptsEmpty)
 	c.Assert(err, NotNil)
-	opts, err = GetManagerOpts("testing.localhost", &ConfigSettings{})
+	opts, err = GetManagerOpts("testing.localhost", cs)
 	log.Debugf("TestGetManagerOptsNoConfig(): opts=%#v", opts)
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Matches, "unknown manager.method.*")
@@ -505,9 +529,9 @@ func (s *ConfigTestSuite) TestGetManagerOptsFullCheck(c *C) {
 	)
 
 	// Load the config initially
-	err = ParseConfig(TestManagerOptsFail1)
+	cs, err := ParseConfig(TestManagerOptsFail1)
 	c.Assert(err, NotNil)
-	opts, err = GetManagerOpts("testing.localhost", &ConfigSettings{})
+	opts, err = GetManagerOpts("testing.localhost", cs)
 	log.Debugf("TestGetManagerOptsNoConfig(): opts=%#v", opts)
 	c.Assert(err, NotNil)
 	// stegen
@@ -516,8 +540,7 @@ func (s *ConfigTestSuite) TestGetManagerOptsFullCheck(c *C) {
 
 func (s *ConfigTestSuite) TestConfigCompleteEnvironment(c *C) {
 	var (
-		err    error
-		config ConfigSettings
+		err error
 	)
 
 	// setup some environment
@@ -527,19 +550,19 @@ func (s *ConfigTestSuite) TestConfigCompleteEnvironment(c *C) {
 	os.Setenv("MSUB", mustacheSub)
 
 	// Load the config initially
-	err = ParseConfig(TestConfigCompleteEnvironment)
+	cs, err := ParseConfig(TestConfigCompleteEnvironment)
 	c.Assert(err, IsNil)
 
 	// Get the configuration
-	err = GetConfigManager("test-handler", &config)
+	err = GetConfigManager("test-handler", cs)
 	c.Assert(err, IsNil)
 
 	// Let's spot test some entries from the config
-	mgr := config.Managers["test-handler"]
+	mgr := cs.Managers["test-handler"]
 	c.Assert(mgr.CleanFiles, Equals, true)
 	c.Assert(mgr.EnableCache, Equals, false)
 	c.Assert(mgr.MustacheSubs["foo"], Equals, mustacheSub)
-	mgrReloaderOpts := config.Managers["test-handler"].Reloader.(reloaders.HTTPReloader)
+	mgrReloaderOpts := cs.Managers["test-handler"].Reloader.(reloaders.HTTPReloader)
 	c.Assert(mgrReloaderOpts.Opts.Host, Equals, reloaderHost)
 
 	// Cleanup env
@@ -549,20 +572,19 @@ func (s *ConfigTestSuite) TestConfigCompleteEnvironment(c *C) {
 
 func (s *ConfigTestSuite) TestConfigWatchOnlyMode(c *C) {
 	var (
-		err    error
-		config ConfigSettings
+		err error
 	)
 
 	// Load the watch-only config
-	err = ParseConfig(TestConfigWatchOnly)
+	cs, err := ParseConfig(TestConfigWatchOnly)
 	c.Assert(err, IsNil)
 
 	// Get the configuration
-	err = GetConfigManager("test-handler", &config)
+	err = GetConfigManager("test-handler", cs)
 	c.Assert(err, IsNil)
 
 	// Verify watch-only mode is enabled
-	mgr := config.Managers["test-handler"]
+	mgr := cs.Managers["test-handler"]
 	c.Assert(mgr.WatchOnly, Equals, true)
 	c.Assert(mgr.SkipButlerHeader, Equals, true)
 	c.Assert(mgr.CleanFiles, Equals, false)
@@ -577,20 +599,19 @@ func (s *ConfigTestSuite) TestConfigWatchOnlyMode(c *C) {
 
 func (s *ConfigTestSuite) TestConfigWatchOnlyModeWithDestPath(c *C) {
 	var (
-		err    error
-		config ConfigSettings
+		err error
 	)
 
 	// Load the watch-only config with dest-path
-	err = ParseConfig(TestConfigWatchOnlyWithDestPath)
+	cs, err := ParseConfig(TestConfigWatchOnlyWithDestPath)
 	c.Assert(err, IsNil)
 
 	// Get the configuration
-	err = GetConfigManager("test-handler", &config)
+	err = GetConfigManager("test-handler", cs)
 	c.Assert(err, IsNil)
 
 	// Verify watch-only mode is enabled
-	mgr := config.Managers["test-handler"]
+	mgr := cs.Managers["test-handler"]
 	c.Assert(mgr.WatchOnly, Equals, true)
 	c.Assert(mgr.SkipButlerHeader, Equals, true)
 
@@ -603,12 +624,11 @@ func (s *ConfigTestSuite) TestConfigWatchOnlyModeWithDestPath(c *C) {
 
 func (s *ConfigTestSuite) TestConfigWatchOnlyModeDisabled(c *C) {
 	var (
-		err    error
-		config ConfigSettings
+		err error
 	)
 
 	// Load the standard config (watch-only not set)
-	err = ParseConfig(TestConfigCompleteEnvironment)
+	cs, err := ParseConfig(TestConfigCompleteEnvironment)
 	c.Assert(err, IsNil)
 
 	// setup environment for this test
@@ -618,13 +638,114 @@ func (s *ConfigTestSuite) TestConfigWatchOnlyModeDisabled(c *C) {
 	defer os.Unsetenv("MSUB")
 
 	// Get the configuration
-	err = GetConfigManager("test-handler", &config)
+	err = GetConfigManager("test-handler", cs)
 	c.Assert(err, IsNil)
 
 	// Verify watch-only mode is disabled by default
-	mgr := config.Managers["test-handler"]
+	mgr := cs.Managers["test-handler"]
 	c.Assert(mgr.WatchOnly, Equals, false)
 
 	// Verify FileHashes map is nil when watch-only is disabled
 	c.Assert(mgr.FileHashes, IsNil)
 }
+
+func (s *ConfigTestSuite) TestParseConfigIndependentSources(c *C) {
+	var (
+		wg    sync.WaitGroup
+		cs1   ConfigSettings
+		cs2   ConfigSettings
+		err1  error
+		err2  error
+		count = 10
+	)
+
+	os.Setenv("RELOADER_HOST", "localhost")
+	os.Setenv("MSUB", "test")
+	defer os.Unsetenv("RELOADER_HOST")
+	defer os.Unsetenv("MSUB")
+
+	// Each configuration is parsed into its own source, so the two do not
+	// trip over each other when parsed side by side.
+	for i := 0; i < count; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			err1 = cs1.ParseConfig(TestConfigCompleteEnvironment)
+		}()
+		go func() {
+			defer wg.Done()
+			err2 = cs2.ParseConfig(TestConfigWatchOnly)
+		}()
+		wg.Wait()
+		c.Assert(err1, IsNil)
+		c.Assert(err2, IsNil)
+		c.Assert(cs1.Managers["test-handler"].WatchOnly, Equals, false)
+		c.Assert(cs1.Managers["test-handler"].DestPath, Equals, "/opt/prometheus")
+		c.Assert(cs2.Managers["test-handler"].WatchOnly, Equals, true)
+	}
+
+	// Managers are still read from the configuration which was parsed into
+	// the settings, rather than the last one parsed.
+	err := GetConfigManager("test-handler", &cs1)
+	c.Assert(err, IsNil)
+	c.Assert(cs1.Managers["test-handler"].PrimaryConfigName, Equals, "prometheus.yml")
+}
+
+// metricValue returns the value of the counter, or gauge, name with the
+// labels, or 0 when it has not been set yet.
+func metricValue(c *C, name string, labels map[string]string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	c.Assert(err, IsNil)
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if labels[l.GetName()] != l.GetValue() {
+					continue metrics
+				}
+			}
+			if m.Counter != nil {
+				return m.GetCounter().GetValue()
+			}
+			return m.GetGauge().GetValue()
+		}
+	}
+	return 0
+}
+
+func (s *ConfigTestSuite) TestRunCounters(c *C) {
+	repo, dest := c.MkDir(), c.MkDir()
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {}\n#butlerend\n"), 0644), IsNil)
+	// The manager, and the repo, are named after the test, since the counters
+	// are shared with the other tests.
+	config := strings.Replace(fmt.Sprintf(TestLintConfig, dest), "/butler", repo, -1)
+	config = strings.Replace(config, "repo1.domain.com", "counters", -1)
+	config = strings.Replace(config, "prometheus", "counters", -1)
+	config = strings.Replace(config, "counters.yml", "prometheus.yml", -1)
+	bc := &ButlerConfig{Config: NewConfigSettings(), RawConfig: []byte(config)}
+	c.Assert(bc.Config.ParseConfig([]byte(config)), IsNil)
+	bc.Config.Globals.StatusFile = c.MkDir() + "/status"
+	m := bc.GetManager("counters")
+	c.Assert(m, NotNil)
+	m.Reloader = &testReloader{}
+	downloads := map[string]string{"manager": "counters", "repo": "counters", "result": "success"}
+	reloads := map[string]string{"manager": "counters", "result": "success"}
+	runs := map[string]string{"manager": "counters", "result": "failure"}
+
+	_, err := bc.Run()
+	c.Assert(err, IsNil)
+	c.Assert(metricValue(c, "butler_remoterepo_downloads_total", downloads), Equals, 1.0)
+	c.Assert(metricValue(c, "butler_manager_reloads_total", reloads), Equals, 1.0)
+
+	// A file which does not validate fails the update of the manager
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("global: {}\n"), 0644), IsNil)
+	_, err = bc.Run()
+	c.Assert(err, IsNil)
+	c.Assert(metricValue(c, "butler_remoterepo_downloads_total", downloads), Equals, 2.0)
+	c.Assert(metricValue(c, "butler_remoterepo_validation_failures_total", map[string]string{"manager": "counters", "repo": "counters"}), Equals, 1.0)
+	c.Assert(metricValue(c, "butler_manager_runs_total", runs), Equals, 1.0)
+	c.Assert(metricValue(c, "butler_manager_reloads_total", reloads), Equals, 1.0)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dependencyOrder returns the managers of deps, which maps each manager to
+// the managers it depends on, ordered so that every manager comes after its
+// dependencies. Managers which do not depend on each other are ordered by
+// name, so that runs are repeatable.
+func dependencyOrder(deps map[string][]string) ([]string, error) {
+	var (
+		names []string
+		order []string
+	)
+	for m := range deps {
+		names = append(names, m)
+	}
+	sort.Strings(names)
+
+	pending := make(map[string]int)
+	dependants := make(map[string][]string)
+	for _, m := range names {
+		for _, d := range deps[m] {
+			if _, ok := deps[d]; !ok {
+				return nil, fmt.Errorf("manager %v depends on unknown manager %v", m, d)
+			}
+			pending[m]++
+			dependants[d] = append(dependants[d], m)
+		}
+	}
+
+	var ready []string
+	for _, m := range names {
+		if pending[m] == 0 {
+			ready = append(ready, m)
+		}
+	}
+	for len(ready) > 0 {
+		m := ready[0]
+		ready = ready[1:]
+		order = append(order, m)
+		for _, d := range dependants[m] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+				sort.Strings(ready)
+			}
+		}
+	}
+
+	if len(order) != len(names) {
+		var cycle []string
+		for _, m := range names {
+			if pending[m] > 0 {
+				cycle = append(cycle, m)
+			}
+		}
+		return nil, fmt.Errorf("managers %v have a dependency cycle", strings.Join(cycle, ", "))
+	}
+	return order, nil
+}
+
+// checkDependencies checks that the depends-on of every manager only names
+// known managers, and that there are no cycles.
+func checkDependencies(managers map[string]*Manager) error {
+	deps := make(map[string][]string)
+	for name, m := range managers {
+		deps[name] = m.DependsOn
+	}
+	_, err := dependencyOrder(deps)
+	return err
+}
+
+// orderManagers returns the managers in the order they are to be updated and
+// reloaded in. Dependencies on managers which are not part of the run, eg:
+// because they are paused, are left out.
+func orderManagers(managers map[string]*Manager) []*Manager {
+	var res []*Manager
+	deps := make(map[string][]string)
+	for name, m := range managers {
+		deps[name] = []string{}
+		for _, d := range m.DependsOn {
+			if _, ok := managers[d]; ok {
+				deps[name] = append(deps[name], d)
+			}
+		}
+	}
+
+	order, err := dependencyOrder(deps)
+	if err != nil {
+		// The dependencies are checked when the configuration is parsed, so
+		// this is not expected. Fall back to the order of the names.
+		order = order[:0]
+		for name := range managers {
+			order = append(order, name)
+		}
+		sort.Strings(order)
+	}
+	for _, name := range order {
+		res = append(res, managers[name])
+	}
+	return res
+}
+
+// failedDependency returns the first dependency of m which failed to update
+// in the run, if there is one.
+func failedDependency(m *Manager, result *RunResult) string {
+	if result == nil {
+		return ""
+	}
+	for _, d := range m.DependsOn {
+		if mr, ok := result.Managers[d]; ok && !mr.Success {
+			return d
+		}
+	}
+	return ""
+}
+
+// deferredDependency returns the first dependency of m which has its reload
+// deferred in the run, if there is one.
+func deferredDependency(m *Manager, result *RunResult) string {
+	if result == nil {
+		return ""
+	}
+	for _, d := range m.DependsOn {
+		if mr, ok := result.Managers[d]; ok && mr.Deferred {
+			return d
+		}
+	}
+	return ""
+}
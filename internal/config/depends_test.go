@@ -0,0 +1,240 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adobe/butler/internal/reloaders"
+
+	. "gopkg.in/check.v1"
+)
+
+// dependsConfig returns a configuration with the managers prometheus and
+// alertmanager, where prometheus has the given depends-on.
+func dependsConfig(c *C, dependsOn string) []byte {
+	config := fmt.Sprintf(TestLintConfig, c.MkDir())
+	start := strings.Index(config, "[prometheus]")
+	end := strings.Index(config, "#butlerend")
+	alertmanager := strings.Replace(config[start:end], "prometheus", "alertmanager", -1)
+	config = config[:end] + alertmanager + config[end:]
+	config = strings.Replace(config, `config-managers = ["prometheus"]`, `config-managers = ["prometheus", "alertmanager"]`, 1)
+	return []byte(strings.Replace(config, `  primary-config-name = "prometheus.yml"`,
+		`  primary-config-name = "prometheus.yml"
+  depends-on = `+dependsOn, 1))
+}
+
+func (s *ConfigTestSuite) TestDependencyOrder(c *C) {
+	order, err := dependencyOrder(map[string][]string{
+		"prometheus":   {"alertmanager", "rules"},
+		"alertmanager": {},
+		"rules":        {"alertmanager"},
+		"blackbox":     nil,
+		"zz":           nil,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(order, DeepEquals, []string{"alertmanager", "blackbox", "rules", "prometheus", "zz"})
+
+	_, err = dependencyOrder(map[string][]string{"prometheus": {"alertmanager"}})
+	c.Assert(err, ErrorMatches, "manager prometheus depends on unknown manager alertmanager")
+
+	_, err = dependencyOrder(map[string][]string{
+		"prometheus":   {"alertmanager"},
+		"alertmanager": {"prometheus"},
+		"blackbox":     nil,
+	})
+	c.Assert(err, ErrorMatches, "managers alertmanager, prometheus have a dependency cycle")
+
+	_, err = dependencyOrder(map[string][]string{"prometheus": {"prometheus"}})
+	c.Assert(err, ErrorMatches, "managers prometheus have a dependency cycle")
+}
+
+func (s *ConfigTestSuite) TestOrderManagers(c *C) {
+	managers := map[string]*Manager{
+		"prometheus":   &Manager{Name: "prometheus", DependsOn: []string{"alertmanager", "paused"}},
+		"alertmanager": &Manager{Name: "alertmanager"},
+	}
+	var names []string
+	for _, m := range orderManagers(managers) {
+		names = append(names, m.Name)
+	}
+	c.Assert(names, DeepEquals, []string{"alertmanager", "prometheus"})
+}
+
+func (s *ConfigTestSuite) TestFailedDependency(c *C) {
+	result := NewRunResult(0)
+	result.AddManager("alertmanager")
+	m := &Manager{Name: "prometheus", DependsOn: []string{"paused", "alertmanager"}}
+	c.Assert(failedDependency(m, result), Equals, "")
+	c.Assert(failedDependency(m, nil), Equals, "")
+
+	result.Managers["alertmanager"].SetError("could not retrieve all configuration files")
+	c.Assert(failedDependency(m, result), Equals, "alertmanager")
+}
+
+func (s *ConfigTestSuite) TestDeferredDependency(c *C) {
+	result := NewRunResult(0)
+	result.AddManager("alertmanager")
+	m := &Manager{Name: "prometheus", DependsOn: []string{"paused", "alertmanager"}}
+	c.Assert(deferredDependency(m, result), Equals, "")
+	c.Assert(deferredDependency(m, nil), Equals, "")
+
+	result.Managers["alertmanager"].Deferred = true
+	c.Assert(deferredDependency(m, result), Equals, "alertmanager")
+	c.Assert(failedDependency(m, result), Equals, "")
+}
+
+func (s *ConfigTestSuite) TestParseConfigDependsOn(c *C) {
+	cs, err := ParseConfig(dependsConfig(c, `["alertmanager"]`))
+	c.Assert(err, IsNil)
+	c.Assert(cs.Managers["prometheus"].DependsOn, DeepEquals, []string{"alertmanager"})
+
+	_, err = ParseConfig(dependsConfig(c, `["grafana"]`))
+	c.Assert(err, ErrorMatches, "manager prometheus depends on unknown manager grafana")
+
+	config := strings.Replace(string(dependsConfig(c, `["alertmanager"]`)), `  primary-config-name = "alertmanager.yml"`,
+		`  primary-config-name = "alertmanager.yml"
+  depends-on = ["prometheus"]`, 1)
+	_, err = ParseConfig([]byte(config))
+	c.Assert(err, ErrorMatches, "managers alertmanager, prometheus have a dependency cycle")
+}
+
+func (s *ConfigTestSuite) TestLintConfigDependsOn(c *C) {
+	c.Assert(lintIssues(dependsConfig(c, `["alertmanager"]`)), HasLen, 0)
+
+	issues := lintIssues(dependsConfig(c, `["grafana"]`))
+	c.Assert(issues, HasLen, 1)
+	c.Assert(issues[0], Matches, ".*prometheus.depends-on.*depends on grafana, which is not listed in globals.config-managers")
+}
+
+func (s *ConfigTestSuite) TestReloadManagerHeldBack(c *C) {
+	bc := &ButlerConfig{Config: NewConfigSettings()}
+	bc.Config.Globals.StatusFile = c.MkDir() + "/status"
+	c.Assert(SetManagerStatus(bc.GetStatusFile(), "prometheus", true), IsNil)
+
+	// The reload of prometheus is held back, as alertmanager failed to
+	// update, and prometheus is marked as out of sync so that it gets
+	// reloaded on the next run
+	result := NewRunResult(0)
+	result.AddManager("alertmanager").SetError("could not reload")
+	mr := result.AddManager("prometheus")
+	bc.reloadManager(bc.Context(), &Manager{Name: "prometheus", DependsOn: []string{"alertmanager"}}, mr)
+	c.Assert(mr.Success, Equals, false)
+	c.Assert(mr.Reloaded, Equals, false)
+	c.Assert(mr.Error, Equals, "reload held back, dependency alertmanager failed to update")
+	c.Assert(GetManagerStatus(bc.GetStatusFile(), "prometheus"), Equals, false)
+}
+
+func (s *ConfigTestSuite) TestRunManagersHeldBack(c *C) {
+	repo := c.MkDir()
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {}\n#butlerend\n"), 0644), IsNil)
+	config := strings.Replace(string(dependsConfig(c, `["alertmanager"]`)), "/butler", repo, -1)
+	bc := &ButlerConfig{Config: NewConfigSettings()}
+	c.Assert(bc.Config.ParseConfig([]byte(strings.Replace(config, "repo1.domain.com", "localhost", -1))), IsNil)
+	bc.Config.Globals.StatusFile = c.MkDir() + "/status"
+	c.Assert(SetManagerStatus(bc.GetStatusFile(), "alertmanager", true), IsNil)
+	c.Assert(SetManagerStatus(bc.GetStatusFile(), "prometheus", true), IsNil)
+
+	// The files of alertmanager cannot be retrieved, so prometheus is not
+	// touched at all
+	res, err := bc.Run()
+	c.Assert(err, IsNil)
+	c.Assert(res.Managers["alertmanager"].Error, Equals, "could not retrieve all configuration files")
+	c.Assert(res.Managers["prometheus"].Error, Equals, "held back, dependency alertmanager failed to update")
+	c.Assert(res.Managers["prometheus"].Files, HasLen, 0)
+
+	// alertmanager is updated, but cannot be reloaded, so the reload of
+	// prometheus is held back
+	c.Assert(os.WriteFile(repo+"/alertmanager.yml", []byte("#butlerstart\nroute: {}\n#butlerend\n"), 0644), IsNil)
+	res, err = bc.Run()
+	c.Assert(err, IsNil)
+	c.Assert(res.Managers["alertmanager"].Changed, Equals, true)
+	c.Assert(res.Managers["alertmanager"].Reloaded, Equals, false)
+	c.Assert(res.Managers["prometheus"].Changed, Equals, true)
+	c.Assert(res.Managers["prometheus"].Error, Equals, "reload held back, dependency alertmanager failed to update")
+	c.Assert(GetManagerStatus(bc.GetStatusFile(), "prometheus"), Equals, false)
+
+	// A paused dependency does not hold back the managers which depend on it
+	c.Assert(SetManagerPaused(bc.GetStatusFile(), "alertmanager", true), IsNil)
+	res, err = bc.Run()
+	c.Assert(err, IsNil)
+	c.Assert(res.Managers["alertmanager"].Paused, Equals, true)
+	c.Assert(res.Managers["prometheus"].Error, Not(Matches), "held back.*")
+}
+
+// orderReloader records the order the managers are reloaded in.
+type orderReloader struct {
+	testReloader
+	name   string
+	lock   *sync.Mutex
+	called *[]string
+}
+
+func (r *orderReloader) Reload(context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	*r.called = append(*r.called, r.name)
+	return nil
+}
+
+func (r *orderReloader) SetCounter(int) reloaders.Reloader { return r }
+
+func (s *ConfigTestSuite) TestRunManagersDeferredDependency(c *C) {
+	repo := c.MkDir()
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {}\n#butlerend\n"), 0644), IsNil)
+	c.Assert(os.WriteFile(repo+"/alertmanager.yml", []byte("#butlerstart\nroute: {}\n#butlerend\n"), 0644), IsNil)
+	config := strings.Replace(string(dependsConfig(c, `["alertmanager"]`)), "/butler", repo, -1)
+	bc := &ButlerConfig{Config: NewConfigSettings()}
+	c.Assert(bc.Config.ParseConfig([]byte(strings.Replace(config, "repo1.domain.com", "localhost", -1))), IsNil)
+	bc.Config.Globals.StatusFile = c.MkDir() + "/status"
+	defer bc.Shutdown()
+
+	var (
+		lock   sync.Mutex
+		called []string
+	)
+	for _, name := range []string{"alertmanager", "prometheus"} {
+		bc.GetManager(name).Reloader = &orderReloader{name: name, lock: &lock, called: &called}
+	}
+	reloaded := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), called...)
+	}
+
+	// alertmanager has reached its reload limits, so its reload is
+	// deferred, and prometheus, which changes in the same run, waits for it
+	bc.GetManager("alertmanager").MinReloadInterval = 3600
+	bc.reloadLimiter("alertmanager").record(time.Now().Add(-time.Hour + 200*time.Millisecond))
+	res, err := bc.Run()
+	c.Assert(err, IsNil)
+	c.Assert(res.Managers["alertmanager"].Deferred, Equals, true)
+	c.Assert(res.Managers["prometheus"].Changed, Equals, true)
+	c.Assert(res.Managers["prometheus"].Deferred, Equals, true)
+	c.Assert(res.Managers["prometheus"].Reloaded, Equals, false)
+	c.Assert(reloaded(), HasLen, 0)
+	c.Assert(GetManagerStatus(bc.GetStatusFile(), "prometheus"), Equals, false)
+
+	// The deferred reload of alertmanager reloads prometheus right after it
+	for i := 0; i < 50 && len(reloaded()) < 2; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	c.Assert(reloaded(), DeepEquals, []string{"alertmanager", "prometheus"})
+	c.Assert(GetManagerStatus(bc.GetStatusFile(), "alertmanager"), Equals, true)
+	c.Assert(GetManagerStatus(bc.GetStatusFile(), "prometheus"), Equals, true)
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around each change.
+	diffContext = 3
+	// diffMaxCells bounds the size of the table used to find the longest
+	// common subsequence of the changed region of two files. Beyond it, the
+	// whole changed region is shown as removed and then added.
+	diffMaxCells = 1 << 22
+)
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	a    int  // line index in a, or the number of lines of a before an insert
+	b    int  // line index in b, or the number of lines of b before a delete
+}
+
+// UnifiedDiff returns the unified diff which turns a into b, using fromName
+// and toName as the file names in the diff header. It returns an empty string
+// when a and b are the same.
+func UnifiedDiff(fromName string, toName string, a []byte, b []byte) string {
+	al, bl := splitLines(string(a)), splitLines(string(b))
+	ops := diffLines(al, bl)
+
+	var out strings.Builder
+	i, last := 0, 0
+	for i < len(ops) {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+
+		start := i - diffContext
+		if start < last {
+			start = last
+		}
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			j := end
+			for j < len(ops) && ops[j].kind == ' ' {
+				j++
+			}
+			if j < len(ops) && j-end <= 2*diffContext {
+				end = j
+				continue
+			}
+			if end+diffContext < j {
+				end += diffContext
+			} else {
+				end = j
+			}
+			break
+		}
+
+		writeHunk(&out, ops[start:end], al, bl)
+		i, last = end, end
+	}
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, ops []diffOp, al []string, bl []string) {
+	aStart, bStart := ops[0].a, ops[0].b
+	aLen, bLen := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			aLen++
+		}
+		if op.kind != '-' {
+			bLen++
+		}
+	}
+	if aLen > 0 {
+		aStart++
+	}
+	if bLen > 0 {
+		bStart++
+	}
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+
+	for _, op := range ops {
+		var line string
+		if op.kind == '+' {
+			line = bl[op.b]
+		} else {
+			line = al[op.a]
+		}
+		out.WriteByte(op.kind)
+		out.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// splitLines splits s into lines, keeping the line endings, so that a
+// missing newline at the end of a file shows up as a difference.
+func splitLines(s string) []string {
+	var lines []string
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+	return lines
+}
+
+// diffLines returns the edit script which turns a into b.
+func diffLines(a []string, b []string) []diffOp {
+	var ops []diffOp
+
+	// Configuration changes tend to be small, so only the region between the
+	// common prefix and the common suffix needs a closer look.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		ops = append(ops, diffOp{kind: ' ', a: pre, b: pre})
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	am, bm := a[pre:len(a)-suf], b[pre:len(b)-suf]
+
+	if len(am)*len(bm) > diffMaxCells {
+		for i := range am {
+			ops = append(ops, diffOp{kind: '-', a: pre + i, b: pre})
+		}
+		for j := range bm {
+			ops = append(ops, diffOp{kind: '+', a: pre + len(am), b: pre + j})
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of
+		// am[i:] and bm[j:]
+		lcs := make([][]int32, len(am)+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, len(bm)+1)
+		}
+		for i := len(am) - 1; i >= 0; i-- {
+			for j := len(bm) - 1; j >= 0; j-- {
+				if am[i] == bm[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(am) || j < len(bm) {
+			switch {
+			case i < len(am) && j < len(bm) && am[i] == bm[j]:
+				ops = append(ops, diffOp{kind: ' ', a: pre + i, b: pre + j})
+				i++
+				j++
+			case j == len(bm) || (i < len(am) && lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, diffOp{kind: '-', a: pre + i, b: pre + j})
+				i++
+			default:
+				ops = append(ops, diffOp{kind: '+', a: pre + i, b: pre + j})
+				j++
+			}
+		}
+	}
+
+	for k := 0; k < suf; k++ {
+		ops = append(ops, diffOp{kind: ' ', a: len(a) - suf + k, b: len(b) - suf + k})
+	}
+	return ops
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *ConfigTestSuite) TestUnifiedDiffSame(c *C) {
+	c.Assert(UnifiedDiff("a", "b", []byte("one\ntwo\n"), []byte("one\ntwo\n")), Equals, "")
+	c.Assert(UnifiedDiff("a", "b", nil, nil), Equals, "")
+}
+
+func (s *ConfigTestSuite) TestUnifiedDiffCreate(c *C) {
+	c.Assert(UnifiedDiff("/dev/null", "b", nil, []byte("one\ntwo\n")), Equals, `--- /dev/null
++++ b
+@@ -0,0 +1,2 @@
++one
++two
+`)
+}
+
+func (s *ConfigTestSuite) TestUnifiedDiffHunks(c *C) {
+	a := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n")
+	b := []byte("1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n16\nseventeen\n")
+	c.Assert(UnifiedDiff("a", "b", a, b), Equals, `--- a
++++ b
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -12,5 +12,5 @@
+ 12
+ 13
+ 14
+-15
+ 16
++seventeen
+`)
+}
+
+func (s *ConfigTestSuite) TestUnifiedDiffNoNewline(c *C) {
+	c.Assert(UnifiedDiff("a", "b", []byte("one\n"), []byte("one")), Equals, `--- a
++++ b
+@@ -1,1 +1,1 @@
+-one
++one
+\ No newline at end of file
+`)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// GlobalsHandler is called with the previous and the current globals whenever
+// a new butler configuration changes them.
+type GlobalsHandler func(prev ConfigGlobals, cur ConfigGlobals)
+
+// OnGlobalsChange registers h to be called whenever a new butler
+// configuration changes the globals, eg: so that the monitor can move to a
+// new port.
+func (bc *ButlerConfig) OnGlobalsChange(h GlobalsHandler) {
+	bc.globalsLock.Lock()
+	defer bc.globalsLock.Unlock()
+	bc.globalsHandlers = append(bc.globalsHandlers, h)
+}
+
+func (bc *ButlerConfig) getGlobals() ConfigGlobals {
+	if bc.Config == nil {
+		return ConfigGlobals{}
+	}
+	return bc.Config.Globals
+}
+
+// AdminToken returns the token of the admin api, or "" when it is disabled.
+// It is safe to call while the butler configuration is being re-parsed.
+func (bc *ButlerConfig) AdminToken() string {
+	bc.globalsLock.Lock()
+	defer bc.globalsLock.Unlock()
+	return bc.adminToken
+}
+
+// SetAdminToken sets the token of the admin api. It is set from
+// globals.admin-token whenever the globals change.
+func (bc *ButlerConfig) SetAdminToken(token string) {
+	bc.globalsLock.Lock()
+	defer bc.globalsLock.Unlock()
+	bc.adminToken = token
+}
+
+// applyGlobals applies the globals which butler itself looks after, and then
+// hands the change over to the registered handlers. Nothing is done if the
+// globals are unchanged.
+func (bc *ButlerConfig) applyGlobals(prev ConfigGlobals, cur ConfigGlobals) {
+	if reflect.DeepEqual(prev, cur) {
+		return
+	}
+	log.Infof("ButlerConfig::applyGlobals(): butler globals have changed. applying.")
+
+	if prev.LogLevel != cur.LogLevel {
+		level := bc.defaultLogLevel
+		if cur.LogLevel != "" {
+			// The log level has already been checked by ParseConfig
+			level, _ = log.ParseLevel(cur.LogLevel)
+		}
+		bc.SetLogLevel(level)
+		log.Infof("ButlerConfig::applyGlobals(): log level is now %v.", level)
+	}
+	if prev.AdminToken != cur.AdminToken {
+		bc.SetAdminToken(cur.AdminToken)
+		log.Infof("ButlerConfig::applyGlobals(): admin api enabled=%v.", cur.AdminToken != "")
+	}
+	if prev.ExitOnFailure != cur.ExitOnFailure {
+		log.Infof("ButlerConfig::applyGlobals(): exit-on-config-failure is now %v.", cur.ExitOnFailure)
+	}
+	if !reflect.DeepEqual(prev.Notifiers, cur.Notifiers) {
+		bc.setNotifiers(cur.Notifiers)
+		log.Infof("ButlerConfig::applyGlobals(): %v notifiers are configured.", len(cur.Notifiers))
+	}
+
+	if prev.AuditLog != cur.AuditLog || prev.AuditLogMaxSize != cur.AuditLogMaxSize || prev.AuditLogMaxBackups != cur.AuditLogMaxBackups {
+		bc.setAuditLog(cur)
+		log.Infof("ButlerConfig::applyGlobals(): audit log is now %q.", cur.AuditLog)
+	}
+
+	if prev.TracingEndpoint != cur.TracingEndpoint || prev.TracingProtocol != cur.TracingProtocol || prev.TracingInsecure != cur.TracingInsecure {
+		bc.setTracing(cur)
+		log.Infof("ButlerConfig::applyGlobals(): tracing endpoint is now %q.", cur.TracingEndpoint)
+	}
+
+	bc.globalsLock.Lock()
+	handlers := append([]GlobalsHandler(nil), bc.globalsHandlers...)
+	bc.globalsLock.Unlock()
+	for _, h := range handlers {
+		h(prev, cur)
+	}
+}
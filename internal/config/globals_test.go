@@ -0,0 +1,155 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/adobe/butler/internal/methods"
+
+	"github.com/jasonlvhit/gocron"
+	log "github.com/sirupsen/logrus"
+	. "gopkg.in/check.v1"
+)
+
+var TestGlobalsConfig = `#butlerstart
+[globals]
+  config-managers = ["prometheus"]
+  status-file = "%v/butler.status"
+  http-port = "%v"
+  log-level = "%v"
+
+[prometheus]
+  repos = ["localhost"]
+  dest-path = "%v"
+
+  [prometheus.localhost]
+    method = "file"
+    repo-path = "%v"
+    primary-config = ["prometheus.yml"]
+
+    [prometheus.localhost.file]
+      path = "%v"
+#butlerend
+`
+
+func (s *ConfigTestSuite) TestHandlerGlobalsChange(c *C) {
+	dir := c.MkDir()
+	level := log.GetLevel()
+	defer log.SetLevel(level)
+	write := func(port int, logLevel string) {
+		data := fmt.Sprintf(TestGlobalsConfig, dir, port, logLevel, dir, dir, dir)
+		c.Assert(os.WriteFile(dir+"/butler.toml", []byte(data), 0644), IsNil)
+	}
+	write(8080, "")
+
+	u, err := url.Parse("file://" + dir + "/butler.toml")
+	c.Assert(err, IsNil)
+	bc, err := NewButlerConfig(&ButlerConfigOpts{URL: u, LogLevel: log.WarnLevel})
+	c.Assert(err, IsNil)
+	bc.SetMethodOpts(methods.FileMethodOpts{Scheme: "file"})
+	c.Assert(bc.Init(), IsNil)
+	bc.SetScheduler(gocron.NewScheduler())
+
+	var changes [][2]ConfigGlobals
+	bc.OnGlobalsChange(func(prev ConfigGlobals, cur ConfigGlobals) {
+		changes = append(changes, [2]ConfigGlobals{prev, cur})
+	})
+	c.Assert(bc.Handler(), IsNil)
+	c.Assert(changes, HasLen, 1)
+	c.Assert(changes[0][1].HTTPPort, Equals, 8080)
+
+	// Nothing changes, so the handlers are left alone
+	c.Assert(bc.Handler(), IsNil)
+	c.Assert(changes, HasLen, 1)
+
+	// The new globals are applied, along with the log level
+	write(8081, "debug")
+	c.Assert(bc.Handler(), IsNil)
+	c.Assert(changes, HasLen, 2)
+	c.Assert(changes[1][0].HTTPPort, Equals, 8080)
+	c.Assert(changes[1][1].HTTPPort, Equals, 8081)
+	c.Assert(bc.GetLogLevel(), Equals, log.DebugLevel)
+	c.Assert(log.GetLevel(), Equals, log.DebugLevel)
+
+	// Without a log level in the globals, butler goes back to -log.level
+	write(8081, "")
+	c.Assert(bc.Handler(), IsNil)
+	c.Assert(changes, HasLen, 3)
+	c.Assert(log.GetLevel(), Equals, log.WarnLevel)
+
+	// A bad configuration leaves the globals as they are
+	write(8082, "loud")
+	c.Assert(bc.Handler(), NotNil)
+	c.Assert(changes, HasLen, 3)
+	c.Assert(bc.Config.Globals.HTTPPort, Equals, 8081)
+}
+
+func (s *ConfigTestSuite) TestHandlerAdminToken(c *C) {
+	dir := c.MkDir()
+	write := func(token string) {
+		data := fmt.Sprintf(TestGlobalsConfig, dir, 8080, "", dir, dir, dir)
+		data = strings.Replace(data, `  log-level = ""`, fmt.Sprintf(`  admin-token = "%v"`, token), 1)
+		c.Assert(os.WriteFile(dir+"/butler.toml", []byte(data), 0644), IsNil)
+	}
+	write("s3cr3t")
+
+	u, err := url.Parse("file://" + dir + "/butler.toml")
+	c.Assert(err, IsNil)
+	bc, err := NewButlerConfig(&ButlerConfigOpts{URL: u})
+	c.Assert(err, IsNil)
+	bc.SetMethodOpts(methods.FileMethodOpts{Scheme: "file"})
+	c.Assert(bc.Init(), IsNil)
+	bc.SetScheduler(gocron.NewScheduler())
+	c.Assert(bc.AdminToken(), Equals, "")
+
+	// The token is read while the butler configuration is re-parsed
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			bc.AdminToken()
+		}
+	}()
+	c.Assert(bc.Handler(), IsNil)
+	<-done
+	c.Assert(bc.AdminToken(), Equals, "s3cr3t")
+
+	write("n3w")
+	c.Assert(bc.Handler(), IsNil)
+	c.Assert(bc.AdminToken(), Equals, "n3w")
+}
+
+func (s *ConfigTestSuite) TestParseConfigHTTPLog(c *C) {
+	config := fmt.Sprintf(TestLintConfig, c.MkDir())
+	cs := NewConfigSettings()
+	c.Assert(cs.ParseConfig([]byte(config)), IsNil)
+	c.Assert(cs.Globals.HTTPLogFormat, Equals, "butler")
+	c.Assert(cs.Globals.HTTPLogFile, Equals, "")
+
+	config = strings.Replace(config, `  scheduler-interval = "300"`, `  scheduler-interval = "300"
+  http-log-format = "Combined"
+  http-log-file = "/var/log/butler/access.log"`, 1)
+	c.Assert(cs.ParseConfig([]byte(config)), IsNil)
+	c.Assert(cs.Globals.HTTPLogFormat, Equals, "combined")
+	c.Assert(cs.Globals.HTTPLogFile, Equals, "/var/log/butler/access.log")
+	c.Assert(lintIssues([]byte(config)), HasLen, 0)
+
+	config = strings.Replace(config, `"Combined"`, `"nginx"`, 1)
+	err := cs.ParseConfig([]byte(config))
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "globals.http-log-format=nginx is not a valid http log format")
+}
@@ -14,6 +14,7 @@ package config
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -21,14 +22,19 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/adobe/butler/internal/audit"
 	"github.com/adobe/butler/internal/methods"
 	"github.com/adobe/butler/internal/metrics"
+	"github.com/adobe/butler/internal/notifier"
 	"github.com/adobe/butler/internal/reloaders"
+	"github.com/adobe/butler/internal/tracing"
 
 	"github.com/jasonlvhit/gocron"
 	log "github.com/sirupsen/logrus"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 type ButlerConfig struct {
@@ -43,14 +49,27 @@ type ButlerConfig struct {
 	RawConfig               []byte
 	Scheduler               *gocron.Scheduler
 	InsecureSkipVerify      bool
+	TLSOpts                 methods.TLSOpts
 	MethodOpts              methods.MethodOpts
+	runLock                 sync.Mutex
+	ctx                     context.Context
+	configWatchCancel       context.CancelFunc
+	managerWatchCancel      context.CancelFunc
+	handlerCounter          int
+	cmHandlerCounter        int
+	defaultLogLevel         log.Level
+	globalsHandlers         []GlobalsHandler
+	globalsLock             sync.Mutex
+	reloadLimiters          map[string]*reloadLimiter
+	syncStates              map[string]*syncState
+	adminToken              string
+	tmpFiles                []ChanEvent
+	notifier                *notifier.Notifier
+	auditLog                *audit.Log
+	tracer                  *sdktrace.TracerProvider
+	badConfig               []byte
 }
 
-var (
-	handlerCounter   = 0
-	cmHandlerCounter = 0
-)
-
 func (bc *ButlerConfig) SetScheme(s string) error {
 	var (
 		res error
@@ -139,7 +158,6 @@ func (bc *ButlerConfig) SetTimeout(t int) error {
 
 func (bc *ButlerConfig) SetLogLevel(level log.Level) {
 	log.SetLevel(level)
-	log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
 	bc.LogLevel = level
 	log.Debugf("Config::SetLogLevel(): setting log level to %s", level)
 }
@@ -165,44 +183,103 @@ func (bc *ButlerConfig) Init() error {
 	return nil
 }
 
-func (bc *ButlerConfig) Handler() error {
-	log.Infof("ButlerConfig::Handler()[count=%v]: entering.", handlerCounter)
-	response, err := bc.Client.Get(bc.URL())
+// Context returns the root context of butler, along with the provider of its
+// spans.
+func (bc *ButlerConfig) Context() context.Context {
+	ctx := bc.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracing.WithProvider(ctx, bc.getTracer())
+}
+
+// Fetch retrieves the raw butler configuration.
+func (bc *ButlerConfig) Fetch() ([]byte, error) {
+	return bc.fetch(bc.Context())
+}
 
+func (bc *ButlerConfig) fetch(ctx context.Context) ([]byte, error) {
+	response, err := bc.Client.Get(ctx, bc.URL())
 	if err != nil {
-		log.Errorf("ButlerConfig::Handler()[count=%v]: Cannot retrieve butler configuration. err=%s", handlerCounter, err.Error())
-		log.Errorf("ButlerConfig::Handler()[count=%v]: done.", handlerCounter)
-		handlerCounter++
-		metrics.SetButlerContactVal(metrics.FAILURE, bc.Host(), bc.Path())
-		return err
+		return nil, err
 	}
 	defer response.GetResponseBody().Close()
 
 	if response.GetResponseStatusCode() != 200 {
-		metrics.SetButlerContactVal(metrics.FAILURE, bc.Host(), bc.Path())
-		log.Errorf("ButlerConfig::Handler()[count=%v]: Did not receive 200 response code for %s. code=%d", handlerCounter, bc.URL().String(), response.GetResponseStatusCode())
-		log.Errorf("ButlerConfig::Handler()[count=%v] done.", handlerCounter)
-		handlerCounter++
 		errMsg := fmt.Sprintf("Did not receive 200 response code for %s. code=%d", bc.URL().String(), response.GetResponseStatusCode())
-		return errors.New(errMsg)
+		return nil, errors.New(errMsg)
 	}
 
 	body, err := ioutil.ReadAll(response.GetResponseBody())
 	if err != nil {
-		metrics.SetButlerContactVal(metrics.FAILURE, bc.Host(), bc.Path())
-		log.Errorf("ButlerConfig::Handler()[count=%v]: Could not read response body for %s. err=%s", handlerCounter, bc.URL().String(), err)
-		log.Errorf("ButlerConfig::Handler()[count=%v] done.", handlerCounter)
-		handlerCounter++
 		errMsg := fmt.Sprintf("Could not read response body for %s. err=%s", bc.URL().String(), err)
-		return errors.New(errMsg)
+		return nil, errors.New(errMsg)
+	}
+	return body, nil
+}
+
+func (bc *ButlerConfig) Handler() error {
+	bc.runLock.Lock()
+	prev := bc.getGlobals()
+	ctx, span := tracing.Start(bc.Context(), "ButlerConfig.Handler", tracing.CountKey.Int(bc.handlerCounter))
+	err := bc.handler(ctx)
+	tracing.End(span, err)
+	cur := bc.getGlobals()
+	bc.runLock.Unlock()
+
+	// The globals are applied once the run lock is released, so that the
+	// handlers may make use of the butler configuration.
+	if err == nil {
+		bc.applyGlobals(prev, cur)
 	}
+	return err
+}
 
-	err = ValidateConfig(NewValidateOpts().WithData(body).WithFileName("butler.toml").WithManager("butler-config"))
+// Load fetches, and parses, the butler configuration, but unlike Handler it
+// leaves the globals unapplied and starts no watches, so that nothing is
+// opened, written or sent. It is meant for the one-off modes, such as the dry
+// run.
+func (bc *ButlerConfig) Load() error {
+	bc.runLock.Lock()
+	defer bc.runLock.Unlock()
+	body, err := bc.fetch(bc.Context())
 	if err != nil {
+		return err
+	}
+	if err := ValidateConfig(NewValidateOpts().WithData(body).WithFileName("butler.toml").WithManager("butler-config")); err != nil {
+		return err
+	}
+	if err := bc.Config.ParseConfig(body); err != nil {
+		return err
+	}
+	bc.RawConfig = body
+	return nil
+}
+
+func (bc *ButlerConfig) handler(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	log.WithField("count", bc.handlerCounter).Info("ButlerConfig::Handler(): entering.")
+	body, err := bc.fetch(ctx)
+	if err != nil {
+		log.WithField("count", bc.handlerCounter).Errorf("ButlerConfig::Handler(): Cannot retrieve butler configuration. err=%s", err.Error())
+		log.WithField("count", bc.handlerCounter).Error("ButlerConfig::Handler(): done.")
+		bc.handlerCounter++
 		metrics.SetButlerContactVal(metrics.FAILURE, bc.Host(), bc.Path())
 		return err
 	}
 
+	_, span := tracing.Start(ctx, "ValidateConfig", tracing.FileKey.String("butler.toml"))
+	err = ValidateConfig(NewValidateOpts().WithData(body).WithFileName("butler.toml").WithManager("butler-config").WithCount(bc.handlerCounter))
+	tracing.End(span, err)
+	if err != nil {
+		metrics.SetButlerContactVal(metrics.FAILURE, bc.Host(), bc.Path())
+		bc.notifyParseFailure(body, err)
+		return err
+	}
+
 	if bc.RawConfig == nil {
 		err := bc.Config.ParseConfig(body)
 		if err != nil {
@@ -210,11 +287,14 @@ func (bc *ButlerConfig) Handler() error {
 				log.Fatal(err)
 			} else {
 				metrics.SetButlerContactVal(metrics.FAILURE, bc.Host(), bc.Path())
+				bc.notifyParseFailure(body, err)
 				return err
 			}
 		} else {
-			log.Debugf("ButlerConfig::Handler()[count=%v]: bc.RawConfig is nil. Filling it up.", handlerCounter)
+			log.WithField("count", bc.handlerCounter).Debug("ButlerConfig::Handler(): bc.RawConfig is nil. Filling it up.")
 			bc.RawConfig = body
+			bc.loadPausedManagers()
+			bc.StartManagerWatches()
 		}
 	}
 
@@ -225,20 +305,25 @@ func (bc *ButlerConfig) Handler() error {
 				log.Fatal(err)
 			} else {
 				metrics.SetButlerContactVal(metrics.FAILURE, bc.Host(), bc.Path())
+				bc.notifyParseFailure(body, err)
 				return err
 			}
 		} else {
-			log.Infof("ButlerConfig::Handler()[count=%v]: butler config has changed. updating.", handlerCounter)
+			log.WithField("count", bc.handlerCounter).Info("ButlerConfig::Handler(): butler config has changed. updating.")
 			bc.RawConfig = body
+			bc.loadPausedManagers()
+			bc.StartManagerWatches()
 		}
 	} else {
 		if !bc.FirstRun {
-			log.Infof("ButlerConfig::Handler()[count=%v]: butler config unchanged.", handlerCounter)
+			log.WithField("count", bc.handlerCounter).Info("ButlerConfig::Handler(): butler config unchanged.")
 		}
 	}
 
+	bc.badConfig = nil
+
 	// We don't want to handle the scheduler stuff on the first run. The scheduler doesn't yet exist
-	log.Debugf("ButlerConfig::Handler()[count=%v]: CM PrevSchedulerInterval=%v SchedulerInterval=%v", handlerCounter, bc.GetCMPrevInterval(), bc.GetCMInterval())
+	log.WithField("count", bc.handlerCounter).Debugf("ButlerConfig::Handler(): CM PrevSchedulerInterval=%v SchedulerInterval=%v", bc.GetCMPrevInterval(), bc.GetCMInterval())
 
 	// This is going to manage the CM scheduler. If it changes in the butler configuration, we should be aware of it.
 	if bc.FirstRun {
@@ -247,56 +332,166 @@ func (bc *ButlerConfig) Handler() error {
 		// If we need to start the scheduler, then let's do that
 		// If PrevInterval == 0, then no scheduler has been started
 		if bc.GetCMPrevInterval() == 0 {
-			log.Debugf("ButlerConfig::Handler()[count=%v]: starting scheduler for RunCMHandler each %v seconds", handlerCounter, bc.GetCMInterval())
+			log.WithField("count", bc.handlerCounter).Debugf("ButlerConfig::Handler(): starting scheduler for RunCMHandler each %v seconds", bc.GetCMInterval())
 			bc.Scheduler.Every(uint64(bc.GetCMInterval())).Seconds().Do(bc.RunCMHandler)
 			bc.SetCMPrevInterval(bc.GetCMInterval())
 		}
 		// If PrevInterval is > 0 and the Intervals differ, then the configuration has changed.
 		// We should restart the scheduler
 		if (bc.GetCMPrevInterval() != 0) && (bc.GetCMPrevInterval() != bc.GetCMInterval()) {
-			log.Debugf("ButlerConfig::Handler()[count=%v]: butler CM interval has changed from %v to %v", handlerCounter, bc.GetCMPrevInterval(), bc.GetCMInterval())
-			log.Debugf("ButlerConfig::Handler()[count=%v]: stopping current butler scheduler for RunCMHandler", handlerCounter)
+			log.WithField("count", bc.handlerCounter).Debugf("ButlerConfig::Handler(): butler CM interval has changed from %v to %v", bc.GetCMPrevInterval(), bc.GetCMInterval())
+			log.WithField("count", bc.handlerCounter).Debug("ButlerConfig::Handler(): stopping current butler scheduler for RunCMHandler")
 			bc.Scheduler.Remove(bc.RunCMHandler)
-			log.Debugf("ButlerConfig::Handler()[count=%v]: re-starting scheduler for RunCMHandler each %v seconds", handlerCounter, bc.GetCMInterval())
+			log.WithField("count", bc.handlerCounter).Debugf("ButlerConfig::Handler(): re-starting scheduler for RunCMHandler each %v seconds", bc.GetCMInterval())
 			bc.Scheduler.Every(uint64(bc.GetCMInterval())).Seconds().Do(bc.RunCMHandler)
 			bc.SetCMPrevInterval(bc.GetCMInterval())
 		}
 	}
 	metrics.SetButlerContactVal(metrics.SUCCESS, bc.Host(), bc.Path())
-	log.Infof("ButlerConfig::Handler()[count=%v]: done.", handlerCounter)
-	handlerCounter++
+	log.WithField("count", bc.handlerCounter).Info("ButlerConfig::Handler(): done.")
+	bc.handlerCounter++
 	return nil
 }
 
+// notifyParseFailure notifies that the butler configuration in body could
+// not be parsed. The same configuration is only notified of once, since it is
+// fetched again on every run of the handler.
+func (bc *ButlerConfig) notifyParseFailure(body []byte, err error) {
+	if bytes.Equal(bc.badConfig, body) {
+		return
+	}
+	bc.badConfig = body
+	bc.notify(notifier.Event{Type: notifier.EventConfigParseFailure, Error: err.Error()})
+}
+
 func (bc *ButlerConfig) SetScheduler(s *gocron.Scheduler) error {
 	log.Debugf("Config::SetScheduler(): entering")
 	bc.Scheduler = s
 	return nil
 }
 
+// RunCMHandler runs the configuration management handler for all of the
+// configured managers.
 func (bc *ButlerConfig) RunCMHandler() error {
+	_, err := bc.Run()
+	return err
+}
+
+// Run runs the configuration management handler for all of the configured
+// managers, and returns the outcome of the run. It does not overlap with any
+// other run.
+func (bc *ButlerConfig) Run() (*RunResult, error) {
+	bc.runLock.Lock()
+	defer bc.runLock.Unlock()
+	return bc.runCMHandler(bc.Context(), bc.GetManagers())
+}
+
+// ErrUnknownManager is returned, wrapped along with the name, by the
+// operations on a single manager when there is no such manager.
+var ErrUnknownManager = errors.New("unknown manager")
+
+// RunManager runs the configuration management handler for a single manager,
+// and returns the outcome of the run. It does not overlap with any other run.
+func (bc *ButlerConfig) RunManager(name string) (*RunResult, error) {
+	bc.runLock.Lock()
+	defer bc.runLock.Unlock()
+	m := bc.GetManager(name)
+	if m == nil {
+		return nil, fmt.Errorf("%w %v", ErrUnknownManager, name)
+	}
+	return bc.runCMHandler(bc.Context(), map[string]*Manager{name: m})
+}
+
+// runManagers runs the named managers together, so that they are reloaded in
+// the order of their dependencies. Managers which no longer exist, since the
+// butler configuration has changed, are left out.
+func (bc *ButlerConfig) runManagers(names []string) (*RunResult, error) {
+	bc.runLock.Lock()
+	defer bc.runLock.Unlock()
+	managers := make(map[string]*Manager)
+	for _, name := range names {
+		if m := bc.GetManager(name); m != nil {
+			managers[name] = m
+		}
+	}
+	if len(managers) == 0 {
+		return nil, fmt.Errorf("%w %v", ErrUnknownManager, strings.Join(names, ", "))
+	}
+	return bc.runCMHandler(bc.Context(), managers)
+}
+
+func (bc *ButlerConfig) runCMHandler(ctx context.Context, managers map[string]*Manager) (*RunResult, error) {
 	var (
 		ReloadManager []string
 	)
-	log.Infof("Config::RunCMHandler()[count=%v]: entering.", cmHandlerCounter)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, span := tracing.Start(ctx, "ButlerConfig.RunCMHandler", tracing.CountKey.Int(bc.cmHandlerCounter))
+	defer span.End()
+	defer bc.cleanTmpFiles()
+	start := time.Now()
+	log.WithField("count", bc.cmHandlerCounter).Info("Config::RunCMHandler(): entering.")
+	result := NewRunResult(bc.cmHandlerCounter)
 
 	c1 := make(chan ChanEvent)
 	c2 := make(chan ChanEvent)
 
-	bc.CheckPaths()
-
-	for _, m := range bc.GetManagers() {
-		go m.DownloadPrimaryConfigFiles(c1)
-		go m.DownloadAdditionalConfigFiles(c2)
+	// Paused managers are skipped entirely. No downloads, no writes and no reloads.
+	active := make(map[string]*Manager)
+	for name, m := range managers {
+		m.Paused = GetManagerPaused(bc.GetStatusFile(), m.Name)
+		metrics.SetButlerManagerPaused(m.Paused, m.Name)
+		if m.Paused {
+			logEntry(bc.cmHandlerCounter, m.Name).Info("Config::RunCMHandler(): manager is paused. skipping.")
+			result.AddManager(m.Name).Paused = true
+			continue
+		}
+		active[name] = m
+	}
+	managers = active
+
+	bc.checkPaths(managers)
+
+	// Managers are updated, and reloaded, after the managers they depend on.
+	ordered := orderManagers(managers)
+	for _, m := range ordered {
+		mr := result.AddManager(m.Name)
+		mr.applied, m.removed = m.removed, nil
+		m.SetCounter(bc.cmHandlerCounter)
+		if ctx.Err() != nil {
+			mr.SetError("skipped, butler is shutting down")
+			continue
+		}
+		if dep := failedDependency(m, result); dep != "" {
+			logEntry(bc.cmHandlerCounter, m.Name).Warnf("Config::RunCMHandler(): dependency %v failed to update. holding back.", dep)
+			mr.SetError(fmt.Sprintf("held back, dependency %v failed to update", dep))
+			continue
+		}
+		go m.DownloadPrimaryConfigFiles(ctx, c1)
+		go m.DownloadAdditionalConfigFiles(ctx, c2)
 		PrimaryChan, AdditionalChan := <-c1, <-c2
+		bc.trackTmpFiles(PrimaryChan, AdditionalChan)
+		mr.AddFiles(PrimaryChan)
+		mr.AddFiles(AdditionalChan)
+
+		// Once the files start being copied, the copy is finished, so that a
+		// shutdown does not leave half of the files updated.
+		if ctx.Err() != nil {
+			logEntry(bc.cmHandlerCounter, m.Name).Info("Config::RunCMHandler(): butler is shutting down. cleaning up...")
+			PrimaryChan.CleanTmpFiles()
+			AdditionalChan.CleanTmpFiles()
+			mr.SetError("skipped, butler is shutting down")
+			continue
+		}
 
 		if PrimaryChan.CanCopyFiles() && AdditionalChan.CanCopyFiles() {
-			log.Debugf("Config::RunCMHandler()[count=%v]: successfully retrieved files. processing...", cmHandlerCounter)
+			logEntry(bc.cmHandlerCounter, m.Name).Debug("Config::RunCMHandler(): successfully retrieved files. processing...")
 
 			// Check if watch-only mode is enabled for this manager
 			if m.WatchOnly {
 				// Watch-only mode: compare hashes without writing files
-				log.Debugf("Config::RunCMHandler()[count=%v][manager=%v]: using watch-only mode", cmHandlerCounter, m.Name)
+				logEntry(bc.cmHandlerCounter, m.Name).Debug("Config::RunCMHandler(): using watch-only mode")
 
 				// Initialize hash map if nil
 				if m.FileHashes == nil {
@@ -309,6 +504,10 @@ func (bc *ButlerConfig) RunCMHandler() error {
 				aChanged, aHashes := AdditionalChan.CompareAdditionalConfigHashes(m.FileHashes)
 
 				// Merge the new hashes back
+				before := make(map[string]string)
+				for k, v := range m.FileHashes {
+					before[k] = v
+				}
 				for k, v := range pHashes {
 					m.FileHashes[k] = v
 				}
@@ -318,14 +517,31 @@ func (bc *ButlerConfig) RunCMHandler() error {
 
 				if pChanged || aChanged {
 					ReloadManager = append(ReloadManager, m.Name)
-					log.Infof("Config::RunCMHandler()[count=%v][manager=%v]: watch-only mode detected changes, will trigger reload", cmHandlerCounter, m.Name)
+					mr.Changed = true
+					logEntry(bc.cmHandlerCounter, m.Name).Info("Config::RunCMHandler(): watch-only mode detected changes, will trigger reload")
+					bc.notify(notifier.Event{Type: notifier.EventFilesChanged, Manager: m.Name, Files: changedFiles(before, m.FileHashes)})
 				}
 			} else {
-				// Normal mode: copy files to destination
+				// Normal mode: copy files to destination. The files are only
+				// hashed when there is someone to notify of the changes, or
+				// an audit log to record them in.
+				var before map[string]string
+				if bc.getNotifier() != nil || bc.getAuditLog() != nil {
+					before = hashFiles(bc.Config.GetAllConfigLocalPaths(m.Name))
+				}
+				_, span := tracing.Start(ctx, "Manager.CopyConfigFiles", tracing.ManagerKey.String(m.Name))
 				p := PrimaryChan.CopyPrimaryConfigFiles(m.ManagerOpts)
 				a := AdditionalChan.CopyAdditionalConfigFiles(m.DestPath)
+				span.SetAttributes(tracing.ChangedKey.Bool(p || a))
+				span.End()
 				if p || a {
 					ReloadManager = append(ReloadManager, m.Name)
+					mr.Changed = true
+					if before != nil {
+						after := hashFiles(bc.Config.GetAllConfigLocalPaths(m.Name))
+						bc.notify(notifier.Event{Type: notifier.EventFilesChanged, Manager: m.Name, Files: changedFiles(before, after)})
+						mr.applied = append(mr.applied, auditFiles(before, after, m.fileSources())...)
+					}
 				}
 			}
 			PrimaryChan.CleanTmpFiles()
@@ -333,100 +549,208 @@ func (bc *ButlerConfig) RunCMHandler() error {
 			metrics.SetButlerRemoteRepoUp(metrics.SUCCESS, m.Name)
 			metrics.SetButlerRemoteRepoSanity(metrics.SUCCESS, m.Name)
 		} else {
-			log.Debugf("Config::RunCMHandler()[count=%v]: cannot copy files. cleaning up...", cmHandlerCounter)
+			logEntry(bc.cmHandlerCounter, m.Name).Debug("Config::RunCMHandler(): cannot copy files. cleaning up...")
 			// Failure statistics for RemoteRepoUp and RemoteRepoSanity
 			// happen in DownloadPrimaryConfigFiles // DownloadAdditionalConfigFiles
 			PrimaryChan.CleanTmpFiles()
 			AdditionalChan.CleanTmpFiles()
+			mr.SetError("could not retrieve all configuration files")
 		}
 		m.LastRun = time.Now()
 	}
 
 	if len(ReloadManager) == 0 {
-		log.Infof("Config::RunCMHandler()[count=%v]: CM files unchanged.", cmHandlerCounter)
+		log.WithField("count", bc.cmHandlerCounter).Info("Config::RunCMHandler(): CM files unchanged.")
 		// We are going to run through the managers and ensure that the status file
 		// is in an OK state for the manager. If it is not, then we will attempt a reload
-		for _, m := range bc.GetManagers() {
+		for _, m := range ordered {
 			metrics.SetButlerRepoInSync(metrics.SUCCESS, m.Name)
 			if !GetManagerStatus(bc.GetStatusFile(), m.Name) {
-				log.Debugf("Config::RunCMHandler()[count=%v]: Could not find manager status. Going to reload to get in sync.", cmHandlerCounter)
-				err := m.Reload()
-				if err != nil {
-					switch e := err.(type) {
-					case *reloaders.ReloaderError:
-						// an http timeout is 1
-						log.Debugf("Config::RunCMHandler()[count=%v]: e.Code=%#v, m.ManagerTimeoutOk=%#v", cmHandlerCounter, e.Code, m.ManagerTimeoutOk)
-						if e.Code == 1 && m.ManagerTimeoutOk == true {
-							// we really don't care about here
-							// let's make sure we at least delete our metrics
-							metrics.DeleteButlerReloadVal(m.Name)
-						} else {
-							log.Errorf("Config::RunCMHandler()[count=%v]: err=%#v", cmHandlerCounter, err)
-							err := SetManagerStatus(bc.GetStatusFile(), m.Name, false)
-							if err != nil {
-								log.Fatalf("Config::RunCMHandler()[count=%v]: could not write to %v err=%v", cmHandlerCounter, bc.GetStatusFile(), err.Error())
-							}
-							metrics.SetButlerReloadVal(metrics.FAILURE, m.Name)
-							if m.EnableCache && m.GoodCache {
-								RestoreCachedConfigs(m.Name, bc.Config.GetAllConfigLocalPaths(m.Name), m.CleanFiles)
-							}
-						}
-					}
-				} else {
-					err := SetManagerStatus(bc.GetStatusFile(), m.Name, true)
-					if err != nil {
-						log.Fatalf("Config::RunCMHandler()[count=%v]: could not write to %v err=%v", cmHandlerCounter, bc.GetStatusFile(), err.Error())
-					}
-					metrics.SetButlerReloadVal(metrics.SUCCESS, m.Name)
-					if m.EnableCache {
-						CacheConfigs(m.Name, bc.Config.GetAllConfigLocalPaths(m.Name))
-						m.GoodCache = true
-					}
-				}
+				logEntry(bc.cmHandlerCounter, m.Name).Debug("Config::RunCMHandler(): Could not find manager status. Going to reload to get in sync.")
+				bc.reloadManager(ctx, m, result.Managers[m.Name])
 			}
 		}
 	} else {
-		log.Debugf("Config::RunCMHandler()[count=%v]: CM files changed... reloading.", cmHandlerCounter)
+		log.WithField("count", bc.cmHandlerCounter).Debug("Config::RunCMHandler(): CM files changed... reloading.")
 		for _, m := range ReloadManager {
-			log.Debugf("Config::RunCMHandler()[count=%v]: m=%#v", cmHandlerCounter, m)
-			mgr := bc.GetManager(m)
-			err := mgr.Reload()
-			if err != nil {
-				switch e := err.(type) {
-				case *reloaders.ReloaderError:
-					log.Debugf("Config::RunCMHandler()[count=%v]: e.Code=%#v, mgr.ManagerTimeoutOk=%#v", cmHandlerCounter, e.Code, mgr.ManagerTimeoutOk)
-					if e.Code == 1 && mgr.ManagerTimeoutOk == true {
-						// we really don't care about here, but
-						// let's make sure we at least delete our metrics
-						metrics.DeleteButlerReloadVal(mgr.Name)
-					} else {
-						log.Errorf("Config::RunCMHandler()[count=%v]: Could not reload manager \"%v\" err=%#v", cmHandlerCounter, mgr.Name, err)
-						err := SetManagerStatus(bc.GetStatusFile(), m, false)
-						if err != nil {
-							log.Fatalf("Config::RunCMHandler()[count=%v]: could not write to %v err=%v", cmHandlerCounter, bc.GetStatusFile(), err.Error())
-						}
-						metrics.SetButlerReloadVal(metrics.FAILURE, m)
-						if mgr.EnableCache && mgr.GoodCache {
-							RestoreCachedConfigs(m, bc.Config.GetAllConfigLocalPaths(mgr.Name), mgr.CleanFiles)
-						}
-					}
-				}
+			logEntry(bc.cmHandlerCounter, m).Debug("Config::RunCMHandler(): reloading.")
+			bc.reloadManager(ctx, bc.GetManager(m), result.Managers[m])
+		}
+	}
+	bc.observeSync(ordered, result, time.Now())
+	bc.observeRun(result, time.Since(start))
+	bc.writeAudit(result)
+	log.WithField("count", bc.cmHandlerCounter).Info("Config::RunCMHandler(): done.")
+	bc.cmHandlerCounter++
+	return result, nil
+}
+
+// observeRun counts the run, and the updates of the managers which are not
+// paused, by their outcome.
+func (bc *ButlerConfig) observeRun(result *RunResult, d time.Duration) {
+	for _, mr := range result.Managers {
+		if mr.Paused {
+			continue
+		}
+		res := metrics.SUCCESS
+		if !mr.Success {
+			res = metrics.FAILURE
+		}
+		metrics.IncButlerManagerRun(res, mr.Name)
+	}
+	res := metrics.SUCCESS
+	if !result.Success {
+		res = metrics.FAILURE
+	}
+	metrics.ObserveButlerRun(res, d)
+}
+
+// PauseManager pauses the named manager. It waits for any run in flight to
+// complete, so that once it returns the manager is no longer touched.
+func (bc *ButlerConfig) PauseManager(name string) error {
+	return bc.setManagerPaused(name, true)
+}
+
+// ResumeManager resumes the named manager. It is picked up again on the
+// next run.
+func (bc *ButlerConfig) ResumeManager(name string) error {
+	return bc.setManagerPaused(name, false)
+}
+
+// loadPausedManagers picks up the paused state of every manager from the
+// status file, since the managers are re-created whenever the butler
+// configuration is parsed.
+func (bc *ButlerConfig) loadPausedManagers() {
+	for _, m := range bc.GetManagers() {
+		m.Paused = GetManagerPaused(bc.GetStatusFile(), m.Name)
+		metrics.SetButlerManagerPaused(m.Paused, m.Name)
+	}
+}
+
+func (bc *ButlerConfig) setManagerPaused(name string, paused bool) error {
+	bc.runLock.Lock()
+	defer bc.runLock.Unlock()
+	m := bc.GetManager(name)
+	if m == nil {
+		return fmt.Errorf("%w %v", ErrUnknownManager, name)
+	}
+	if err := SetManagerPaused(bc.GetStatusFile(), name, paused); err != nil {
+		return fmt.Errorf("could not write to %v err=%v", bc.GetStatusFile(), err)
+	}
+	m.Paused = paused
+	metrics.SetButlerManagerPaused(paused, name)
+	log.WithField("manager", name).Infof("Config::setManagerPaused(): paused=%v", paused)
+	return nil
+}
+
+// reloadManager reloads mgr, and takes care of the status file, metrics and
+// configuration cache depending on the outcome. The outcome is recorded in mr.
+func (bc *ButlerConfig) reloadManager(ctx context.Context, mgr *Manager, mr *ManagerResult) {
+	// The files may have been updated, so the manager is marked as out of
+	// sync in the status file, which gets it reloaded on the next start.
+	if ctx.Err() != nil {
+		logEntry(bc.cmHandlerCounter, mgr.Name).Warn("Config::RunCMHandler(): butler is shutting down. skipping reload until the next start.")
+		mr.SetError("reload skipped, butler is shutting down")
+		mr.reload = &audit.Reload{Outcome: audit.ReloadSkipped, Error: mr.Error}
+		if err := SetManagerStatus(bc.GetStatusFile(), mgr.Name, false); err != nil {
+			logEntry(bc.cmHandlerCounter, mgr.Name).Fatalf("Config::RunCMHandler(): could not write to %v err=%v", bc.GetStatusFile(), err.Error())
+		}
+		return
+	}
+
+	// The files have been updated, but the manager is not reloaded against a
+	// dependency which failed to update. They are rolled back if possible, and
+	// the manager is marked as out of sync, which gets it reloaded on the
+	// next run.
+	if dep := failedDependency(mgr, mr.run); dep != "" {
+		logEntry(bc.cmHandlerCounter, mgr.Name).Warnf("Config::RunCMHandler(): dependency %v failed to update. holding back reload.", dep)
+		mr.SetError(fmt.Sprintf("reload held back, dependency %v failed to update", dep))
+		mr.reload = &audit.Reload{Outcome: audit.ReloadHeldBack, Error: mr.Error}
+		if err := SetManagerStatus(bc.GetStatusFile(), mgr.Name, false); err != nil {
+			logEntry(bc.cmHandlerCounter, mgr.Name).Fatalf("Config::RunCMHandler(): could not write to %v err=%v", bc.GetStatusFile(), err.Error())
+		}
+		bc.rollback(mgr, mr.Error)
+		return
+	}
+
+	// A dependency which has its reload deferred has not applied its files
+	// yet, so the reload waits for the deferred reload of the dependency, and
+	// runs right after it.
+	if dep := deferredDependency(mgr, mr.run); dep != "" {
+		logEntry(bc.cmHandlerCounter, mgr.Name).Infof("Config::RunCMHandler(): reload of dependency %v is deferred. deferring reload.", dep)
+		mr.Deferred = true
+		mr.reload = &audit.Reload{Outcome: audit.ReloadDeferred}
+		if err := SetManagerStatus(bc.GetStatusFile(), mgr.Name, false); err != nil {
+			logEntry(bc.cmHandlerCounter, mgr.Name).Fatalf("Config::RunCMHandler(): could not write to %v err=%v", bc.GetStatusFile(), err.Error())
+		}
+		bc.deferDependant(mgr, dep)
+		return
+	}
+
+	// The files have been updated, but the reload waits until the reload
+	// limits of the manager allow for it. The manager is marked as out of sync
+	// in the meantime.
+	limiter := bc.reloadLimiter(mgr.Name)
+	if wait := limiter.wait(mgr, time.Now()); wait > 0 {
+		logEntry(bc.cmHandlerCounter, mgr.Name).Infof("Config::RunCMHandler(): reload limits reached. deferring reload for %v.", wait.Round(time.Second))
+		mr.Deferred = true
+		mr.reload = &audit.Reload{Outcome: audit.ReloadDeferred}
+		if err := SetManagerStatus(bc.GetStatusFile(), mgr.Name, false); err != nil {
+			logEntry(bc.cmHandlerCounter, mgr.Name).Fatalf("Config::RunCMHandler(): could not write to %v err=%v", bc.GetStatusFile(), err.Error())
+		}
+		bc.deferReload(mgr, limiter, wait)
+		return
+	}
+	limiter.record(time.Now())
+	metrics.SetButlerReloadPending(false, mgr.Name)
+
+	start := time.Now()
+	err := mgr.Reload(ctx)
+	elapsed := time.Since(start)
+	mr.reload = &audit.Reload{Outcome: audit.ReloadSuccess, Duration: elapsed.Seconds()}
+	if err != nil {
+		mr.reload.Outcome, mr.reload.Error = audit.ReloadFailure, err.Error()
+		switch e := err.(type) {
+		case *reloaders.ReloaderError:
+			logEntry(bc.cmHandlerCounter, mgr.Name).Debugf("Config::RunCMHandler(): e.Code=%#v, mgr.ManagerTimeoutOk=%#v", e.Code, mgr.ManagerTimeoutOk)
+			if e.IsTimeout() && mgr.ManagerTimeoutOk == true {
+				// we really don't care about here, but
+				// let's make sure we at least delete our metrics
+				metrics.DeleteButlerReloadVal(mgr.Name)
+				metrics.ObserveButlerReload(metrics.ResultTimeout, mgr.Name, elapsed)
+				mr.reload.Outcome = audit.ReloadTimeout
 			} else {
-				err := SetManagerStatus(bc.GetStatusFile(), m, true)
+				logEntry(bc.cmHandlerCounter, mgr.Name).Errorf("Config::RunCMHandler(): Could not reload manager. err=%#v", err)
+				mr.SetError(err.Error())
+				err := SetManagerStatus(bc.GetStatusFile(), mgr.Name, false)
 				if err != nil {
-					log.Fatalf("Config::RunCMHandler()[count=%v]: could not write to %v err=%v", cmHandlerCounter, bc.GetStatusFile(), err.Error())
-				}
-				metrics.SetButlerReloadVal(metrics.SUCCESS, m)
-				if mgr.EnableCache {
-					CacheConfigs(m, bc.Config.GetAllConfigLocalPaths(mgr.Name))
-					mgr.GoodCache = true
+					logEntry(bc.cmHandlerCounter, mgr.Name).Fatalf("Config::RunCMHandler(): could not write to %v err=%v", bc.GetStatusFile(), err.Error())
 				}
+				metrics.SetButlerReloadVal(metrics.FAILURE, mgr.Name)
+				metrics.ObserveButlerReload(metrics.ResultFailure, mgr.Name, elapsed)
+				bc.notify(notifier.Event{Type: notifier.EventReloadFailure, Manager: mgr.Name, Error: mr.Error})
+				bc.rollback(mgr, mr.Error)
 			}
+		default:
+			mr.SetError(err.Error())
+			metrics.ObserveButlerReload(metrics.ResultFailure, mgr.Name, elapsed)
+			bc.notify(notifier.Event{Type: notifier.EventReloadFailure, Manager: mgr.Name, Error: err.Error()})
 		}
+	} else {
+		mr.Reloaded = true
+		err := SetManagerStatus(bc.GetStatusFile(), mgr.Name, true)
+		if err != nil {
+			logEntry(bc.cmHandlerCounter, mgr.Name).Fatalf("Config::RunCMHandler(): could not write to %v err=%v", bc.GetStatusFile(), err.Error())
+		}
+		metrics.SetButlerReloadVal(metrics.SUCCESS, mgr.Name)
+		metrics.SetButlerManagerLastReload(mgr.Name)
+		metrics.ObserveButlerReload(metrics.ResultSuccess, mgr.Name, elapsed)
+		if mgr.EnableCache {
+			mgr.CacheConfigs(bc.Config.GetAllConfigLocalPaths(mgr.Name))
+			mgr.GoodCache = true
+		}
+		bc.notify(notifier.Event{Type: notifier.EventReloadSuccess, Manager: mgr.Name})
 	}
-	log.Infof("Config::RunCMHandler()[count=%v]: done.", cmHandlerCounter)
-	cmHandlerCounter++
-	return nil
 }
 
 func (bc *ButlerConfig) GetManagers() map[string]*Manager {
@@ -434,6 +758,9 @@ func (bc *ButlerConfig) GetManagers() map[string]*Manager {
 }
 
 func (bc *ButlerConfig) GetManager(m string) *Manager {
+	if bc.Config == nil {
+		return nil
+	}
 	return bc.Config.Managers[m]
 }
 
@@ -442,8 +769,12 @@ func (bc *ButlerConfig) GetStatusFile() string {
 }
 
 func (bc *ButlerConfig) CheckPaths() error {
+	return bc.checkPaths(bc.Config.Managers)
+}
+
+func (bc *ButlerConfig) checkPaths(managers map[string]*Manager) error {
 	log.Debugf("Config::CheckPaths(): entering")
-	for _, m := range bc.Config.Managers {
+	for _, m := range managers {
 		// Skip path creation and cleanup in watch-only mode
 		if m.WatchOnly {
 			log.Debugf("Config::CheckPaths(): skipping path checks for manager %s (watch-only mode)", m.Name)
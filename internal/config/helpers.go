@@ -23,11 +23,14 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/adobe/butler/internal/auth"
 	"github.com/adobe/butler/internal/environment"
 	"github.com/adobe/butler/internal/methods"
 	"github.com/adobe/butler/internal/metrics"
+	"github.com/adobe/butler/internal/notifier"
 	"github.com/adobe/butler/internal/reloaders"
 
 	"github.com/Jeffail/gabs"
@@ -41,6 +44,12 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+// logEntry returns the log entry for a run of manager, which carries the run
+// count and the manager as fields.
+func logEntry(count int, manager string) *log.Entry {
+	return log.WithFields(log.Fields{"count": count, "manager": manager})
+}
+
 func IsValidScheme(s string) bool {
 	var (
 		Found = false
@@ -67,7 +76,7 @@ func ValidateConfig(opts *ValidateOpts) error {
 		contentTypeSwitch string
 	)
 
-	log.Debugf("ValidateConfig()[count=%v][manager=%v]: checking content-type=%v FileName=%v skip-butler-header=%v", cmHandlerCounter, opts.Manager, opts.ContentType, opts.FileName, opts.SkipButlerHeader)
+	logEntry(opts.Count, opts.Manager).Debugf("ValidateConfig(): checking content-type=%v FileName=%v skip-butler-header=%v", opts.ContentType, opts.FileName, opts.SkipButlerHeader)
 	f := opts.Data
 	switch t := f.(type) {
 	case *os.File:
@@ -75,21 +84,21 @@ func ValidateConfig(opts *ValidateOpts) error {
 
 		fd, err := os.Open(newf.Name())
 		if err != nil {
-			log.Errorf("ValidateConfig()[count=%v][manager=%v]: caught error on open err=%#v", cmHandlerCounter, opts.Manager, err.Error())
+			logEntry(opts.Count, opts.Manager).Errorf("ValidateConfig(): caught error on open err=%#v", err.Error())
 			return err
 		}
 		defer fd.Close()
 
 		fi, err := fd.Stat()
 		if err != nil {
-			log.Errorf("ValidateConfig()[count=%v][manager=%v]: caught error on stat err=%#v", cmHandlerCounter, opts.Manager, err.Error())
+			logEntry(opts.Count, opts.Manager).Errorf("ValidateConfig(): caught error on stat err=%#v", err.Error())
 			return err
 		}
 
 		data := make([]byte, fi.Size())
 		_, err = fd.Read(data)
 		if err != nil {
-			log.Errorf("ValidateConfig()[count=%v][manager=%v]: caught error on fd.Read() err=%#v", cmHandlerCounter, opts.Manager, err.Error())
+			logEntry(opts.Count, opts.Manager).Errorf("ValidateConfig(): caught error on fd.Read() err=%#v", err.Error())
 			return err
 		}
 
@@ -98,7 +107,7 @@ func ValidateConfig(opts *ValidateOpts) error {
 		newf := f.([]byte)
 		file = bytes.NewReader(newf)
 	default:
-		return fmt.Errorf("ValidateConfig()[count=%v][manager=%v]: unknown file type %s for %s", cmHandlerCounter, opts.Manager, t, f)
+		return fmt.Errorf("ValidateConfig(): unknown file type %s for %s", t, f)
 	}
 
 	if opts.ContentType == "auto" {
@@ -110,21 +119,21 @@ func ValidateConfig(opts *ValidateOpts) error {
 	switch contentTypeSwitch {
 	case "text":
 		if opts.SkipButlerHeader {
-			log.Debugf("ValidateConfig()[count=%v][manager=%v]: skipping butler header/footer validation for text content", cmHandlerCounter, opts.Manager)
+			logEntry(opts.Count, opts.Manager).Debug("ValidateConfig(): skipping butler header/footer validation for text content")
 			err = nil
 		} else {
-			err = runTextValidate(file, opts.Manager)
+			err = runTextValidate(file, opts.Count, opts.Manager)
 		}
 	case "json":
-		err = runJSONValidate(file, opts.Manager)
+		err = runJSONValidate(file, opts.Count, opts.Manager)
 	case "yaml":
-		err = runYamlValidate(file, opts.Manager, opts.SkipButlerHeader)
+		err = runYamlValidate(file, opts.Count, opts.Manager, opts.SkipButlerHeader)
 	default:
 		err = fmt.Errorf("unknown content type %s", opts.ContentType)
 	}
 
 	if err != nil {
-		log.Errorf("ValidateConfig()[count=%v][manager=%v]: returning err=%v for content-type=%v and FileName=%v", cmHandlerCounter, opts.Manager, err.Error(), opts.ContentType, opts.FileName)
+		logEntry(opts.Count, opts.Manager).Errorf("ValidateConfig(): returning err=%v for content-type=%v and FileName=%v", err.Error(), opts.ContentType, opts.FileName)
 		return err
 	}
 
@@ -133,7 +142,7 @@ func ValidateConfig(opts *ValidateOpts) error {
 	if !opts.SkipButlerHeader {
 		err = removeButlerHeaderFooter(opts.Data)
 		if err != nil {
-			log.Errorf("ValidateConfig()[count=%v][manager=%v]: returning err=%v for content-type=%v and FileName=%v", cmHandlerCounter, opts.Manager, err.Error(), opts.ContentType, opts.FileName)
+			logEntry(opts.Count, opts.Manager).Errorf("ValidateConfig(): returning err=%v for content-type=%v and FileName=%v", err.Error(), opts.ContentType, opts.FileName)
 		}
 	}
 	return err
@@ -195,7 +204,7 @@ func removeButlerHeaderFooter(file interface{}) error {
 	}
 }
 
-func runTextValidate(f *bytes.Reader, m string) error {
+func runTextValidate(f *bytes.Reader, count int, m string) error {
 	var (
 		//err error
 		configLine    string
@@ -227,17 +236,17 @@ func runTextValidate(f *bytes.Reader, m string) error {
 	}
 
 	if !isValidHeader && !isValidFooter {
-		return fmt.Errorf("runTextValidate()[count=%v][manager=%v]: Invalid butler header and footer", cmHandlerCounter, m)
+		return fmt.Errorf("runTextValidate(): Invalid butler header and footer")
 	} else if !isValidHeader {
-		return fmt.Errorf("runTextValidate()[count=%v][manager=%v]: Invalid butler header", cmHandlerCounter, m)
+		return fmt.Errorf("runTextValidate(): Invalid butler header")
 	} else if !isValidFooter {
-		return fmt.Errorf("runTextValidate()[count=%v][manager=%v]: Invalid butler footer", cmHandlerCounter, m)
+		return fmt.Errorf("runTextValidate(): Invalid butler footer")
 	} else {
 		return nil
 	}
 }
 
-func runJSONValidate(f *bytes.Reader, m string) error {
+func runJSONValidate(f *bytes.Reader, count int, m string) error {
 	var (
 		err  error
 		data []byte
@@ -245,19 +254,19 @@ func runJSONValidate(f *bytes.Reader, m string) error {
 
 	data, err = ioutil.ReadAll(f)
 	if err != nil {
-		msg := fmt.Sprintf("runJSONValidate()[count=%v][manager=%v], could not read data from bytes.Reader. err=%v", cmHandlerCounter, m, err.Error())
+		msg := fmt.Sprintf("runJSONValidate(), could not read data from bytes.Reader. err=%v", err.Error())
 		return errors.New(msg)
 	}
 
 	_, err = gabs.ParseJSON(data)
 	if err != nil {
-		msg := fmt.Sprintf("runJSONValidate()[count=%v][manager=%v], could not Unmarshal json data into interface. err=%v", cmHandlerCounter, m, err.Error())
+		msg := fmt.Sprintf("runJSONValidate(), could not Unmarshal json data into interface. err=%v", err.Error())
 		return errors.New(msg)
 	}
 	return nil
 }
 
-func runYamlValidate(f *bytes.Reader, m string, skipButlerHeader bool) error {
+func runYamlValidate(f *bytes.Reader, count int, m string, skipButlerHeader bool) error {
 	var (
 		err  error
 		data []byte
@@ -266,25 +275,25 @@ func runYamlValidate(f *bytes.Reader, m string, skipButlerHeader bool) error {
 
 	data, err = ioutil.ReadAll(f)
 	if err != nil {
-		msg := fmt.Sprintf("runYamlValidate()[count=%v][manager=%v]: could not read data from bytes.Reader. err=%v", cmHandlerCounter, m, err.Error())
+		msg := fmt.Sprintf("runYamlValidate(): could not read data from bytes.Reader. err=%v", err.Error())
 		return errors.New(msg)
 	}
 
 	err = yaml.Unmarshal(data, &v)
 	if err != nil {
-		msg := fmt.Sprintf("runYamlValidate()[count=%v][manager=%v]: could not Unmarshal yaml data into interface. err=%v", cmHandlerCounter, m, err.Error())
+		msg := fmt.Sprintf("runYamlValidate(): could not Unmarshal yaml data into interface. err=%v", err.Error())
 		return errors.New(msg)
 	}
 
 	// Skip butler header/footer validation if requested
 	if skipButlerHeader {
-		log.Debugf("runYamlValidate()[count=%v][manager=%v]: skipping butler header/footer validation", cmHandlerCounter, m)
+		logEntry(count, m).Debug("runYamlValidate(): skipping butler header/footer validation")
 		return nil
 	}
 
-	err = runTextValidate(bytes.NewReader(data), m)
+	err = runTextValidate(bytes.NewReader(data), count, m)
 	if err != nil {
-		msg := fmt.Sprintf("runYamlValidate()[count=%v][manager=%v]: could not verify butler header/footer for yaml data. err=%v", cmHandlerCounter, m, err.Error())
+		msg := fmt.Sprintf("runYamlValidate(): could not verify butler header/footer for yaml data. err=%v", err.Error())
 		return errors.New(msg)
 	}
 	return nil
@@ -372,19 +381,19 @@ func RenderConfigMustache(f *os.File, subs map[string]string) error {
 	return nil
 }
 
-func CompareAndCopy(source string, dest string, m string) bool {
+func CompareAndCopy(source string, dest string, count int, m string) bool {
 	// Let's compare the source and destination files
 	cmp := equalfile.New(nil, equalfile.Options{})
 	equal, err := cmp.CompareFile(source, dest)
 	if !equal {
 		if err != nil {
-			log.Errorf("helpers.CompareAndCopy()[count=%v][manager=%v]: caught error from compare. source=%v dest=%v err=%#v", cmHandlerCounter, m, source, dest, err)
+			logEntry(count, m).Errorf("helpers.CompareAndCopy(): caught error from compare. source=%v dest=%v err=%#v", source, dest, err)
 		}
-		log.Infof("helpers.CompareAndCopy()[count=%v][manager=%v]: Found difference in \"%s.\"  Updating.", cmHandlerCounter, m, dest)
+		logEntry(count, m).Infof("helpers.CompareAndCopy(): Found difference in \"%s.\"  Updating.", dest)
 		err = CopyFile(source, dest)
 		if err != nil {
 			metrics.SetButlerWriteVal(metrics.FAILURE, metrics.GetStatsLabel(dest))
-			log.Errorf("helpers.CompareAndCopy()[count=%v][manager=%v]: could not copy source=%v to dest=%v. err=%#v", cmHandlerCounter, m, source, dest, err)
+			logEntry(count, m).Errorf("helpers.CompareAndCopy(): could not copy source=%v to dest=%v. err=%#v", source, dest, err)
 			return false
 		}
 		metrics.SetButlerWriteVal(metrics.SUCCESS, metrics.GetStatsLabel(dest))
@@ -411,6 +420,16 @@ func ComputeFileHash(filePath string) (string, error) {
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// fileSize returns the size of the file at path, or 0 when it cannot be
+// stat'ed.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
 // ComputeDataHash computes the SHA256 hash of a byte slice and returns it as a hex string.
 // This is used in watch-only mode to hash downloaded content before comparison.
 func ComputeDataHash(data []byte) string {
@@ -421,16 +440,16 @@ func ComputeDataHash(data []byte) string {
 // CompareHashOnly compares the hash of a source file against a stored hash.
 // Returns true if the file has changed (hashes differ), false if unchanged.
 // This is used in watch-only mode instead of CompareAndCopy.
-func CompareHashOnly(source string, storedHash string, m string) (bool, string, error) {
+func CompareHashOnly(source string, storedHash string, count int, m string) (bool, string, error) {
 	newHash, err := ComputeFileHash(source)
 	if err != nil {
-		log.Errorf("helpers.CompareHashOnly()[count=%v][manager=%v]: could not compute hash for source=%v err=%#v", cmHandlerCounter, m, source, err)
+		logEntry(count, m).Errorf("helpers.CompareHashOnly(): could not compute hash for source=%v err=%#v", source, err)
 		return false, "", err
 	}
 
 	if storedHash == "" {
 		// First run - no stored hash, consider it changed
-		log.Infof("helpers.CompareHashOnly()[count=%v][manager=%v]: No stored hash for \"%s\". First run detected.", cmHandlerCounter, m, source)
+		logEntry(count, m).Infof("helpers.CompareHashOnly(): No stored hash for \"%s\". First run detected.", source)
 		return true, newHash, nil
 	}
 
@@ -444,11 +463,11 @@ func CompareHashOnly(source string, storedHash string, m string) (bool, string,
 		if len(newHash) > 16 {
 			newHashDisplay = newHash[:16] + "..."
 		}
-		log.Infof("helpers.CompareHashOnly()[count=%v][manager=%v]: Hash changed for \"%s\". Old=%s New=%s", cmHandlerCounter, m, source, oldHashDisplay, newHashDisplay)
+		logEntry(count, m).Infof("helpers.CompareHashOnly(): Hash changed for \"%s\". Old=%s New=%s", source, oldHashDisplay, newHashDisplay)
 		return true, newHash, nil
 	}
 
-	log.Debugf("helpers.CompareHashOnly()[count=%v][manager=%v]: Hash unchanged for \"%s\"", cmHandlerCounter, m, source)
+	logEntry(count, m).Debugf("helpers.CompareHashOnly(): Hash unchanged for \"%s\"", source)
 	return false, newHash, nil
 }
 
@@ -504,83 +523,12 @@ func CopyFile(src string, dst string) error {
 	return cerr
 }
 
-// CacheConfigs takes in a string of the base directory for
-// the config directory and a slice of config file names and
-// caches those files into memory. It returns an error
-// on the event of error
-func CacheConfigs(manager string, files []string) error {
-	log.Infof("helpers.CacheConfig()[count=%v][manager=%v]: Storing known good configurations to cache.", cmHandlerCounter, manager)
-	if ConfigCache == nil {
-		ConfigCache = make(map[string]map[string][]byte)
-	}
-	ConfigCache[manager] = make(map[string][]byte)
-	for _, file := range files {
-		out, err := ioutil.ReadFile(file)
-		if err != nil {
-			msg := fmt.Sprintf("helpers.CacheConfig()[count=%v][manager=%v]: Could not store %s to cache. err=%s", cmHandlerCounter, manager, file, err.Error())
-			log.Errorf(msg)
-			return errors.New(msg)
-		} else {
-			ConfigCache[manager][file] = out
-		}
-	}
-	log.Infof("helpers.CacheConfig()[count=%v][manager=%v]: Done storing known good configurations to cache.", cmHandlerCounter, manager)
-	metrics.SetButlerKnownGoodCachedVal(metrics.SUCCESS, manager)
-	metrics.SetButlerKnownGoodRestoredVal(metrics.FAILURE, manager)
-	return nil
-}
-
-// RestoreCachedConfigs takes in a strint of the base directory for
-// the config directory and a slice of config file names
-// and restores those files from the cache back to the
-// filesystem. It returns an error on the event of an error
-func RestoreCachedConfigs(manager string, files []string, cleanFiles bool) error {
-	// If we do not have a good configuration cache, then there's nothing for us to do.
-	if ConfigCache == nil {
-		if cleanFiles {
-			log.Infof("helpers.RestoreCachedConfigs()[count=%v][manager=%v]: No current known good configurations in cache. Cleaning configuration...", cmHandlerCounter, manager)
-			for _, file := range files {
-				log.Warnf("helpers.RestoreCachedConfigs()[count=%v][manager=%v]: Removing bad configuration file %s.", cmHandlerCounter, manager, file)
-				os.Remove(file)
-			}
-			log.Infof("helpers.RestoreCachedConfigs()[count=%v][manager=%v]: Done cleaning broken configuration. Returning...", cmHandlerCounter, manager)
-		}
-		metrics.SetButlerKnownGoodCachedVal(metrics.FAILURE, manager)
-		metrics.SetButlerKnownGoodRestoredVal(metrics.FAILURE, manager)
-		return nil
-	}
-
-	log.Warnf("helpers.RestoreCachedConfigs()[count=%v][manager=%v]: Restoring known good configurations from cache.", cmHandlerCounter, manager)
-	for _, file := range files {
-		fileData := ConfigCache[manager][file]
-
-		f, err := os.OpenFile(file, os.O_WRONLY|os.O_TRUNC, 0644)
-		if err != nil {
-			log.Errorf("helpers.RestoreCachedConfigs()[count=%v][manager=%v]: Could not open %s for writing! err=%s.", cmHandlerCounter, manager, file, err.Error())
-			continue
-		} else {
-			count, err := f.Write(fileData)
-			if err != nil {
-				log.Errorf("helpers.RestoreCachedConfigs()[count=%v][manager=%v]: Could not write to %s! err=%s.", cmHandlerCounter, manager, file, err.Error())
-				continue
-			} else {
-				f.Close()
-				log.Warnf("helpers.RestoreCachedConfigs()[count=%v][manager=%v]: Wrote %d bytes for %s.", cmHandlerCounter, manager, count, file)
-			}
-		}
-	}
-	log.Warnf("helpers.RestoreCachedConfigs()[count=%v][manager=%v]: Done restoring known good configurations from cache.", cmHandlerCounter, manager)
-	metrics.SetButlerKnownGoodCachedVal(metrics.FAILURE, manager)
-	metrics.SetButlerKnownGoodRestoredVal(metrics.SUCCESS, manager)
-	return nil
-}
-
 func GetManagerOpts(entry string, bc *ConfigSettings) (*ManagerOpts, error) {
 	var (
 		err     error
 		MgrOpts ManagerOpts
 	)
-	err = viper.UnmarshalKey(entry, &MgrOpts)
+	err = bc.getSource().UnmarshalKey(entry, &MgrOpts)
 	if err != nil {
 		return &ManagerOpts{}, err
 	}
@@ -643,7 +591,7 @@ func GetManagerOpts(entry string, bc *ConfigSettings) (*ManagerOpts, error) {
 	}
 
 	methodOpts := fmt.Sprintf("%s.%s", entry, MgrOpts.Method)
-	mopts, err := methods.New(&managerName, MgrOpts.Method, &methodOpts)
+	mopts, err := methods.New(bc.getSource(), &managerName, MgrOpts.Method, &methodOpts)
 	if err != nil {
 		return &ManagerOpts{}, err
 	}
@@ -662,7 +610,7 @@ func GetConfigManager(entry string, bc *ConfigSettings) error {
 	Mgr.ReloadManager = false
 	Mgr.GoodCache = false
 
-	err = viper.UnmarshalKey(entry, &Mgr)
+	err = bc.getSource().UnmarshalKey(entry, &Mgr)
 	if err != nil {
 		return err
 	}
@@ -707,11 +655,25 @@ func GetConfigManager(entry string, bc *ConfigSettings) error {
 		Mgr.WatchOnly = true
 		// Initialize the hash storage map for watch-only mode
 		Mgr.FileHashes = make(map[string]string)
-		log.Infof("helpers.GetConfigManager()[count=%v][manager=%v]: watch-only mode enabled", cmHandlerCounter, entry)
+		log.WithField("manager", entry).Info("helpers.GetConfigManager(): watch-only mode enabled")
 	} else {
 		Mgr.WatchOnly = false
 	}
 
+	// The reload limits are off unless they are set.
+	if v := environment.GetVar(Mgr.CfgMinReloadInterval); v != "" {
+		Mgr.MinReloadInterval, err = strconv.Atoi(v)
+		if err != nil || Mgr.MinReloadInterval < 0 {
+			return fmt.Errorf("manager.min-reload-interval=%v is not a number of seconds for manager %s", v, entry)
+		}
+	}
+	if v := environment.GetVar(Mgr.CfgMaxReloadsPerHour); v != "" {
+		Mgr.MaxReloadsPerHour, err = strconv.Atoi(v)
+		if err != nil || Mgr.MaxReloadsPerHour < 0 {
+			return fmt.Errorf("manager.max-reloads-per-hour=%v is not a number for manager %s", v, entry)
+		}
+	}
+
 	Mgr.CachePath = filepath.Clean(environment.GetVar(Mgr.CachePath))
 	if Mgr.EnableCache && Mgr.CachePath == "" {
 		msg := fmt.Sprintf("Caching Enabled but manager.cache-path is unset for manager %s", entry)
@@ -728,7 +690,7 @@ func GetConfigManager(entry string, bc *ConfigSettings) error {
 	// In watch-only mode, dest-path is optional but we'll set a default if not provided
 	if Mgr.WatchOnly && Mgr.DestPath == "." {
 		Mgr.DestPath = ""
-		log.Debugf("helpers.GetConfigManager()[count=%v][manager=%v]: watch-only mode - dest-path not required", cmHandlerCounter, entry)
+		log.WithField("manager", entry).Debug("helpers.GetConfigManager(): watch-only mode - dest-path not required")
 	}
 
 	Mgr.ManagerOpts = make(map[string]*ManagerOpts)
@@ -745,18 +707,18 @@ func GetConfigManager(entry string, bc *ConfigSettings) error {
 		bc.Managers[entry].ManagerOpts[mopts] = opts
 	}
 
-	reloader, err := reloaders.New(entry)
+	reloader, err := reloaders.New(bc.getSource(), entry)
 	if err != nil {
-		log.Warnf("helpers.GetConfigManager()[count=%v][manager=%v]: %v.", cmHandlerCounter, entry, err.Error())
+		log.WithField("manager", entry).Warnf("helpers.GetConfigManager(): %v.", err.Error())
 		reloader = nil
 		// If we've got no reloader for this manager, then there is no need to cache
-		log.Debugf("helpers.GetConfigManager()[count=%v][manager=%v]: No reloader has been defined for manager. Setting EnableCache to false", cmHandlerCounter, entry)
+		log.WithField("manager", entry).Debug("helpers.GetConfigManager(): No reloader has been defined for manager. Setting EnableCache to false")
 		Mgr.EnableCache = false
 	}
 
 	Mgr.MustacheSubs, err = ParseMustacheSubs(Mgr.MustacheSubsArray)
 	if err != nil {
-		log.Debugf("helpers.GetConfigManager()[count=%v][manager=%v]: could not get mustache subs. err=%s", cmHandlerCounter, entry, err.Error())
+		log.WithField("manager", entry).Debugf("helpers.GetConfigManager(): could not get mustache subs. err=%s", err.Error())
 		return err
 	}
 	m := bc.Managers[entry]
@@ -765,26 +727,31 @@ func GetConfigManager(entry string, bc *ConfigSettings) error {
 	return nil
 }
 
-func ParseConfig(config []byte) error {
+// ParseConfig parses the managers of the butler configuration, without any of
+// the checks on the globals that ConfigSettings.ParseConfig does. The returned
+// settings are set whenever the configuration could be read, even if parsing
+// the managers failed.
+func ParseConfig(config []byte) (*ConfigSettings, error) {
 	var (
 		//handlers []string
 		Config  ConfigSettings
 		Globals ConfigGlobals
 	)
 	// The  configuration is in TOML format
-	viper.SetConfigType("toml")
+	v := viper.New()
+	v.SetConfigType("toml")
 
 	// We grab the config from a remote repo so it's in []byte format. let's see
 	// if we can process it.
-	err := viper.ReadConfig(bytes.NewBuffer(config))
+	err := v.ReadConfig(bytes.NewBuffer(config))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	Config = ConfigSettings{}
+	Config = ConfigSettings{source: v}
 
 	// Let's start piecing together the globals
-	err = viper.UnmarshalKey("globals", &Globals)
+	err = v.UnmarshalKey("globals", &Globals)
 	if err != nil {
 		log.Fatalf("Unable to decode into struct, %v", err)
 	}
@@ -795,6 +762,15 @@ func ParseConfig(config []byte) error {
 		Config.Globals.SchedulerInterval = ConfigSchedulerInterval
 	}
 
+	if _, err := notifier.New(Config.Globals.Notifiers); err != nil {
+		if Config.Globals.ExitOnFailure {
+			log.Fatalf("ParseConfig(): globals.notifiers: %v! exiting...", err)
+		} else {
+			log.Debugf("ParseConfig(): globals.notifiers: %v", err)
+			return &Config, fmt.Errorf("globals.notifiers: %v", err)
+		}
+	}
+
 	log.Debugf("ParseConfig(): globals.config-managers=%#v", Config.Globals.Managers)
 	log.Debugf("ParseConfig(): len(globals.config-managers)=%v", len(Config.Globals.Managers))
 
@@ -804,20 +780,20 @@ func ParseConfig(config []byte) error {
 			log.Fatalf("ParseConfig(): globals.config-managers has no entries! exiting...")
 		} else {
 			log.Debugf("ParseConfig(): globals.config-managers has no entries!")
-			return errors.New("globals.config-managers has no entries. Nothing to do")
+			return &Config, errors.New("globals.config-managers has no entries. Nothing to do")
 		}
 	}
 
 	Config.Managers = make(map[string]*Manager)
 	// Now let's start processing the managers. This is going
 	for _, entry := range Config.Globals.Managers {
-		if !viper.IsSet(entry) {
+		if !v.IsSet(entry) {
 			if Config.Globals.ExitOnFailure {
 				log.Fatalf("ParseConfig(): %v is not in the configuration as a manager! exiting...", entry)
 			} else {
 				log.Debugf("ParseConfig(): %v is not in the configuration as a manager", entry)
 				msg := fmt.Sprintf("Cannot find manager for %s", entry)
-				return errors.New(msg)
+				return &Config, errors.New(msg)
 			}
 		} else {
 			err = GetConfigManager(entry, &Config)
@@ -827,14 +803,23 @@ func ParseConfig(config []byte) error {
 				} else {
 					log.Debugf("ParseConfig(): could not retrieve config options for %v. err=%v", entry, err.Error())
 					msg := fmt.Sprintf("could not retrieve config options for %v. err=%v", entry, err.Error())
-					return errors.New(msg)
+					return &Config, errors.New(msg)
 				}
 			}
 		}
 	}
 
+	if err := checkDependencies(Config.Managers); err != nil {
+		if Config.Globals.ExitOnFailure {
+			log.Fatalf("ParseConfig(): %v! exiting...", err.Error())
+		} else {
+			log.Debugf("ParseConfig(): %v", err.Error())
+			return &Config, err
+		}
+	}
+
 	log.Debugf("Config.Managers=%#v", Config.Managers)
-	return nil
+	return &Config, nil
 }
 
 func NewButlerConfig(opts *ButlerConfigOpts) (*ButlerConfig, error) {
@@ -842,7 +827,11 @@ func NewButlerConfig(opts *ButlerConfigOpts) (*ButlerConfig, error) {
 		cfg ButlerConfig
 	)
 	cfg.FirstRun = true
+	cfg.ctx = opts.Context
+	cfg.LogLevel = opts.LogLevel
+	cfg.defaultLogLevel = opts.LogLevel
 	cfg.InsecureSkipVerify = opts.InsecureSkipVerify
+	cfg.TLSOpts = opts.TLSOpts
 	cfg.url = opts.URL
 
 	if !IsValidScheme(cfg.Scheme()) {
@@ -864,7 +853,7 @@ func NewConfigChanEvent() *ConfigChanEvent {
 func NewConfigClient(bc *ButlerConfig) (*ConfigClient, error) {
 	var c ConfigClient
 	opts := bc.MethodOpts
-	method, err := methods.New(nil, opts.GetScheme(), nil)
+	method, err := methods.New(nil, nil, opts.GetScheme(), nil)
 	// we can skip this check if it's blob.
 	// should figure out a better way for this
 	if (err != nil) && (opts.GetScheme() != "blob") {
@@ -878,13 +867,23 @@ func NewConfigClient(bc *ButlerConfig) (*ConfigClient, error) {
 		}
 	}
 	log.Warnf("ButlerConfig::Init() Above \"NewHttpMethod(): could not convert\" warnings may be safely disregarded.")
+	tlsOpts := bc.TLSOpts
+	tlsOpts.InsecureSkipVerify = bc.InsecureSkipVerify
 	switch opts.GetScheme() {
 	case "http", "https":
 		o := opts.(methods.HTTPMethodOpts)
 		m := method.(methods.HTTPMethod)
 		m.AuthType = o.HTTPAuthType
 		m.AuthToken = o.HTTPAuthToken
-		m.InsecureSkipVerify = bc.InsecureSkipVerify
+		if o.HTTPAuthType == "bearer" {
+			m.Authorizer, err = auth.NewBearer(environment.GetVar(o.HTTPAuthToken), "")
+			if err != nil {
+				return &ConfigClient{}, err
+			}
+		}
+		if err := m.SetTLSOpts(tlsOpts); err != nil {
+			return &ConfigClient{}, err
+		}
 		c.Scheme = o.GetScheme()
 		c.HTTPClient = retryablehttp.NewClient()
 		c.HTTPClient.Logger = nil
@@ -917,10 +916,13 @@ func NewConfigClient(bc *ButlerConfig) (*ConfigClient, error) {
 	case "etcd":
 		o := opts.(methods.EtcdMethodOpts)
 		c.Scheme = o.GetScheme()
-		c.Method, err = methods.NewEtcdMethodWithEndpoints(o.Endpoints, bc.InsecureSkipVerify)
+		method, err := methods.NewEtcdMethodWithEndpoints(o.Endpoints, tlsOpts)
 		if err != nil {
 			return &ConfigClient{}, err
 		}
+		m := method.(methods.EtcdMethod)
+		m.Watch = o.Watch
+		c.Method = m
 	default:
 		errMsg := fmt.Sprintf("Unsupported butler config scheme: %s", opts.GetScheme())
 		return &ConfigClient{}, errors.New(errMsg)
@@ -931,3 +933,12 @@ func NewConfigClient(bc *ButlerConfig) (*ConfigClient, error) {
 func NewConfigSettings() *ConfigSettings {
 	return &ConfigSettings{}
 }
+
+// getSource returns the parsed configuration which the managers are read
+// from. Settings which have not been parsed have an empty source.
+func (c *ConfigSettings) getSource() *viper.Viper {
+	if c.source == nil {
+		return viper.New()
+	}
+	return c.source
+}
@@ -42,17 +42,17 @@ some text
 some more text`)
 	var testTextConfigBad3 = []byte(`some text
 some more text`)
-	c.Assert(runTextValidate(bytes.NewReader(testTextConfigGood), "test-manager"), IsNil)
-	c.Assert(runTextValidate(bytes.NewReader(testTextConfigBad1), "test-manager"), NotNil)
-	c.Assert(runTextValidate(bytes.NewReader(testTextConfigBad2), "test-manager"), NotNil)
-	c.Assert(runTextValidate(bytes.NewReader(testTextConfigBad3), "test-manager"), NotNil)
+	c.Assert(runTextValidate(bytes.NewReader(testTextConfigGood), 0, "test-manager"), IsNil)
+	c.Assert(runTextValidate(bytes.NewReader(testTextConfigBad1), 0, "test-manager"), NotNil)
+	c.Assert(runTextValidate(bytes.NewReader(testTextConfigBad2), 0, "test-manager"), NotNil)
+	c.Assert(runTextValidate(bytes.NewReader(testTextConfigBad3), 0, "test-manager"), NotNil)
 }
 
 func (s *ConfigTestSuite) TestrunJsonValidate(c *C) {
 	var testJSONConfigGood = []byte(`{"foo": "bar", "baz": ["one", "two", "three"] }`)
 	var testJSONConfigBad = []byte(`{"foo": "bar", ["one", "two", "three"] }`)
-	c.Assert(runJSONValidate(bytes.NewReader(testJSONConfigGood), "test-manager"), IsNil)
-	c.Assert(runJSONValidate(bytes.NewReader(testJSONConfigBad), "test-manager"), NotNil)
+	c.Assert(runJSONValidate(bytes.NewReader(testJSONConfigGood), 0, "test-manager"), IsNil)
+	c.Assert(runJSONValidate(bytes.NewReader(testJSONConfigBad), 0, "test-manager"), NotNil)
 }
 
 func (s *ConfigTestSuite) TestrunYamlValidate(c *C) {
@@ -78,9 +78,9 @@ http_200x:
   icmp:
     prober:icmp`)
 	// Test with skipButlerHeader = false (default behavior, requires headers)
-	c.Assert(runYamlValidate(bytes.NewReader(testYamlConfigGood), "test-manager", false), IsNil)
-	c.Assert(runYamlValidate(bytes.NewReader(testYamlConfigBad1), "test-manager", false), NotNil)
-	c.Assert(runYamlValidate(bytes.NewReader(testYamlConfigBad2), "test-manager", false), NotNil)
+	c.Assert(runYamlValidate(bytes.NewReader(testYamlConfigGood), 0, "test-manager", false), IsNil)
+	c.Assert(runYamlValidate(bytes.NewReader(testYamlConfigBad1), 0, "test-manager", false), NotNil)
+	c.Assert(runYamlValidate(bytes.NewReader(testYamlConfigBad2), 0, "test-manager", false), NotNil)
 
 	// Test with skipButlerHeader = true (skips header validation, only checks YAML syntax)
 	var testYamlNoHeaders = []byte(`modules:
@@ -89,9 +89,9 @@ http_200x:
     http:
   icmp:
     prober: icmp`)
-	c.Assert(runYamlValidate(bytes.NewReader(testYamlNoHeaders), "test-manager", true), IsNil)
+	c.Assert(runYamlValidate(bytes.NewReader(testYamlNoHeaders), 0, "test-manager", true), IsNil)
 	// Bad YAML syntax should still fail even with skipButlerHeader = true
-	c.Assert(runYamlValidate(bytes.NewReader(testYamlConfigBad2), "test-manager", true), NotNil)
+	c.Assert(runYamlValidate(bytes.NewReader(testYamlConfigBad2), 0, "test-manager", true), NotNil)
 }
 
 func (s *ConfigTestSuite) TestgetFileExtension(c *C) {
@@ -161,25 +161,25 @@ func (s *ConfigTestSuite) TestCompareHashOnly(c *C) {
 	tmpFile.Close()
 
 	// Test first run (empty stored hash) - should return changed=true
-	changed, newHash, err := CompareHashOnly(tmpFile.Name(), "", "test-manager")
+	changed, newHash, err := CompareHashOnly(tmpFile.Name(), "", 0, "test-manager")
 	c.Assert(err, IsNil)
 	c.Assert(changed, Equals, true)
 	c.Assert(len(newHash), Equals, 64)
 
 	// Test same hash - should return changed=false
-	changed, newHash2, err := CompareHashOnly(tmpFile.Name(), newHash, "test-manager")
+	changed, newHash2, err := CompareHashOnly(tmpFile.Name(), newHash, 0, "test-manager")
 	c.Assert(err, IsNil)
 	c.Assert(changed, Equals, false)
 	c.Assert(newHash2, Equals, newHash)
 
 	// Test different hash - should return changed=true
-	changed, newHash3, err := CompareHashOnly(tmpFile.Name(), "differenthash", "test-manager")
+	changed, newHash3, err := CompareHashOnly(tmpFile.Name(), "differenthash", 0, "test-manager")
 	c.Assert(err, IsNil)
 	c.Assert(changed, Equals, true)
 	c.Assert(newHash3, Equals, newHash)
 
 	// Test error case - non-existent file
-	_, _, err = CompareHashOnly("/nonexistent/file/path", "", "test-manager")
+	_, _, err = CompareHashOnly("/nonexistent/file/path", "", 0, "test-manager")
 	c.Assert(err, NotNil)
 }
 
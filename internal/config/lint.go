@@ -0,0 +1,375 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/adobe/butler/internal/environment"
+	"github.com/adobe/butler/internal/methods"
+	"github.com/adobe/butler/internal/notifier"
+	"github.com/adobe/butler/internal/reloaders"
+
+	"github.com/spf13/viper"
+)
+
+// LintError and LintWarning are the levels of a LintIssue. Errors stop butler
+// from loading the configuration, warnings probably do not do what was meant.
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// LintIssue is a single issue found in a butler configuration. Section is
+// the toml section, or key, which the issue was found in.
+type LintIssue struct {
+	Level   string `json:"level"`
+	Section string `json:"section"`
+	Message string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Level, i.Section, i.Message)
+}
+
+// lintMethodOpts holds the option structures for each of the repository
+// methods. The known options are their mapstructure tags.
+var lintMethodOpts = map[string]interface{}{
+	"blob":  methods.BlobMethod{},
+	"etcd":  methods.EtcdMethod{},
+	"file":  methods.FileMethod{},
+	"http":  methods.HTTPMethod{},
+	"https": methods.HTTPMethod{},
+	"s3":    methods.S3Method{},
+}
+
+// lintReloaderOpts holds the option structures for each of the reloaders.
+// The reloader options are decoded from json, so the known options are their
+// json tags.
+var lintReloaderOpts = map[string]interface{}{
+	"chain":  reloaders.ChainReloaderOpts{},
+	"docker": reloaders.DockerReloaderOpts{},
+	"exec":   reloaders.ExecReloaderOpts{},
+	"http":   reloaders.HTTPReloaderOpts{},
+	"https":  reloaders.HTTPReloaderOpts{},
+}
+
+type linter struct {
+	issues []LintIssue
+	seen   map[string]bool
+	v      *viper.Viper
+}
+
+func (l *linter) add(level string, section string, format string, args ...interface{}) {
+	i := LintIssue{Level: level, Section: section, Message: fmt.Sprintf(format, args...)}
+	if l.seen[i.String()] {
+		return
+	}
+	l.seen[i.String()] = true
+	l.issues = append(l.issues, i)
+}
+
+// LintConfig checks the butler configuration, and returns all of the issues
+// it finds, rather than stopping at the first one like ParseConfig does. The
+// managers and repositories go through GetConfigManager and GetManagerOpts.
+func LintConfig(config []byte) []LintIssue {
+	l := &linter{seen: make(map[string]bool), v: viper.New()}
+
+	if err := ValidateConfig(NewValidateOpts().WithData(config).WithFileName("butler.toml").WithManager("butler-config")); err != nil {
+		l.add(LintError, "butler.toml", "%v", err)
+	}
+
+	l.v.SetConfigType("toml")
+	if err := l.v.ReadConfig(bytes.NewBuffer(config)); err != nil {
+		l.add(LintError, "butler.toml", "could not parse configuration. err=%v", err)
+		return l.issues
+	}
+
+	var globals ConfigGlobals
+	if err := l.v.UnmarshalKey("globals", &globals); err != nil {
+		l.add(LintError, "globals", "%v", err)
+	}
+	if len(globals.Managers) < 1 {
+		l.add(LintError, "globals.config-managers", "has no entries. Nothing to do")
+	}
+	if strings.ToLower(environment.GetVar(globals.CfgHTTPProto)) == "https" &&
+		(environment.GetVar(globals.CfgHTTPTLSCert) == "" || environment.GetVar(globals.CfgHTTPTLSKey) == "") {
+		l.add(LintError, "globals.http-proto", "set to https but no http-tls-cert and/or http-tls-key defined")
+	}
+	l.lintNotifiers(globals.Notifiers)
+
+	managers := make(map[string]bool)
+	for _, m := range globals.Managers {
+		managers[strings.ToLower(m)] = true
+		if !l.v.IsSet(m) {
+			l.add(LintError, m, "is listed in globals.config-managers, but there is no [%s] section", m)
+			continue
+		}
+		l.lintManager(m)
+	}
+	l.lintDependencies(globals.Managers)
+
+	keys := l.v.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		l.lintKey(key, managers)
+		l.lintEnv(key, l.v.Get(key))
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool { return l.issues[i].Section < l.issues[j].Section })
+	return l.issues
+}
+
+// lintManager runs the manager, and each of its repositories, through the
+// same parsing that butler uses, and checks what it cannot.
+func (l *linter) lintManager(m string) {
+	repoErrs := make(map[string]bool)
+	for _, repo := range l.v.GetStringSlice(fmt.Sprintf("%s.repos", m)) {
+		entry := fmt.Sprintf("%s.%s", m, repo)
+		_, err := GetManagerOpts(entry, &ConfigSettings{source: l.v})
+		if err != nil {
+			repoErrs[err.Error()] = true
+		}
+		if !l.v.IsSet(entry) {
+			l.add(LintError, entry, "is listed in %s.repos, but there is no [%s] section", m, entry)
+			continue
+		}
+		method := strings.ToLower(l.v.GetString(fmt.Sprintf("%s.method", entry)))
+		if IsValidScheme(method) && !l.v.IsSet(fmt.Sprintf("%s.%s", entry, method)) {
+			l.add(LintError, entry, "uses method \"%s\", but there is no [%s.%s] section", method, entry, method)
+		}
+		if err != nil {
+			l.add(LintError, entry, "%v", err)
+		}
+	}
+
+	// GetConfigManager stops at the first bad repository, which has already
+	// been reported above.
+	if err := GetConfigManager(m, &ConfigSettings{source: l.v}); err != nil && !repoErrs[err.Error()] {
+		l.add(LintError, m, "%v", err)
+	}
+
+	if strings.ToLower(environment.GetVar(l.v.GetString(fmt.Sprintf("%s.enable-cache", m)))) == "true" {
+		cachePath := environment.GetVar(l.v.GetString(fmt.Sprintf("%s.cache-path", m)))
+		if cachePath != "" {
+			if info, err := os.Stat(cachePath); err != nil {
+				l.add(LintWarning, fmt.Sprintf("%s.cache-path", m), "%v is not reachable. err=%v", cachePath, err)
+			} else if !info.IsDir() {
+				l.add(LintWarning, fmt.Sprintf("%s.cache-path", m), "%v is not a directory", cachePath)
+			}
+		}
+	}
+
+	for _, sub := range l.v.GetStringSlice(fmt.Sprintf("%s.mustache-subs", m)) {
+		if strings.TrimSpace(sub) != "" && len(strings.Split(sub, "=")) != 2 {
+			l.add(LintWarning, fmt.Sprintf("%s.mustache-subs", m), "\"%s\" is not in the form of mustache=substitution, and will be ignored", sub)
+		}
+	}
+
+	reloader, err := reloaders.New(l.v, m)
+	if _, ok := reloader.(reloaders.GenericReloader); ok {
+		l.add(LintWarning, fmt.Sprintf("%s.reloader", m), "falls back to the generic reloader, which never reloads anything. err=%v", err)
+	} else if err != nil {
+		l.add(LintError, fmt.Sprintf("%s.reloader", m), "%v", err)
+	}
+}
+
+// lintNotifiers checks each of the [[globals.notifiers]], and their options.
+func (l *linter) lintNotifiers(notifiers []notifier.Opts) {
+	raw, _ := l.v.Get("globals.notifiers").([]interface{})
+	for i, o := range notifiers {
+		section := fmt.Sprintf("globals.notifiers.%d", i)
+		if i < len(raw) {
+			keys, _ := raw[i].(map[string]interface{})
+			for k := range keys {
+				if !lintKeys(notifier.Opts{}, "mapstructure")[k] {
+					l.add(LintError, fmt.Sprintf("%s.%s", section, k), "unknown option")
+				}
+			}
+		}
+		if _, err := notifier.NewWebhook(o); err != nil {
+			l.add(LintError, section, "%v", err)
+		}
+	}
+}
+
+// lintDependencies checks the depends-on of the managers. Unknown managers
+// are reported on the manager, and cycles once for all of them.
+func (l *linter) lintDependencies(managers []string) {
+	deps := make(map[string][]string)
+	for _, m := range managers {
+		deps[m] = []string{}
+	}
+	for _, m := range managers {
+		for _, d := range l.v.GetStringSlice(fmt.Sprintf("%s.depends-on", m)) {
+			if _, ok := deps[d]; !ok {
+				l.add(LintError, fmt.Sprintf("%s.depends-on", m), "depends on %s, which is not listed in globals.config-managers", d)
+				continue
+			}
+			deps[m] = append(deps[m], d)
+		}
+	}
+	if _, err := dependencyOrder(deps); err != nil {
+		l.add(LintError, "globals.config-managers", "%v", err)
+	}
+}
+
+// lintKey checks that key is a known option.
+func (l *linter) lintKey(key string, managers map[string]bool) {
+	parts := strings.SplitN(key, ".", 2)
+	top, rest := parts[0], ""
+	if len(parts) == 2 {
+		rest = parts[1]
+	}
+
+	switch {
+	case top == "title":
+		return
+	case top == "globals":
+		if !lintKeys(ConfigGlobals{}, "mapstructure")[rest] {
+			l.add(LintError, key, "unknown option")
+		}
+		return
+	case !managers[top]:
+		l.add(LintWarning, top, "[%s] is not listed in globals.config-managers, and will be ignored", top)
+		return
+	}
+
+	if lintKeys(Manager{}, "mapstructure")[rest] {
+		return
+	}
+
+	if strings.HasPrefix(rest, "reloader.") {
+		r := strings.TrimPrefix(rest, "reloader.")
+		if r == "method" {
+			return
+		}
+		method := strings.ToLower(l.v.GetString(fmt.Sprintf("%s.reloader.method", top)))
+		sub, opt := splitSection(r)
+		// Options which are maps, eg: headers, show up as one key per entry.
+		if name, _ := splitSection(opt); lintMapKeys(lintReloaderOpts[method], "json")[name] {
+			opt = name
+		}
+		switch {
+		case sub != method:
+			l.add(LintError, fmt.Sprintf("%s.reloader.%s", top, sub), "section does not match the reloader method \"%s\"", method)
+		case lintReloaderOpts[method] != nil && !lintKeys(lintReloaderOpts[method], "json")[opt]:
+			l.add(LintError, key, "unknown option")
+		}
+		return
+	}
+
+	// The repository names tend to have dots in them, so look for the longest
+	// repository which the key falls under.
+	repo := ""
+	for _, r := range l.v.GetStringSlice(fmt.Sprintf("%s.repos", top)) {
+		r = strings.ToLower(r)
+		if strings.HasPrefix(rest, r+".") && len(r) > len(repo) {
+			repo = r
+		}
+	}
+	if repo == "" {
+		l.add(LintError, key, "unknown option. It is neither a manager option, nor part of a repo listed in %s.repos", top)
+		return
+	}
+
+	entry := fmt.Sprintf("%s.%s", top, repo)
+	r := strings.TrimPrefix(rest, repo+".")
+	if lintKeys(ManagerOpts{}, "mapstructure")[r] {
+		return
+	}
+	method := strings.ToLower(l.v.GetString(fmt.Sprintf("%s.method", entry)))
+	sub, opt := splitSection(r)
+	switch {
+	case sub == method:
+		if lintMethodOpts[method] != nil && !lintKeys(lintMethodOpts[method], "mapstructure")[opt] {
+			l.add(LintError, key, "unknown option")
+		}
+	case IsValidScheme(sub):
+		l.add(LintError, fmt.Sprintf("%s.%s", entry, sub), "section does not match the method \"%s\" of [%s]. Is it under the wrong repo?", method, entry)
+	default:
+		l.add(LintError, key, "unknown option")
+	}
+}
+
+// lintEnv checks that the environment variables used by the value of key are
+// set.
+func (l *linter) lintEnv(key string, val interface{}) {
+	var vals []string
+	switch v := val.(type) {
+	case string:
+		vals = append(vals, v)
+	case []interface{}:
+		for _, i := range v {
+			if s, ok := i.(string); ok {
+				vals = append(vals, s)
+			}
+		}
+	case []string:
+		vals = v
+	}
+
+	for _, v := range vals {
+		// mustache-subs entries are in the form of mustache=env:VAR
+		if i := strings.Index(strings.ToLower(v), "=env:"); i >= 0 {
+			v = v[i+1:]
+		}
+		if len(v) > len("env:") && strings.ToLower(v[:4]) == "env:" && os.Getenv(v[4:]) == "" {
+			l.add(LintWarning, key, "environment variable %s is not set", v[4:])
+		}
+	}
+}
+
+// lintKeys returns the option names of the struct v, from its tag.
+func lintKeys(v interface{}, tag string) map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get(tag), ",")[0]
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// lintMapKeys returns the names of the fields of the structure v, as per
+// tag, which hold maps.
+func lintMapKeys(v interface{}, tag string) map[string]bool {
+	keys := make(map[string]bool)
+	if v == nil {
+		return keys
+	}
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get(tag), ",")[0]
+		if name != "" && name != "-" && t.Field(i).Type.Kind() == reflect.Map {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// splitSection splits key into its first section, and the rest.
+func splitSection(key string) (string, string) {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
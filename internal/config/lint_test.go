@@ -0,0 +1,233 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var TestLintConfig = `#butlerstart
+[globals]
+  config-managers = ["prometheus"]
+  scheduler-interval = "300"
+
+[prometheus]
+  repos = ["repo1.domain.com"]
+  dest-path = "%v"
+  primary-config-name = "prometheus.yml"
+
+  [prometheus.repo1.domain.com]
+    method = "file"
+    repo-path = "/butler"
+    primary-config = ["prometheus.yml"]
+
+    [prometheus.repo1.domain.com.file]
+      path = "/butler"
+
+  [prometheus.reloader]
+    method = "http"
+
+    [prometheus.reloader.http]
+      host = "localhost"
+      port = "9090"
+      uri = "/-/reload"
+      method = "post"
+#butlerend
+`
+
+func lintIssues(config []byte) []string {
+	var res []string
+	for _, i := range LintConfig(config) {
+		res = append(res, i.String())
+	}
+	return res
+}
+
+func (s *ConfigTestSuite) TestLintConfigClean(c *C) {
+	c.Assert(lintIssues([]byte(fmt.Sprintf(TestLintConfig, c.MkDir()))), HasLen, 0)
+}
+
+func (s *ConfigTestSuite) TestLintConfigReloaderHeaders(c *C) {
+	config := strings.Replace(fmt.Sprintf(TestLintConfig, c.MkDir()), `      method = "post"`,
+		`      method = "post"
+      headers = { "X-Scope-OrgID" = "tenant", "X-Token" = "secret" }`, 1)
+	c.Assert(lintIssues([]byte(config)), HasLen, 0)
+
+	// Only the options which are maps take dotted keys
+	config = strings.Replace(fmt.Sprintf(TestLintConfig, c.MkDir()), `      method = "post"`,
+		`      method = "post"
+      payload = { "a" = "b" }`, 1)
+	c.Assert(strings.Join(lintIssues([]byte(config)), "\n"), Matches, `(?s).*error: prometheus.reloader.http.payload.a: unknown option.*`)
+}
+
+func (s *ConfigTestSuite) TestLintConfigReloaderChain(c *C) {
+	reloader := `  [prometheus.reloader]
+    method = "http"
+
+    [prometheus.reloader.http]
+      host = "localhost"
+      port = "9090"
+      uri = "/-/reload"
+      method = "post"
+`
+	chain := `  [prometheus.reloader]
+    method = "chain"
+
+    [[prometheus.reloader.chain.steps]]
+      name = "reload"
+      method = "http"
+      on-failure = "%s"
+      http = { host = "localhost", port = "9090", uri = "/-/reload", method = "post" }
+`
+	config := fmt.Sprintf(TestLintConfig, c.MkDir())
+	c.Assert(lintIssues([]byte(strings.Replace(config, reloader, fmt.Sprintf(chain, "continue"), 1))), HasLen, 0)
+
+	issues := lintIssues([]byte(strings.Replace(config, reloader, fmt.Sprintf(chain, "retry"), 1)))
+	c.Assert(issues, HasLen, 1)
+	c.Assert(issues[0], Matches, ".*prometheus.reloader.*step reload: unsupported on-failure retry.*")
+}
+
+func (s *ConfigTestSuite) TestLintConfigReloaderExec(c *C) {
+	reloader := `  [prometheus.reloader]
+    method = "http"
+
+    [prometheus.reloader.http]
+      host = "localhost"
+      port = "9090"
+      uri = "/-/reload"
+      method = "post"
+`
+	exec := `  [prometheus.reloader]
+    method = "exec"
+
+    [prometheus.reloader.exec]
+      command = ["promtool", "check", "config", "prometheus.yml"]
+      dir = "/etc/prometheus"
+      %s = "30"
+`
+	config := fmt.Sprintf(TestLintConfig, c.MkDir())
+	c.Assert(lintIssues([]byte(strings.Replace(config, reloader, fmt.Sprintf(exec, "timeout"), 1))), HasLen, 0)
+
+	issues := lintIssues([]byte(strings.Replace(config, reloader, fmt.Sprintf(exec, "time-out"), 1)))
+	c.Assert(issues, HasLen, 1)
+	c.Assert(issues[0], Equals, "error: prometheus.reloader.exec.time-out: unknown option")
+}
+
+func (s *ConfigTestSuite) TestLintConfigNotifiers(c *C) {
+	notifiers := `  scheduler-interval = "300"
+
+  [[globals.notifiers]]
+    name = "slack"
+    type = "slack"
+    url = "https://hooks.slack.com/services/T0/B0/x"
+    events = ["reload-failure", "rollback"]
+
+  [[globals.notifiers]]
+    type = "pagerduty"
+    url = "http://localhost/hook"
+    events = ["reloaded"]
+    retries = "3"
+`
+	config := strings.Replace(fmt.Sprintf(TestLintConfig, c.MkDir()), "  scheduler-interval = \"300\"\n", notifiers, 1)
+	issues := lintIssues([]byte(config))
+	c.Assert(issues, HasLen, 2)
+	c.Assert(issues[0], Matches, ".*globals.notifiers.1: .*unsupported type pagerduty.*")
+	c.Assert(issues[1], Matches, ".*globals.notifiers.1.retries: unknown option.*")
+}
+
+func (s *ConfigTestSuite) TestLintConfigBadToml(c *C) {
+	issues := LintConfig([]byte("#butlerstart\n[globals\n#butlerend\n"))
+	c.Assert(issues, HasLen, 1)
+	c.Assert(issues[0].Level, Equals, LintError)
+	c.Assert(issues[0].Section, Equals, "butler.toml")
+}
+
+func (s *ConfigTestSuite) TestLintConfigReportsEverything(c *C) {
+	os.Unsetenv("BUTLER_LINT_UNSET")
+	issues := lintIssues([]byte(fmt.Sprintf(`#butlerstart
+[globals]
+  config-managers = ["prometheus"]
+
+[prometheus]
+  repos = ["repo1.domain.com"]
+  dest-path = "%v"
+  mustache-subs = ["env=env:BUTLER_LINT_UNSET", "broken"]
+  enable-cache = "true"
+  cache-path = "/nonexistent/butler/cache"
+
+  [prometheus.repo1.domain.com]
+    method = "file"
+    methd = "file"
+    primary-config = ["prometheus.yml"]
+
+    [prometheus.repo1.domain.com.file]
+      paht = "/butler"
+
+    [prometheus.repo1.domain.com.blob]
+      storage-account-name = "name"
+
+  [prometheus.reloader]
+    method = "http"
+
+    [prometheus.reloader.http]
+      host = "localhost"
+
+[alertmanager]
+  repos = []
+#butlerend
+`, c.MkDir())))
+	c.Assert(issues, DeepEquals, []string{
+		`warning: alertmanager: [alertmanager] is not listed in globals.config-managers, and will be ignored`,
+		`warning: prometheus.cache-path: /nonexistent/butler/cache is not reachable. err=stat /nonexistent/butler/cache: no such file or directory`,
+		`warning: prometheus.mustache-subs: "broken" is not in the form of mustache=substitution, and will be ignored`,
+		`warning: prometheus.mustache-subs: environment variable BUTLER_LINT_UNSET is not set`,
+		`error: prometheus.repo1.domain.com.blob: section does not match the method "file" of [prometheus.repo1.domain.com]. Is it under the wrong repo?`,
+		`error: prometheus.repo1.domain.com.file.paht: unknown option`,
+		`error: prometheus.repo1.domain.com.methd: unknown option`,
+	})
+}
+
+func (s *ConfigTestSuite) TestLintConfigManagers(c *C) {
+	issues := lintIssues([]byte(`#butlerstart
+[globals]
+  config-managers = ["prometheus", "alertmanager"]
+
+[prometheus]
+  repos = ["repo1.domain.com", "repo2.domain.com"]
+  dest-path = "/opt/prometheus"
+  typo-option = "true"
+
+  [prometheus.repo1.domain.com]
+    method = "http"
+    primary-config = ["prometheus.yml"]
+
+  [prometheus.reloader]
+    method = "https"
+
+    [prometheus.reloader.http]
+      host = "localhost"
+#butlerend
+`))
+	c.Assert(issues, DeepEquals, []string{
+		`error: alertmanager: is listed in globals.config-managers, but there is no [alertmanager] section`,
+		`warning: prometheus.reloader: falls back to the generic reloader, which never reloads anything. err=no reloader configuration has been defined for manager`,
+		`error: prometheus.reloader.http: section does not match the reloader method "https"`,
+		`error: prometheus.repo1.domain.com: uses method "http", but there is no [prometheus.repo1.domain.com.http] section`,
+		`error: prometheus.repo2.domain.com: is listed in prometheus.repos, but there is no [prometheus.repo2.domain.com] section`,
+		`error: prometheus.typo-option: unknown option. It is neither a manager option, nor part of a repo listed in prometheus.repos`,
+	})
+}
@@ -13,6 +13,7 @@ governing permissions and limitations under the License.
 package config
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -21,13 +22,16 @@ import (
 	"os"
 	"time"
 
+	"github.com/adobe/butler/internal/audit"
 	"github.com/adobe/butler/internal/methods"
 	"github.com/adobe/butler/internal/metrics"
 	"github.com/adobe/butler/internal/reloaders"
+	"github.com/adobe/butler/internal/tracing"
 
 	"strings"
 
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Manager struct {
@@ -50,10 +54,19 @@ type Manager struct {
 	SkipButlerHeader       bool                    `json:"skip-butler-header"`
 	CfgWatchOnly           string                  `mapstructure:"watch-only" json:"-"`
 	WatchOnly              bool                    `json:"watch-only"`
+	DependsOn              []string                `mapstructure:"depends-on" json:"depends-on,omitempty"`
+	CfgMinReloadInterval   string                  `mapstructure:"min-reload-interval" json:"-"`
+	MinReloadInterval      int                     `json:"min-reload-interval,omitempty"`
+	CfgMaxReloadsPerHour   string                  `mapstructure:"max-reloads-per-hour" json:"-"`
+	MaxReloadsPerHour      int                     `json:"max-reloads-per-hour,omitempty"`
+	Paused                 bool                    `json:"paused"`
 	FileHashes             map[string]string       `json:"-"` // In-memory hash storage for watch-only mode
 	ManagerOpts            map[string]*ManagerOpts `json:"opts"`
 	Reloader               reloaders.Reloader      `mapstructure:"-" json:"reloader,omitempty"`
 	ReloadManager          bool                    `json:"-"`
+	count                  int
+	cache                  map[string][]byte
+	removed                []audit.File
 }
 
 type ManagerOpts struct {
@@ -69,19 +82,32 @@ type ManagerOpts struct {
 	ContentType                     string         `mapstructure:"content-type" json:"content-type"`
 	Opts                            methods.Method `json:"opts"`
 	parentManager                   string
+	count                           int
 }
 
-func (bm *Manager) Reload() error {
-	log.Debugf("Manager::Reload(): reloading %s manager...", bm.Name)
+func (bm *Manager) Reload(ctx context.Context) error {
+	logEntry(bm.count, bm.Name).Debug("Manager::Reload(): reloading manager...")
 	if bm.Reloader == nil {
-		log.Warnf("Manager::Reload(): No reloader defined for %s manager. Moving on...", bm.Name)
+		logEntry(bm.count, bm.Name).Warn("Manager::Reload(): No reloader defined for manager. Moving on...")
 		return nil
 	} else {
-		return bm.Reloader.SetCounter(cmHandlerCounter).Reload()
+		ctx, span := tracing.Start(ctx, "Reloader.Reload", tracing.ManagerKey.String(bm.Name), tracing.ReloaderKey.String(bm.Reloader.GetMethod()))
+		err := bm.Reloader.SetCounter(bm.count).Reload(ctx)
+		tracing.End(span, err)
+		return err
 	}
 }
 
-func (bm *Manager) DownloadPrimaryConfigFiles(c chan ChanEvent) error {
+// SetCounter sets the run count of the manager, and of its repositories, which
+// is logged throughout the run.
+func (bm *Manager) SetCounter(c int) {
+	bm.count = c
+	for _, opts := range bm.ManagerOpts {
+		opts.SetCounter(c)
+	}
+}
+
+func (bm *Manager) DownloadPrimaryConfigFiles(ctx context.Context, c chan ChanEvent) error {
 	var (
 		Chan              *ConfigChanEvent
 		PrimaryConfigName string
@@ -89,14 +115,14 @@ func (bm *Manager) DownloadPrimaryConfigFiles(c chan ChanEvent) error {
 
 	Chan = NewConfigChanEvent()
 	Chan.Manager = bm.Name
+	Chan.Count = bm.count
 	PrimaryConfigName = fmt.Sprintf("%s/%s", bm.DestPath, bm.PrimaryConfigName)
 	Chan.ConfigFile = &PrimaryConfigName
 
 	// Create a temporary file for the merged prometheus configurations.
 	tmpFile, err := ioutil.TempFile("/tmp", "bcmsfile")
 	if err != nil {
-		msg := fmt.Sprintf("Manager::DownloadPrimaryConfigFiles(): Could not create temporary file . err=%s", err.Error())
-		log.Fatal(msg)
+		logEntry(bm.count, bm.Name).Fatalf("Manager::DownloadPrimaryConfigFiles(): Could not create temporary file . err=%s", err.Error())
 	}
 	Chan.TmpFile = tmpFile
 
@@ -104,21 +130,24 @@ func (bm *Manager) DownloadPrimaryConfigFiles(c chan ChanEvent) error {
 	// We are going to iterate through each of the potential managers configured
 	for _, opts := range bm.ManagerOpts {
 		for i, u := range opts.GetPrimaryConfigURLs() {
-			log.Debugf("Manager::DownloadPrimaryConfigFiles(): i=%v, u=%v", i, u)
-			log.Debugf("Manager::DownloadPrimaryConfigFiles(): f=%s", opts.GetPrimaryRemoteConfigFiles()[i])
-			f := opts.DownloadConfigFile(u)
+			opts.logEntry(u).Debugf("Manager::DownloadPrimaryConfigFiles(): i=%v, u=%v", i, u)
+			opts.logEntry(u).Debugf("Manager::DownloadPrimaryConfigFiles(): f=%s", opts.GetPrimaryRemoteConfigFiles()[i])
+			start := time.Now()
+			f := opts.DownloadConfigFile(ctx, u)
 			if f == nil {
 				metrics.SetButlerContactVal(metrics.FAILURE, opts.Repo, opts.GetPrimaryRemoteConfigFiles()[i])
+				metrics.ObserveButlerDownload(metrics.FAILURE, bm.Name, opts.Repo, time.Since(start), 0)
 
 				// Set this metrics global as failure here, since we aren't sure whether or not it was a parse error or
 				// download error in RunCMHandler()
 				metrics.SetButlerRemoteRepoUp(metrics.FAILURE, bm.Name)
 
-				log.Debugf("Manager::DownloadPrimaryConfigFiles(): download for %s is nil.", u)
+				opts.logEntry(u).Debugf("Manager::DownloadPrimaryConfigFiles(): download for %s is nil.", u)
 				Chan.SetFailure(opts.Repo, opts.GetPrimaryRemoteConfigFiles()[i], errors.New("could not download file"))
 				continue
 			} else {
 				metrics.SetButlerContactVal(metrics.SUCCESS, opts.Repo, opts.GetPrimaryRemoteConfigFiles()[i])
+				metrics.ObserveButlerDownload(metrics.SUCCESS, bm.Name, opts.Repo, time.Since(start), fileSize(f.Name()))
 				Chan.SetSuccess(opts.Repo, opts.GetPrimaryRemoteConfigFiles()[i], nil)
 			}
 			Chan.SetTmpFile(opts.Repo, opts.GetPrimaryRemoteConfigFiles()[i], f.Name())
@@ -126,11 +155,12 @@ func (bm *Manager) DownloadPrimaryConfigFiles(c chan ChanEvent) error {
 			// For the prometheus.yml we have to do some mustache replacement on downloaded file
 			// We are doing this before the header/footer check because YAML parsing doesn't like
 			// the mustache entries... so we shuffled this around.
-			if err := RenderConfigMustache(f, bm.MustacheSubs); err != nil {
-				log.Errorf("%s for %s.", err.Error(), u)
+			if err := opts.renderConfigMustache(ctx, f, opts.GetPrimaryRemoteConfigFiles()[i], bm.MustacheSubs); err != nil {
+				opts.logEntry(u).Errorf("%s for %s.", err.Error(), u)
 				metrics.SetButlerRenderVal(metrics.FAILURE, opts.Repo, opts.GetPrimaryRemoteConfigFiles()[i])
+				metrics.IncButlerValidationFailure(bm.Name, opts.Repo)
 				metrics.SetButlerConfigVal(metrics.FAILURE, opts.Repo, opts.GetPrimaryRemoteConfigFiles()[i])
-				log.Debugf("Manager::DownloadPrimaryConfigFiles(): render for %s is nil.", opts.GetPrimaryRemoteConfigFiles()[i])
+				opts.logEntry(u).Debugf("Manager::DownloadPrimaryConfigFiles(): render for %s is nil.", opts.GetPrimaryRemoteConfigFiles()[i])
 				Chan.SetFailure(opts.Repo, opts.GetPrimaryRemoteConfigFiles()[i], errors.New("could not render file"))
 				continue
 			} else {
@@ -145,13 +175,14 @@ func (bm *Manager) DownloadPrimaryConfigFiles(c chan ChanEvent) error {
 			// we did not get a correct configuration, or that there is an
 			// issue with the upstream
 			filename := opts.GetPrimaryRemoteConfigFiles()[i]
-			if err := ValidateConfig(NewValidateOpts().WithContentType(opts.ContentType).WithFileName(filename).WithData(f).WithManager(bm.Name).WithSkipButlerHeader(bm.SkipButlerHeader)); err != nil {
-				log.Errorf("%s for %s.", err.Error(), u)
+			if err := opts.validateConfig(ctx, NewValidateOpts().WithContentType(opts.ContentType).WithFileName(filename).WithData(f).WithManager(bm.Name).WithCount(bm.count).WithSkipButlerHeader(bm.SkipButlerHeader)); err != nil {
+				opts.logEntry(u).Errorf("%s for %s.", err.Error(), u)
 				metrics.SetButlerConfigVal(metrics.FAILURE, opts.Repo, opts.GetPrimaryRemoteConfigFiles()[i])
 
 				// Set this metrics global as failure here, since we aren't sure whether or not it was a parse error or
 				// download error in RunCMHandler()
 				metrics.SetButlerRemoteRepoSanity(metrics.FAILURE, bm.Name)
+				metrics.IncButlerValidationFailure(bm.Name, opts.Repo)
 
 				Chan.SetFailure(opts.Repo, opts.GetPrimaryRemoteConfigFiles()[i], errors.New("could not validate file"))
 				continue
@@ -168,7 +199,7 @@ func (bm *Manager) DownloadPrimaryConfigFiles(c chan ChanEvent) error {
 	return nil
 }
 
-func (bm *Manager) DownloadAdditionalConfigFiles(c chan ChanEvent) error {
+func (bm *Manager) DownloadAdditionalConfigFiles(ctx context.Context, c chan ChanEvent) error {
 	var (
 		Chan       *ConfigChanEvent
 		IsModified bool
@@ -176,17 +207,20 @@ func (bm *Manager) DownloadAdditionalConfigFiles(c chan ChanEvent) error {
 
 	Chan = NewConfigChanEvent()
 	Chan.Manager = bm.Name
+	Chan.Count = bm.count
 	IsModified = false
 	_ = IsModified
 
 	// Process the additional configuration files
 	for _, opts := range bm.ManagerOpts {
 		for i, u := range opts.GetAdditionalConfigURLs() {
-			log.Debugf("Manager::DownloadAdditionalConfigFiles(): i=%v, u=%v", i, u)
-			f := opts.DownloadConfigFile(u)
+			opts.logEntry(u).Debugf("Manager::DownloadAdditionalConfigFiles(): i=%v, u=%v", i, u)
+			start := time.Now()
+			f := opts.DownloadConfigFile(ctx, u)
 			if f == nil {
-				log.Debugf("Manager::DownloadAdditionalConfigFiles(): download for %s is nil.", u)
+				opts.logEntry(u).Debugf("Manager::DownloadAdditionalConfigFiles(): download for %s is nil.", u)
 				metrics.SetButlerContactVal(metrics.FAILURE, opts.Repo, opts.GetAdditionalRemoteConfigFiles()[i])
+				metrics.ObserveButlerDownload(metrics.FAILURE, bm.Name, opts.Repo, time.Since(start), 0)
 
 				// Set this metrics global as failure here, since we aren't sure whether or not it was a parse error or
 				// download error in RunCMHandler()
@@ -196,6 +230,7 @@ func (bm *Manager) DownloadAdditionalConfigFiles(c chan ChanEvent) error {
 				continue
 			} else {
 				metrics.SetButlerContactVal(metrics.SUCCESS, opts.Repo, opts.GetAdditionalRemoteConfigFiles()[i])
+				metrics.ObserveButlerDownload(metrics.SUCCESS, bm.Name, opts.Repo, time.Since(start), fileSize(f.Name()))
 				Chan.SetSuccess(opts.Repo, opts.GetAdditionalRemoteConfigFiles()[i], nil)
 				Chan.SetTmpFile(opts.Repo, opts.GetAdditionalRemoteConfigFiles()[i], f.Name())
 			}
@@ -206,8 +241,9 @@ func (bm *Manager) DownloadAdditionalConfigFiles(c chan ChanEvent) error {
 			// effects.
 			// We are doing this before the header/footer check because YAML parsing doesn't like
 			// the mustache entries... so we shuffled this around.
-			if err := RenderConfigMustache(f, bm.MustacheSubs); err != nil {
+			if err := opts.renderConfigMustache(ctx, f, opts.GetAdditionalRemoteConfigFiles()[i], bm.MustacheSubs); err != nil {
 				metrics.SetButlerRenderVal(metrics.FAILURE, opts.Repo, opts.GetAdditionalRemoteConfigFiles()[i])
+				metrics.IncButlerValidationFailure(bm.Name, opts.Repo)
 				metrics.SetButlerConfigVal(metrics.FAILURE, opts.Repo, opts.GetAdditionalRemoteConfigFiles()[i])
 				Chan.SetFailure(opts.Repo, opts.GetAdditionalRemoteConfigFiles()[i], errors.New("could not render file"))
 				continue
@@ -222,12 +258,13 @@ func (bm *Manager) DownloadAdditionalConfigFiles(c chan ChanEvent) error {
 			// we did not get a correct configuration, or that there is an
 			// issue with the upstream
 			filename := opts.GetAdditionalRemoteConfigFiles()[i]
-			if err := ValidateConfig(NewValidateOpts().WithContentType(opts.ContentType).WithFileName(filename).WithData(f).WithManager(bm.Name).WithSkipButlerHeader(bm.SkipButlerHeader)); err != nil {
+			if err := opts.validateConfig(ctx, NewValidateOpts().WithContentType(opts.ContentType).WithFileName(filename).WithData(f).WithManager(bm.Name).WithCount(bm.count).WithSkipButlerHeader(bm.SkipButlerHeader)); err != nil {
 				metrics.SetButlerConfigVal(metrics.FAILURE, opts.Repo, opts.GetAdditionalRemoteConfigFiles()[i])
 
 				// Set this metrics global as failure here, since we aren't sure whether or not it was a parse error or
 				// download error in RunCMHandler()
 				metrics.SetButlerRemoteRepoSanity(metrics.FAILURE, bm.Name)
+				metrics.IncButlerValidationFailure(bm.Name, opts.Repo)
 
 				Chan.SetFailure(opts.Repo, opts.GetAdditionalRemoteConfigFiles()[i], errors.New("could not validate file"))
 				continue
@@ -264,8 +301,11 @@ func (bm *Manager) PathCleanup(path string, f os.FileInfo, err error) error {
 
 	if !Found {
 		message := fmt.Sprintf("Found unknown file \"%s\". deleting...", path)
-		log.Debugf("Manager::PathCleanup(): Found unknown file \"%s\". deleting...", path)
-		os.Remove(path)
+		logEntry(bm.count, bm.Name).WithField("file", path).Debug("Manager::PathCleanup(): Found unknown file. deleting...")
+		hash, _ := ComputeFileHash(path)
+		if os.Remove(path) == nil {
+			bm.removed = append(bm.removed, audit.File{Path: path, Action: audit.ActionRemove, OldSha256: hash})
+		}
 		return errors.New(message)
 	}
 	return nil
@@ -314,6 +354,50 @@ func (bmo *ManagerOpts) SetParentManager(c string) error {
 	return nil
 }
 
+// SetCounter sets the run count which is logged while retrieving the files of
+// the repository.
+func (bmo *ManagerOpts) SetCounter(c int) error {
+	bmo.count = c
+	return nil
+}
+
+// logEntry returns the log entry for the retrieval of file from the
+// repository, which carries the run count, the manager, the repository, the
+// retrieval method and the file as fields.
+func (bmo *ManagerOpts) logEntry(file string) *log.Entry {
+	return logEntry(bmo.count, bmo.parentManager).WithFields(log.Fields{"repo": bmo.Repo, "method": bmo.Method, "file": file})
+}
+
+// startSpan starts a span of the processing of file, from the repository.
+func (bmo *ManagerOpts) startSpan(ctx context.Context, name string, file string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name, tracing.ManagerKey.String(bmo.parentManager), tracing.RepoKey.String(bmo.Repo),
+		tracing.FileKey.String(file), tracing.MethodKey.String(bmo.Method))
+}
+
+// renderConfigMustache renders the mustache of the downloaded file, within a
+// span.
+func (bmo *ManagerOpts) renderConfigMustache(ctx context.Context, f *os.File, file string, subs map[string]string) error {
+	_, span := bmo.startSpan(ctx, "RenderConfigMustache", file)
+	err := RenderConfigMustache(f, subs)
+	tracing.End(span, err)
+	return err
+}
+
+// validateConfig validates the downloaded file, within a span.
+func (bmo *ManagerOpts) validateConfig(ctx context.Context, opts *ValidateOpts) error {
+	_, span := bmo.startSpan(ctx, "ValidateConfig", opts.FileName)
+	err := ValidateConfig(opts)
+	tracing.End(span, err)
+	return err
+}
+
+// GetWatchKey returns the upstream key, or prefix, which holds all of the
+// files for this repository. It is what gets watched for watch capable
+// methods.
+func (bmo *ManagerOpts) GetWatchKey() string {
+	return fmt.Sprintf("/%s", strings.TrimPrefix(bmo.RepoPath, "/"))
+}
+
 func (bmo *ManagerOpts) GetPrimaryConfigURLs() []string {
 	return bmo.PrimaryConfigsFullURLs
 }
@@ -339,12 +423,11 @@ func (bmo *ManagerOpts) GetAdditionalRemoteConfigFiles() []string {
 }
 
 // Really need to come up with a better method for this.
-func (bmo *ManagerOpts) DownloadConfigFile(file string) *os.File {
+func (bmo *ManagerOpts) DownloadConfigFile(ctx context.Context, file string) *os.File {
 	if IsValidScheme(bmo.Method) {
 		tmpFile, err := ioutil.TempFile("/tmp", "bcmsfile")
 		if err != nil {
-			msg := fmt.Sprintf("ManagerOpts::DownloadConfigFile()[count=%v][manager=%v]: could not create temporary file. err=%v", cmHandlerCounter, bmo.parentManager, err)
-			log.Fatal(msg)
+			bmo.logEntry(file).Fatalf("ManagerOpts::DownloadConfigFile(): could not create temporary file. err=%v", err)
 		}
 
 		if (bmo.Method == "file") || (bmo.Method == "s3") {
@@ -365,16 +448,19 @@ func (bmo *ManagerOpts) DownloadConfigFile(file string) *os.File {
 		if err != nil {
 			tmpFile.Close()
 			os.Remove(tmpFile.Name())
-			log.Errorf("ManagerOpts::DownloadConfigFile()[count=%v][manager=%v]: Could not parse file %s to *url.URL, err=%s", cmHandlerCounter, bmo.parentManager, file, err.Error())
+			bmo.logEntry(file).Errorf("ManagerOpts::DownloadConfigFile(): Could not parse file %s to *url.URL, err=%s", file, err.Error())
 			tmpFile = nil
 			return tmpFile
 		}
-		response, err := bmo.Opts.Get(url)
+		getCtx, span := bmo.startSpan(ctx, "Method.Get", url.Path)
+		span.SetAttributes(tracing.URLKey.String(url.Redacted()))
+		response, err := bmo.Opts.Get(getCtx, url)
+		tracing.End(span, err)
 
 		if err != nil {
 			tmpFile.Close()
 			os.Remove(tmpFile.Name())
-			log.Errorf("ManagerOpts::DownloadConfigFile()[count=%v][manager=%v]: Could not download from %s, err=%s", cmHandlerCounter, bmo.parentManager, file, err.Error())
+			bmo.logEntry(file).Errorf("ManagerOpts::DownloadConfigFile(): Could not download from %s, err=%s", file, err.Error())
 			tmpFile = nil
 			return tmpFile
 		}
@@ -384,7 +470,7 @@ func (bmo *ManagerOpts) DownloadConfigFile(file string) *os.File {
 		if response.GetResponseStatusCode() != 200 {
 			tmpFile.Close()
 			os.Remove(tmpFile.Name())
-			log.Errorf("ManagerOpts::DownloadConfigFile()[count=%v][manager=%v]: Did not receive 200 response code for %s. code=%v", cmHandlerCounter, bmo.parentManager, file, response.GetResponseStatusCode())
+			bmo.logEntry(file).Errorf("ManagerOpts::DownloadConfigFile(): Did not receive 200 response code for %s. code=%v", file, response.GetResponseStatusCode())
 			tmpFile = nil
 			return tmpFile
 		}
@@ -393,7 +479,7 @@ func (bmo *ManagerOpts) DownloadConfigFile(file string) *os.File {
 		if err != nil {
 			tmpFile.Close()
 			os.Remove(tmpFile.Name())
-			log.Errorf("ManagerOpts::DownloadConfigFile()[count=%v][manager=%v]: Could not copy to %s, err=%s", cmHandlerCounter, bmo.parentManager, file, err.Error())
+			bmo.logEntry(file).Errorf("ManagerOpts::DownloadConfigFile(): Could not copy to %s, err=%s", file, err.Error())
 			tmpFile = nil
 			return tmpFile
 		}
@@ -402,3 +488,71 @@ func (bmo *ManagerOpts) DownloadConfigFile(file string) *os.File {
 		return nil
 	}
 }
+
+// CacheConfigs reads the known good configuration files of the manager into
+// its in-memory cache, so that they can be restored should a later reload
+// fail. It returns an error on the event of error
+func (bm *Manager) CacheConfigs(files []string) error {
+	logEntry(bm.count, bm.Name).Info("Manager::CacheConfigs(): Storing known good configurations to cache.")
+	cache := make(map[string][]byte)
+	for _, file := range files {
+		out, err := ioutil.ReadFile(file)
+		if err != nil {
+			logEntry(bm.count, bm.Name).WithField("file", file).Errorf("Manager::CacheConfigs(): Could not store to cache. err=%s", err.Error())
+			return fmt.Errorf("Manager::CacheConfigs(): Could not store %s to cache. err=%s", file, err.Error())
+		} else {
+			cache[file] = out
+		}
+	}
+	bm.cache = cache
+	logEntry(bm.count, bm.Name).Info("Manager::CacheConfigs(): Done storing known good configurations to cache.")
+	metrics.SetButlerKnownGoodCachedVal(metrics.SUCCESS, bm.Name)
+	metrics.SetButlerKnownGoodRestoredVal(metrics.FAILURE, bm.Name)
+	return nil
+}
+
+// RestoreCachedConfigs writes the configuration files held in the cache of
+// the manager back to the filesystem. Without a cache, the files are removed
+// if cleanFiles is set. It returns an error on the event of an error
+func (bm *Manager) RestoreCachedConfigs(files []string, cleanFiles bool) error {
+	// If we do not have a good configuration cache, then there's nothing for us to do.
+	if bm.cache == nil {
+		if cleanFiles {
+			logEntry(bm.count, bm.Name).Info("Manager::RestoreCachedConfigs(): No current known good configurations in cache. Cleaning configuration...")
+			for _, file := range files {
+				logEntry(bm.count, bm.Name).Warnf("Manager::RestoreCachedConfigs(): Removing bad configuration file %s.", file)
+				os.Remove(file)
+			}
+			logEntry(bm.count, bm.Name).Info("Manager::RestoreCachedConfigs(): Done cleaning broken configuration. Returning...")
+		}
+		metrics.SetButlerKnownGoodCachedVal(metrics.FAILURE, bm.Name)
+		metrics.SetButlerKnownGoodRestoredVal(metrics.FAILURE, bm.Name)
+		metrics.IncButlerRollback(metrics.FAILURE, bm.Name)
+		return nil
+	}
+
+	logEntry(bm.count, bm.Name).Warn("Manager::RestoreCachedConfigs(): Restoring known good configurations from cache.")
+	for _, file := range files {
+		fileData := bm.cache[file]
+
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			logEntry(bm.count, bm.Name).Errorf("Manager::RestoreCachedConfigs(): Could not open %s for writing! err=%s.", file, err.Error())
+			continue
+		} else {
+			count, err := f.Write(fileData)
+			if err != nil {
+				logEntry(bm.count, bm.Name).Errorf("Manager::RestoreCachedConfigs(): Could not write to %s! err=%s.", file, err.Error())
+				continue
+			} else {
+				f.Close()
+				logEntry(bm.count, bm.Name).Warnf("Manager::RestoreCachedConfigs(): Wrote %d bytes for %s.", count, file)
+			}
+		}
+	}
+	logEntry(bm.count, bm.Name).Warn("Manager::RestoreCachedConfigs(): Done restoring known good configurations from cache.")
+	metrics.SetButlerKnownGoodCachedVal(metrics.FAILURE, bm.Name)
+	metrics.SetButlerKnownGoodRestoredVal(metrics.SUCCESS, bm.Name)
+	metrics.IncButlerRollback(metrics.SUCCESS, bm.Name)
+	return nil
+}
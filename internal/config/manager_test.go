@@ -13,9 +13,11 @@ governing permissions and limitations under the License.
 package config
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 
+	log "github.com/sirupsen/logrus"
 	. "gopkg.in/check.v1"
 )
 
@@ -228,6 +230,60 @@ func (s *ConfigTestSuite) TestManagerReloadNoReloader(c *C) {
 	}
 
 	// Should return nil when no reloader is defined
-	err := mgr.Reload()
+	err := mgr.Reload(context.Background())
 	c.Assert(err, IsNil)
 }
+
+func (s *ConfigTestSuite) TestManagerCacheConfigs(c *C) {
+	dir := c.MkDir()
+	file1 := filepath.Join(dir, "one.yml")
+	file2 := filepath.Join(dir, "two.yml")
+	c.Assert(os.WriteFile(file1, []byte("one"), 0644), IsNil)
+	c.Assert(os.WriteFile(file2, []byte("two"), 0644), IsNil)
+
+	// Each manager keeps its own cache
+	m1 := &Manager{Name: "one"}
+	m2 := &Manager{Name: "two"}
+	c.Assert(m1.CacheConfigs([]string{file1}), IsNil)
+	c.Assert(m2.CacheConfigs([]string{file2}), IsNil)
+
+	c.Assert(os.WriteFile(file1, []byte("broken"), 0644), IsNil)
+	c.Assert(m1.RestoreCachedConfigs([]string{file1}, false), IsNil)
+	data, err := os.ReadFile(file1)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "one")
+
+	// A missing file can not be cached
+	c.Assert(m1.CacheConfigs([]string{filepath.Join(dir, "missing.yml")}), NotNil)
+}
+
+func (s *ConfigTestSuite) TestManagerRestoreCachedConfigsNoCache(c *C) {
+	dir := c.MkDir()
+	file := filepath.Join(dir, "one.yml")
+	c.Assert(os.WriteFile(file, []byte("broken"), 0644), IsNil)
+
+	m := &Manager{Name: "one"}
+	c.Assert(m.RestoreCachedConfigs([]string{file}, false), IsNil)
+	_, err := os.Stat(file)
+	c.Assert(err, IsNil)
+
+	c.Assert(m.RestoreCachedConfigs([]string{file}, true), IsNil)
+	_, err = os.Stat(file)
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *ConfigTestSuite) TestManagerSetCounter(c *C) {
+	opts := &ManagerOpts{}
+	m := &Manager{Name: "one", ManagerOpts: map[string]*ManagerOpts{"one.localhost": opts}}
+	m.SetCounter(5)
+	c.Assert(m.count, Equals, 5)
+	c.Assert(opts.count, Equals, 5)
+}
+
+func (s *ConfigTestSuite) TestManagerOptsLogEntry(c *C) {
+	opts := &ManagerOpts{Repo: "repo1.domain.com", Method: "http"}
+	opts.SetParentManager("prometheus")
+	opts.SetCounter(3)
+	c.Assert(opts.logEntry("prometheus.yml").Data, DeepEquals, log.Fields{
+		"count": 3, "manager": "prometheus", "repo": "repo1.domain.com", "method": "http", "file": "prometheus.yml"})
+}
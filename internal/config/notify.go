@@ -0,0 +1,96 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"sort"
+
+	"github.com/adobe/butler/internal/notifier"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// getNotifier returns the notifier for the globals.notifiers. It is nil when
+// there are none.
+func (bc *ButlerConfig) getNotifier() *notifier.Notifier {
+	bc.globalsLock.Lock()
+	defer bc.globalsLock.Unlock()
+	return bc.notifier
+}
+
+// setNotifiers replaces the notifier with one for opts. The notifier is kept
+// across butler configurations which cannot be parsed, so that those can be
+// notified of too.
+func (bc *ButlerConfig) setNotifiers(opts []notifier.Opts) {
+	n, err := notifier.New(opts)
+	if err != nil {
+		// The notifiers have already been checked by ParseConfig
+		log.Errorf("ButlerConfig::setNotifiers(): could not set up the notifiers. err=%v", err)
+		return
+	}
+	bc.globalsLock.Lock()
+	prev := bc.notifier
+	bc.notifier = n
+	bc.globalsLock.Unlock()
+	prev.Wait()
+}
+
+// notify sends e to the notifiers, if there are any.
+func (bc *ButlerConfig) notify(e notifier.Event) {
+	bc.getNotifier().Notify(e)
+}
+
+// hashFiles returns the sha256 of each of the files. Files which cannot be
+// read have an empty hash.
+func hashFiles(files []string) map[string]string {
+	res := make(map[string]string)
+	for _, f := range files {
+		res[f], _ = ComputeFileHash(f)
+	}
+	return res
+}
+
+// changedFiles returns the files whose hashes differ between before and
+// after, in the order of their names.
+func changedFiles(before map[string]string, after map[string]string) []notifier.File {
+	var res []notifier.File
+	for f, hash := range after {
+		if before[f] != hash {
+			res = append(res, notifier.File{Path: f, Hash: hash})
+		}
+	}
+	for f := range before {
+		if _, ok := after[f]; !ok {
+			res = append(res, notifier.File{Path: f})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res
+}
+
+// rollback puts the last known good configuration of mgr back in place, when
+// it has one, and notifies of it.
+func (bc *ButlerConfig) rollback(mgr *Manager, reason string) {
+	if !mgr.EnableCache || !mgr.GoodCache {
+		return
+	}
+	files := bc.Config.GetAllConfigLocalPaths(mgr.Name)
+	mgr.RestoreCachedConfigs(files, mgr.CleanFiles)
+
+	var restored []notifier.File
+	hashes := hashFiles(files)
+	for _, f := range files {
+		restored = append(restored, notifier.File{Path: f, Hash: hashes[f]})
+	}
+	bc.notify(notifier.Event{Type: notifier.EventRollback, Manager: mgr.Name, Files: restored, Error: reason})
+}
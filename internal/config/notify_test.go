@@ -0,0 +1,193 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+
+	"github.com/adobe/butler/internal/notifier"
+	"github.com/adobe/butler/internal/reloaders"
+
+	. "gopkg.in/check.v1"
+)
+
+// testReloader fails its reloads with err, when set.
+type testReloader struct {
+	err error
+}
+
+func (r *testReloader) Reload(context.Context) error        { return r.err }
+func (r *testReloader) GetMethod() string                   { return "test" }
+func (r *testReloader) GetOpts() reloaders.ReloaderOpts     { return nil }
+func (r *testReloader) SetOpts(reloaders.ReloaderOpts) bool { return true }
+func (r *testReloader) SetCounter(int) reloaders.Reloader   { return r }
+
+// newTestNotifier returns a ButlerConfig which notifies of every event to a
+// webhook, and the events the webhook has received so far.
+func newTestNotifier(c *C) (*ButlerConfig, func() []notifier.Event) {
+	var (
+		lock   sync.Mutex
+		events []notifier.Event
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e notifier.Event
+		body, _ := io.ReadAll(r.Body)
+		c.Check(json.Unmarshal(body, &e), IsNil)
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, e)
+	}))
+	bc := &ButlerConfig{Config: NewConfigSettings()}
+	bc.Config.Globals.StatusFile = c.MkDir() + "/status"
+	bc.setNotifiers([]notifier.Opts{{URL: server.URL}})
+	return bc, func() []notifier.Event {
+		bc.getNotifier().Wait()
+		lock.Lock()
+		defer lock.Unlock()
+		return events
+	}
+}
+
+func (s *ConfigTestSuite) TestChangedFiles(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(dir+"/b.yml", []byte("b\n"), 0644), IsNil)
+	before := hashFiles([]string{dir + "/a.yml", dir + "/b.yml", dir + "/c.yml"})
+	c.Assert(before[dir+"/a.yml"], Equals, "")
+
+	c.Assert(os.WriteFile(dir+"/a.yml", []byte("a\n"), 0644), IsNil)
+	c.Assert(os.WriteFile(dir+"/c.yml", []byte("c\n"), 0644), IsNil)
+	after := hashFiles([]string{dir + "/a.yml", dir + "/b.yml"})
+
+	files := changedFiles(before, after)
+	c.Assert(files, HasLen, 2)
+	c.Assert(files[0].Path, Equals, dir+"/a.yml")
+	c.Assert(files[0].Hash, Equals, after[dir+"/a.yml"])
+	c.Assert(files[1].Path, Equals, dir+"/c.yml")
+	c.Assert(files[1].Hash, Equals, "")
+	c.Assert(changedFiles(after, after), HasLen, 0)
+}
+
+func (s *ConfigTestSuite) TestReloadManagerNotify(c *C) {
+	bc, events := newTestNotifier(c)
+	m := &Manager{Name: "prometheus", Reloader: &testReloader{}}
+	bc.Config.Managers = map[string]*Manager{"prometheus": m}
+
+	bc.reloadManager(bc.Context(), m, NewRunResult(0).AddManager("prometheus"))
+	c.Assert(events(), HasLen, 1)
+	c.Assert(events()[0].Type, Equals, notifier.EventReloadSuccess)
+	c.Assert(events()[0].Manager, Equals, "prometheus")
+
+	m.Reloader = &testReloader{err: reloaders.NewReloaderError().WithCode(500).WithMessage("bad response")}
+	bc.reloadManager(bc.Context(), m, NewRunResult(0).AddManager("prometheus"))
+	c.Assert(events(), HasLen, 2)
+	c.Assert(events()[1].Type, Equals, notifier.EventReloadFailure)
+	c.Assert(events()[1].Error, Equals, "bad response. code=500")
+
+	m.Reloader = &testReloader{err: errors.New("no such host")}
+	bc.reloadManager(bc.Context(), m, NewRunResult(0).AddManager("prometheus"))
+	c.Assert(events(), HasLen, 3)
+	c.Assert(events()[2].Type, Equals, notifier.EventReloadFailure)
+	c.Assert(events()[2].Error, Equals, "no such host")
+}
+
+func (s *ConfigTestSuite) TestReloadManagerNotifyRollback(c *C) {
+	bc, events := newTestNotifier(c)
+	dir := c.MkDir()
+	file := dir + "/prometheus.yml"
+	c.Assert(os.WriteFile(file, []byte("good\n"), 0644), IsNil)
+	m := &Manager{Name: "prometheus", DestPath: dir, PrimaryConfigName: "prometheus.yml",
+		EnableCache: true, GoodCache: true,
+		Reloader: &testReloader{err: reloaders.NewReloaderError().WithCode(500).WithMessage("bad response")}}
+	bc.Config.Managers = map[string]*Manager{"prometheus": m}
+	c.Assert(m.CacheConfigs([]string{file}), IsNil)
+	c.Assert(os.WriteFile(file, []byte("bad\n"), 0644), IsNil)
+
+	bc.reloadManager(bc.Context(), m, NewRunResult(0).AddManager("prometheus"))
+	data, err := os.ReadFile(file)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "good\n")
+
+	c.Assert(events(), HasLen, 2)
+	c.Assert(events()[0].Type, Equals, notifier.EventReloadFailure)
+	c.Assert(events()[1].Type, Equals, notifier.EventRollback)
+	c.Assert(events()[1].Error, Equals, "bad response. code=500")
+	c.Assert(events()[1].Files, DeepEquals, []notifier.File{{Path: file, Hash: hashFiles([]string{file})[file]}})
+}
+
+func (s *ConfigTestSuite) TestReloadManagerChainTimeoutOk(c *C) {
+	for _, step := range []string{
+		// The validation fails
+		`{"name": "validate", "method": "exec", "exec": {"command": ["false"]}}`,
+		// The validation times out
+		`{"name": "validate", "method": "exec", "timeout": "1", "exec": {"command": ["sleep", "5"]}}`,
+	} {
+		bc, events := newTestNotifier(c)
+		dir := c.MkDir()
+		file := dir + "/prometheus.yml"
+		c.Assert(os.WriteFile(file, []byte("good\n"), 0644), IsNil)
+		reloader, err := reloaders.NewChainReloader("prometheus", "chain", []byte(`{"steps": [`+step+`,
+			{"name": "reload", "method": "exec", "exec": {"command": ["true"]}}]}`))
+		c.Assert(err, IsNil)
+		m := &Manager{Name: "prometheus", DestPath: dir, PrimaryConfigName: "prometheus.yml",
+			EnableCache: true, GoodCache: true, ManagerTimeoutOk: true, Reloader: reloader}
+		bc.Config.Managers = map[string]*Manager{"prometheus": m}
+		c.Assert(m.CacheConfigs([]string{file}), IsNil)
+		c.Assert(os.WriteFile(file, []byte("bad\n"), 0644), IsNil)
+
+		// manager-timeout-ok does not let the abort-and-rollback step through
+		mr := NewRunResult(0).AddManager("prometheus")
+		bc.reloadManager(bc.Context(), m, mr)
+		c.Assert(mr.Success, Equals, false, Commentf("step=%v", step))
+		c.Assert(mr.Error, Matches, "step 1/2 \\(validate\\) failed: .*")
+		c.Assert(GetManagerStatus(bc.GetStatusFile(), "prometheus"), Equals, false)
+		data, err := os.ReadFile(file)
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, "good\n")
+		c.Assert(events(), HasLen, 2)
+		c.Assert(events()[1].Type, Equals, notifier.EventRollback)
+	}
+}
+
+func (s *ConfigTestSuite) TestNotifyParseFailure(c *C) {
+	bc, events := newTestNotifier(c)
+	bc.notifyParseFailure([]byte("[globals"), errors.New("bad toml"))
+	bc.notifyParseFailure([]byte("[globals"), errors.New("bad toml"))
+	c.Assert(events(), HasLen, 1)
+	c.Assert(events()[0].Type, Equals, notifier.EventConfigParseFailure)
+	c.Assert(events()[0].Error, Equals, "bad toml")
+
+	// A different bad configuration is notified of again
+	bc.notifyParseFailure([]byte("[globals]\n[globals"), errors.New("bad toml"))
+	c.Assert(events(), HasLen, 2)
+}
+
+func (s *ConfigTestSuite) TestParseConfigBadNotifier(c *C) {
+	config := []byte(`[globals]
+  config-managers = ["prometheus"]
+
+  [[globals.notifiers]]
+    name = "ops"
+    url = "http://localhost/hook"
+    events = ["reloaded"]
+`)
+	_, err := ParseConfig(config)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, "globals.notifiers: notifier ops: unknown event reloaded.*")
+}
@@ -14,10 +14,11 @@ package config
 
 import (
 	"fmt"
-)
 
-var (
-	ConfigCache map[string]map[string][]byte
+	"github.com/adobe/butler/internal/audit"
+	"github.com/adobe/butler/internal/notifier"
+
+	"github.com/spf13/viper"
 )
 
 type TmpFile struct {
@@ -48,6 +49,89 @@ func (r *RepoFileEvent) SetTmpFile(file string, tmpfile string) error {
 	return nil
 }
 
+// RunResult is the outcome of a configuration management run.
+type RunResult struct {
+	Count    int                       `json:"count"`
+	DryRun   bool                      `json:"dry-run,omitempty"`
+	Success  bool                      `json:"success"`
+	Managers map[string]*ManagerResult `json:"managers"`
+}
+
+// ManagerResult is the outcome of a configuration management run for a
+// single manager. Files is keyed by repository, and then by file name.
+// Deferred is set when the reload is held off by the reload limits of the
+// manager. For a dry run, WouldReload and Changes describe what a real run
+// would have done.
+type ManagerResult struct {
+	Name        string                           `json:"name"`
+	Success     bool                             `json:"success"`
+	Changed     bool                             `json:"changed"`
+	Reloaded    bool                             `json:"reloaded"`
+	Deferred    bool                             `json:"deferred,omitempty"`
+	Paused      bool                             `json:"paused,omitempty"`
+	WouldReload bool                             `json:"would-reload,omitempty"`
+	Changes     []FileChange                     `json:"changes,omitempty"`
+	Error       string                           `json:"error,omitempty"`
+	Files       map[string]map[string]FileResult `json:"files"`
+	run         *RunResult
+	// applied and reload are what is recorded in the audit log.
+	applied []audit.File
+	reload  *audit.Reload
+}
+
+// FileChange is a change which a dry run found would be made to a file in
+// the destination path. Action is one of "create", "update" or "remove".
+type FileChange struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Diff   string `json:"diff,omitempty"`
+}
+
+// FileResult is whether or not a single file was successfully retrieved,
+// rendered and validated.
+type FileResult struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NewRunResult returns a new, so far successful, RunResult for run count.
+func NewRunResult(count int) *RunResult {
+	return &RunResult{Count: count, Success: true, Managers: make(map[string]*ManagerResult)}
+}
+
+// AddManager adds, and returns, a new successful ManagerResult for the
+// manager m.
+func (r *RunResult) AddManager(m string) *ManagerResult {
+	mr := &ManagerResult{Name: m, Success: true, Files: make(map[string]map[string]FileResult), run: r}
+	r.Managers[m] = mr
+	return mr
+}
+
+// SetError marks the manager, and therefore the whole run, as failed.
+func (mr *ManagerResult) SetError(msg string) {
+	mr.Success = false
+	mr.Error = msg
+	if mr.run != nil {
+		mr.run.Success = false
+	}
+}
+
+// AddFiles records the per file outcome held in the ChanEvent.
+func (mr *ManagerResult) AddFiles(e ChanEvent) {
+	for repo, files := range e.GetRepoFileEvents() {
+		if _, ok := mr.Files[repo]; !ok {
+			mr.Files[repo] = make(map[string]FileResult)
+		}
+		for file, ok := range files.Success {
+			res := FileResult{Success: ok}
+			if err := files.Error[file]; err != nil {
+				res.Error = err.Error()
+			}
+			mr.Files[repo][file] = res
+		}
+	}
+}
+
 type ConfigFileMap struct {
 	TmpFile string
 	Success bool
@@ -56,6 +140,8 @@ type ConfigFileMap struct {
 type ConfigSettings struct {
 	Managers map[string]*Manager `json:"managers"`
 	Globals  ConfigGlobals       `json:"globals"`
+	// source is the parsed butler configuration which the managers are read from.
+	source *viper.Viper
 }
 
 func (b *ConfigSettings) GetAllConfigLocalPaths(mgr string) []string {
@@ -75,23 +161,46 @@ func (b *ConfigSettings) GetAllConfigLocalPaths(mgr string) []string {
 }
 
 type ConfigGlobals struct {
-	Managers             []string `mapstructure:"config-managers" json:"-"`
-	SchedulerInterval    int      `json:"scheduler-interval"`
-	CfgEnableHTTPLog     string   `mapstructure:"enable-http-log" json:"-"`
-	EnableHTTPLog        bool     `json:"enable-http-log"`
-	CfgSchedulerInterval string   `mapstructure:"scheduler-interval" json:"-"`
-	CfgExitOnFailure     string   `mapstructure:"exit-on-config-failure" json:"-"`
-	ExitOnFailure        bool     `json:"exit-on-failure"`
-	CfgStatusFile        string   `mapstructure:"status-file" json:"-"`
-	StatusFile           string   `json:"status-file"`
-	CfgHTTPProto         string   `mapstructure:"http-proto" json:"-"`
-	HTTPProto            string   `json:"http-proto"`
-	CfgHTTPPort          string   `mapstructure:"http-port" json:"-"`
-	HTTPPort             int      `json:"http-port"`
-	CfgHTTPTLSCert       string   `mapstructure:"http-tls-cert" json:"-"`
-	HTTPTLSCert          string   `json:"http-tls-cert"`
-	CfgHTTPTLSKey        string   `mapstructure:"http-tls-key" json:"-"`
-	HTTPTLSKey           string   `json:"http-tls-key"`
+	Managers              []string        `mapstructure:"config-managers" json:"-"`
+	SchedulerInterval     int             `json:"scheduler-interval"`
+	CfgEnableHTTPLog      string          `mapstructure:"enable-http-log" json:"-"`
+	EnableHTTPLog         bool            `json:"enable-http-log"`
+	CfgHTTPLogFormat      string          `mapstructure:"http-log-format" json:"-"`
+	HTTPLogFormat         string          `json:"http-log-format"`
+	CfgHTTPLogFile        string          `mapstructure:"http-log-file" json:"-"`
+	HTTPLogFile           string          `json:"http-log-file,omitempty"`
+	CfgSchedulerInterval  string          `mapstructure:"scheduler-interval" json:"-"`
+	CfgExitOnFailure      string          `mapstructure:"exit-on-config-failure" json:"-"`
+	ExitOnFailure         bool            `json:"exit-on-failure"`
+	CfgStatusFile         string          `mapstructure:"status-file" json:"-"`
+	StatusFile            string          `json:"status-file"`
+	CfgHTTPProto          string          `mapstructure:"http-proto" json:"-"`
+	HTTPProto             string          `json:"http-proto"`
+	CfgHTTPPort           string          `mapstructure:"http-port" json:"-"`
+	HTTPPort              int             `json:"http-port"`
+	CfgHTTPTLSCert        string          `mapstructure:"http-tls-cert" json:"-"`
+	HTTPTLSCert           string          `json:"http-tls-cert"`
+	CfgHTTPTLSKey         string          `mapstructure:"http-tls-key" json:"-"`
+	HTTPTLSKey            string          `json:"http-tls-key"`
+	CfgHTTPTLSClientCA    string          `mapstructure:"http-tls-client-ca" json:"-"`
+	HTTPTLSClientCA       string          `json:"http-tls-client-ca,omitempty"`
+	CfgAdminToken         string          `mapstructure:"admin-token" json:"-"`
+	AdminToken            string          `json:"-"`
+	CfgLogLevel           string          `mapstructure:"log-level" json:"-"`
+	LogLevel              string          `json:"log-level,omitempty"`
+	Notifiers             []notifier.Opts `mapstructure:"notifiers" json:"notifiers,omitempty"`
+	CfgAuditLog           string          `mapstructure:"audit-log" json:"-"`
+	AuditLog              string          `json:"audit-log,omitempty"`
+	CfgAuditLogMaxSize    string          `mapstructure:"audit-log-max-size" json:"-"`
+	AuditLogMaxSize       int             `json:"audit-log-max-size,omitempty"`
+	CfgAuditLogMaxBackups string          `mapstructure:"audit-log-max-backups" json:"-"`
+	AuditLogMaxBackups    int             `json:"audit-log-max-backups,omitempty"`
+	CfgTracingEndpoint    string          `mapstructure:"tracing-endpoint" json:"-"`
+	TracingEndpoint       string          `json:"tracing-endpoint,omitempty"`
+	CfgTracingProtocol    string          `mapstructure:"tracing-protocol" json:"-"`
+	TracingProtocol       string          `json:"tracing-protocol,omitempty"`
+	CfgTracingInsecure    string          `mapstructure:"tracing-insecure" json:"-"`
+	TracingInsecure       bool            `json:"tracing-insecure,omitempty"`
 }
 
 type ValidateOpts struct {
@@ -99,6 +208,7 @@ type ValidateOpts struct {
 	Data             interface{}
 	FileName         string
 	Manager          string
+	Count            int
 	SkipButlerHeader bool
 }
 
@@ -126,6 +236,11 @@ func (o *ValidateOpts) WithManager(m string) *ValidateOpts {
 	return o
 }
 
+func (o *ValidateOpts) WithCount(c int) *ValidateOpts {
+	o.Count = c
+	return o
+}
+
 func (o *ValidateOpts) WithSkipButlerHeader(s bool) *ValidateOpts {
 	o.SkipButlerHeader = s
 	return o
@@ -106,6 +106,32 @@ func (s *ConfigTestSuite) TestRepoFileEventSetTmpFile(c *C) {
 	c.Assert(rfe.TmpFile["config.yml"], Equals, "/tmp/butler-12345")
 }
 
+func (s *ConfigTestSuite) TestRunResult(c *C) {
+	res := NewRunResult(42)
+	c.Assert(res.Count, Equals, 42)
+	c.Assert(res.Success, Equals, true)
+
+	mr := res.AddManager("prometheus")
+	c.Assert(res.Managers["prometheus"], Equals, mr)
+	c.Assert(mr.Success, Equals, true)
+
+	rfe := &RepoFileEvent{
+		Success: make(map[string]bool),
+		Error:   make(map[string]error),
+		TmpFile: make(map[string]string),
+	}
+	rfe.SetSuccess("prometheus.yml", nil)
+	rfe.SetFailure("alerts.yml", NewReloaderErrorForTest("bad yaml"))
+	mr.AddFiles(&ConfigChanEvent{Repo: map[string]*RepoFileEvent{"repo.domain.com": rfe}})
+	c.Assert(mr.Files["repo.domain.com"]["prometheus.yml"], DeepEquals, FileResult{Success: true})
+	c.Assert(mr.Files["repo.domain.com"]["alerts.yml"], DeepEquals, FileResult{Success: false, Error: "bad yaml"})
+
+	mr.SetError("could not retrieve all configuration files")
+	c.Assert(mr.Success, Equals, false)
+	c.Assert(mr.Error, Equals, "could not retrieve all configuration files")
+	c.Assert(res.Success, Equals, false)
+}
+
 func (s *ConfigTestSuite) TestConfigSettingsGetAllConfigLocalPaths(c *C) {
 	// Create a ConfigSettings with managers
 	cs := &ConfigSettings{
@@ -0,0 +1,175 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Plan does a dry run of the configuration management handler for all of the
+// configured managers. The files are downloaded, rendered, validated and
+// merged as usual, and then compared against what is on disk. Nothing is
+// written, nothing is reloaded, and the status file is left untouched.
+func (bc *ButlerConfig) Plan() (*RunResult, error) {
+	bc.runLock.Lock()
+	defer bc.runLock.Unlock()
+	return bc.planCMHandler(bc.GetManagers())
+}
+
+// PlanManager does a dry run of the configuration management handler for a
+// single manager. See Plan.
+func (bc *ButlerConfig) PlanManager(name string) (*RunResult, error) {
+	bc.runLock.Lock()
+	defer bc.runLock.Unlock()
+	m := bc.GetManager(name)
+	if m == nil {
+		return nil, fmt.Errorf("%w %v", ErrUnknownManager, name)
+	}
+	return bc.planCMHandler(map[string]*Manager{name: m})
+}
+
+func (bc *ButlerConfig) planCMHandler(managers map[string]*Manager) (*RunResult, error) {
+	var (
+		ReloadManager []string
+	)
+	log.WithField("count", bc.cmHandlerCounter).Info("Config::PlanCMHandler(): entering.")
+	result := NewRunResult(bc.cmHandlerCounter)
+	result.DryRun = true
+	defer bc.cleanTmpFiles()
+
+	active := make(map[string]*Manager)
+	for name, m := range managers {
+		if GetManagerPaused(bc.GetStatusFile(), m.Name) {
+			logEntry(bc.cmHandlerCounter, m.Name).Info("Config::PlanCMHandler(): manager is paused. skipping.")
+			result.AddManager(m.Name).Paused = true
+			continue
+		}
+		active[name] = m
+	}
+
+	c1 := make(chan ChanEvent)
+	c2 := make(chan ChanEvent)
+
+	ordered := orderManagers(active)
+	for _, m := range ordered {
+		mr := result.AddManager(m.Name)
+		m.SetCounter(bc.cmHandlerCounter)
+		go m.DownloadPrimaryConfigFiles(bc.Context(), c1)
+		go m.DownloadAdditionalConfigFiles(bc.Context(), c2)
+		PrimaryChan, AdditionalChan := <-c1, <-c2
+		bc.trackTmpFiles(PrimaryChan, AdditionalChan)
+		mr.AddFiles(PrimaryChan)
+		mr.AddFiles(AdditionalChan)
+
+		if !PrimaryChan.CanCopyFiles() || !AdditionalChan.CanCopyFiles() {
+			PrimaryChan.CleanTmpFiles()
+			AdditionalChan.CleanTmpFiles()
+			mr.SetError("could not retrieve all configuration files")
+			continue
+		}
+
+		if m.WatchOnly {
+			// Watch-only managers do not write files, so there is nothing to
+			// diff. The stored hashes are compared, but not updated.
+			pChanged, _ := PrimaryChan.ComparePrimaryConfigHashes(m.ManagerOpts, m.FileHashes)
+			aChanged, _ := AdditionalChan.CompareAdditionalConfigHashes(m.FileHashes)
+			mr.Changed = pChanged || aChanged
+		} else {
+			p, err := PrimaryChan.PlanPrimaryConfigFiles(m.ManagerOpts)
+			if err != nil {
+				mr.SetError(err.Error())
+			}
+			a, err := AdditionalChan.PlanAdditionalConfigFiles(m.DestPath)
+			if err != nil {
+				mr.SetError(err.Error())
+			}
+			mr.Changes = append(append(p, a...), m.planCleanFiles()...)
+			mr.Changed = len(mr.Changes) > 0
+		}
+		PrimaryChan.CleanTmpFiles()
+		AdditionalChan.CleanTmpFiles()
+
+		if mr.Changed {
+			ReloadManager = append(ReloadManager, m.Name)
+		}
+	}
+
+	// Mirror the reload decisions of the configuration management handler
+	if len(ReloadManager) == 0 {
+		for _, m := range ordered {
+			if !GetManagerStatus(bc.GetStatusFile(), m.Name) && failedDependency(m, result) == "" {
+				result.Managers[m.Name].WouldReload = true
+			}
+		}
+	} else {
+		for _, m := range ReloadManager {
+			if failedDependency(bc.GetManager(m), result) == "" {
+				result.Managers[m].WouldReload = true
+			}
+		}
+	}
+	log.WithField("count", bc.cmHandlerCounter).Info("Config::PlanCMHandler(): done.")
+	return result, nil
+}
+
+// planCleanFiles returns the files which clean-files would remove from the
+// destination path of the manager.
+func (bm *Manager) planCleanFiles() []FileChange {
+	var changes []FileChange
+	if !bm.CleanFiles || bm.WatchOnly {
+		return nil
+	}
+
+	known := make(map[string]bool)
+	for _, f := range bm.GetAllLocalPaths() {
+		known[f] = true
+	}
+	filepath.Walk(bm.DestPath, func(path string, f os.FileInfo, err error) error {
+		if err != nil || f.Mode().IsDir() {
+			return nil
+		}
+		if !known[path] {
+			changes = append(changes, FileChange{Path: path, Action: "remove"})
+		}
+		return nil
+	})
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// planFileChange returns the change which copying source over dest would
+// make, or nil if they are the same.
+func planFileChange(source string, dest string) (*FileChange, error) {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := os.ReadFile(dest)
+	if os.IsNotExist(err) {
+		return &FileChange{Path: dest, Action: "create", Diff: UnifiedDiff("/dev/null", dest, nil, data)}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(current, data) {
+		return nil, nil
+	}
+	return &FileChange{Path: dest, Action: "update", Diff: UnifiedDiff(dest, dest, current, data)}, nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/adobe/butler/internal/methods"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *ConfigTestSuite) TestPlanPrimaryConfigFiles(c *C) {
+	dir := c.MkDir()
+	dest := dir + "/prometheus.yml"
+	c.Assert(os.WriteFile(dest, []byte("global:\n  scrape_interval: 15s\n"), 0644), IsNil)
+	part1 := dir + "/part1"
+	c.Assert(os.WriteFile(part1, []byte("global:\n"), 0644), IsNil)
+	part2 := dir + "/part2"
+	c.Assert(os.WriteFile(part2, []byte("  scrape_interval: 30s\n"), 0644), IsNil)
+	tmpFile, err := os.CreateTemp(dir, "merged")
+	c.Assert(err, IsNil)
+
+	e := NewConfigChanEvent()
+	e.TmpFile = tmpFile
+	e.ConfigFile = &dest
+	e.SetSuccess("repo.domain.com", "one.yml", nil)
+	e.SetTmpFile("repo.domain.com", "one.yml", part1)
+	e.SetSuccess("repo.domain.com", "two.yml", nil)
+	e.SetTmpFile("repo.domain.com", "two.yml", part2)
+	opts := map[string]*ManagerOpts{"repo.domain.com": &ManagerOpts{PrimaryConfig: []string{"one.yml", "two.yml"}}}
+
+	changes, err := e.PlanPrimaryConfigFiles(opts)
+	c.Assert(err, IsNil)
+	c.Assert(changes, HasLen, 1)
+	c.Assert(changes[0].Path, Equals, dest)
+	c.Assert(changes[0].Action, Equals, "update")
+	c.Assert(changes[0].Diff, Matches, `(?s).*-  scrape_interval: 15s\n\+  scrape_interval: 30s\n`)
+
+	// The destination must be left alone
+	data, err := os.ReadFile(dest)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "global:\n  scrape_interval: 15s\n")
+}
+
+func (s *ConfigTestSuite) TestPlanAdditionalConfigFiles(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.Mkdir(dir+"/rules", 0755), IsNil)
+	c.Assert(os.WriteFile(dir+"/rules/same.yml", []byte("same\n"), 0644), IsNil)
+	src := c.MkDir()
+	c.Assert(os.WriteFile(src+"/same", []byte("same\n"), 0644), IsNil)
+	c.Assert(os.WriteFile(src+"/new", []byte("new\n"), 0644), IsNil)
+
+	e := NewConfigChanEvent()
+	e.SetSuccess("repo.domain.com", "rules/same.yml", nil)
+	e.SetTmpFile("repo.domain.com", "rules/same.yml", src+"/same")
+	e.SetSuccess("repo.domain.com", "rules/new.yml", nil)
+	e.SetTmpFile("repo.domain.com", "rules/new.yml", src+"/new")
+
+	changes, err := e.PlanAdditionalConfigFiles(dir)
+	c.Assert(err, IsNil)
+	c.Assert(changes, HasLen, 1)
+	c.Assert(changes[0].Path, Equals, dir+"/rules/new.yml")
+	c.Assert(changes[0].Action, Equals, "create")
+	_, err = os.Stat(dir + "/rules/new.yml")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *ConfigTestSuite) TestManagerPlanCleanFiles(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(dir+"/known.yml", []byte("known\n"), 0644), IsNil)
+	c.Assert(os.WriteFile(dir+"/stale.yml", []byte("stale\n"), 0644), IsNil)
+	m := &Manager{
+		Name:        "prometheus",
+		DestPath:    dir,
+		ManagerOpts: map[string]*ManagerOpts{"repo.domain.com": &ManagerOpts{PrimaryConfigsFullLocalPaths: []string{dir + "/known.yml"}}},
+	}
+	c.Assert(m.planCleanFiles(), HasLen, 0)
+
+	m.CleanFiles = true
+	changes := m.planCleanFiles()
+	c.Assert(changes, DeepEquals, []FileChange{{Path: dir + "/stale.yml", Action: "remove"}})
+	_, err := os.Stat(dir + "/stale.yml")
+	c.Assert(err, IsNil)
+}
+
+func (s *ConfigTestSuite) TestPlanManagerUnknown(c *C) {
+	bc := &ButlerConfig{Config: NewConfigSettings()}
+	_, err := bc.PlanManager("nope")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "unknown manager nope")
+	c.Assert(errors.Is(err, ErrUnknownManager), Equals, true)
+}
+
+func (s *ConfigTestSuite) TestLoadPlan(c *C) {
+	dir, dest := c.MkDir(), c.MkDir()
+	c.Assert(os.WriteFile(dir+"/prometheus.yml", []byte("#butlerstart\nglobal: {}\n#butlerend\n"), 0644), IsNil)
+	data := strings.Replace(fmt.Sprintf(TestLintConfig, dest), "/butler", dir, -1)
+	data = strings.Replace(data, `  scheduler-interval = "300"`, fmt.Sprintf(`  scheduler-interval = "300"
+  status-file = "%v/butler.status"
+  audit-log = "%v/audit.log"`, dir, dir), 1)
+	c.Assert(os.WriteFile(dir+"/butler.toml", []byte(data), 0644), IsNil)
+
+	u, err := url.Parse("file://" + dir + "/butler.toml")
+	c.Assert(err, IsNil)
+	bc, err := NewButlerConfig(&ButlerConfigOpts{URL: u})
+	c.Assert(err, IsNil)
+	bc.SetMethodOpts(methods.FileMethodOpts{Scheme: "file"})
+	c.Assert(bc.Init(), IsNil)
+
+	// The globals are parsed, but not applied
+	c.Assert(bc.Load(), IsNil)
+	c.Assert(bc.Config.Globals.AuditLog, Equals, dir+"/audit.log")
+	c.Assert(bc.getAuditLog(), IsNil)
+
+	res, err := bc.Plan()
+	c.Assert(err, IsNil)
+	c.Assert(res.Success, Equals, true)
+	c.Assert(res.Managers["prometheus"].Changed, Equals, true)
+	_, err = os.Stat(dir + "/audit.log")
+	c.Assert(os.IsNotExist(err), Equals, true)
+	_, err = os.Stat(dest + "/prometheus.yml")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"time"
+
+	"github.com/adobe/butler/internal/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// reloadLimiter keeps track of the reloads of a manager over the last hour,
+// for min-reload-interval and max-reloads-per-hour. It belongs to the
+// ButlerConfig rather than to the manager, since the managers are re-created
+// whenever the butler configuration changes.
+type reloadLimiter struct {
+	reloads []time.Time
+	timer   *time.Timer
+	// dependants are the managers which wait for the deferred reload of the
+	// manager, since they depend on it.
+	dependants map[string]bool
+}
+
+// wait returns how long the reload of m has to wait for, from now on, to stay
+// within its reload limits.
+func (l *reloadLimiter) wait(m *Manager, now time.Time) time.Duration {
+	var res time.Duration
+	for len(l.reloads) > 0 && !l.reloads[0].After(now.Add(-time.Hour)) {
+		l.reloads = l.reloads[1:]
+	}
+
+	n := len(l.reloads)
+	if m.MinReloadInterval > 0 && n > 0 {
+		res = l.reloads[n-1].Add(time.Duration(m.MinReloadInterval) * time.Second).Sub(now)
+	}
+	if m.MaxReloadsPerHour > 0 && n >= m.MaxReloadsPerHour {
+		if w := l.reloads[n-m.MaxReloadsPerHour].Add(time.Hour).Sub(now); w > res {
+			res = w
+		}
+	}
+	if res < 0 {
+		return 0
+	}
+	return res
+}
+
+// record records a reload at now.
+func (l *reloadLimiter) record(now time.Time) {
+	l.reloads = append(l.reloads, now)
+}
+
+// reloadLimiter returns the reload limiter of the named manager. The run lock
+// must be held.
+func (bc *ButlerConfig) reloadLimiter(name string) *reloadLimiter {
+	if bc.reloadLimiters == nil {
+		bc.reloadLimiters = make(map[string]*reloadLimiter)
+	}
+	if bc.reloadLimiters[name] == nil {
+		bc.reloadLimiters[name] = &reloadLimiter{}
+	}
+	return bc.reloadLimiters[name]
+}
+
+// deferReload runs the manager again once wait has passed, which reloads it
+// since it is out of sync. Any changes which arrive in the meantime are
+// coalesced into that one reload. The run lock must be held.
+func (bc *ButlerConfig) deferReload(mgr *Manager, l *reloadLimiter, wait time.Duration) {
+	metrics.SetButlerReloadPending(true, mgr.Name)
+	if l.timer != nil {
+		return
+	}
+	name := mgr.Name
+	l.timer = time.AfterFunc(wait, func() {
+		bc.runLock.Lock()
+		l.timer = nil
+		names := []string{name}
+		for d := range l.dependants {
+			names = append(names, d)
+		}
+		l.dependants = nil
+		bc.runLock.Unlock()
+
+		log.WithField("manager", name).Info("Config::deferReload(): running the deferred reload.")
+		if _, err := bc.runManagers(names); err != nil {
+			log.WithField("manager", name).Warnf("Config::deferReload(): could not run the deferred reload. err=%v", err)
+		}
+	})
+}
+
+// deferDependant defers the reload of mgr until the deferred reload of its
+// dependency dep, which runs both of them, dep first. When dep itself waits
+// for one of its own dependencies, mgr waits for that one too. The run lock
+// must be held.
+func (bc *ButlerConfig) deferDependant(mgr *Manager, dep string) {
+	metrics.SetButlerReloadPending(true, mgr.Name)
+	l := bc.reloadLimiter(dep)
+	if l.timer == nil {
+		for _, o := range bc.reloadLimiters {
+			if o.dependants[dep] {
+				l = o
+			}
+		}
+	}
+	if l.dependants == nil {
+		l.dependants = make(map[string]bool)
+	}
+	l.dependants[mgr.Name] = true
+}
+
+// stopDeferredReloads stops the deferred reloads which have not run yet. The
+// managers remain out of sync, so they are reloaded on the next start. The
+// run lock must be held.
+func (bc *ButlerConfig) stopDeferredReloads() {
+	for _, l := range bc.reloadLimiters {
+		if l.timer != nil {
+			l.timer.Stop()
+			l.timer = nil
+		}
+		l.dependants = nil
+	}
+}
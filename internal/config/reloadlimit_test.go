@@ -0,0 +1,127 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *ConfigTestSuite) TestReloadLimiterWait(c *C) {
+	now := time.Now()
+	l := &reloadLimiter{}
+
+	// Without limits, nothing ever waits
+	m := &Manager{Name: "prometheus"}
+	c.Assert(l.wait(m, now), Equals, time.Duration(0))
+	l.record(now.Add(-time.Second))
+	c.Assert(l.wait(m, now), Equals, time.Duration(0))
+
+	m.MinReloadInterval = 60
+	c.Assert(l.wait(m, now), Equals, 59*time.Second)
+	c.Assert(l.wait(m, now.Add(time.Minute)), Equals, time.Duration(0))
+
+	// The last three reloads of the hour are what counts
+	l = &reloadLimiter{}
+	m = &Manager{Name: "prometheus", MaxReloadsPerHour: 3}
+	l.record(now.Add(-90 * time.Minute))
+	l.record(now.Add(-50 * time.Minute))
+	l.record(now.Add(-40 * time.Minute))
+	c.Assert(l.wait(m, now), Equals, time.Duration(0))
+	c.Assert(l.reloads, HasLen, 2)
+	l.record(now.Add(-30 * time.Minute))
+	c.Assert(l.wait(m, now), Equals, 10*time.Minute)
+
+	// The longest of the two limits wins
+	m.MinReloadInterval = 3600
+	c.Assert(l.wait(m, now), Equals, 30*time.Minute)
+}
+
+func (s *ConfigTestSuite) TestReloadManagerDeferred(c *C) {
+	bc := &ButlerConfig{Config: NewConfigSettings()}
+	bc.Config.Globals.StatusFile = c.MkDir() + "/status"
+	m := &Manager{Name: "prometheus", MinReloadInterval: 3600}
+	bc.Config.Managers = map[string]*Manager{"prometheus": m}
+
+	mr := NewRunResult(0).AddManager("prometheus")
+	bc.reloadManager(bc.Context(), m, mr)
+	c.Assert(mr.Reloaded, Equals, true)
+	c.Assert(GetManagerStatus(bc.GetStatusFile(), "prometheus"), Equals, true)
+
+	// The second reload is within the hour, so it is deferred, and changes
+	// in the meantime are coalesced into the one deferred reload
+	timer := (*time.Timer)(nil)
+	for i := 0; i < 2; i++ {
+		mr = NewRunResult(0).AddManager("prometheus")
+		bc.reloadManager(bc.Context(), m, mr)
+		c.Assert(mr.Success, Equals, true)
+		c.Assert(mr.Reloaded, Equals, false)
+		c.Assert(mr.Deferred, Equals, true)
+		c.Assert(GetManagerStatus(bc.GetStatusFile(), "prometheus"), Equals, false)
+		c.Assert(bc.reloadLimiter("prometheus").timer, NotNil)
+		if timer != nil {
+			c.Assert(bc.reloadLimiter("prometheus").timer, Equals, timer)
+		}
+		timer = bc.reloadLimiter("prometheus").timer
+	}
+
+	bc.Shutdown()
+	c.Assert(bc.reloadLimiter("prometheus").timer, IsNil)
+}
+
+func (s *ConfigTestSuite) TestReloadManagerDeferredRuns(c *C) {
+	bc := &ButlerConfig{Config: NewConfigSettings()}
+	bc.Config.Globals.StatusFile = c.MkDir() + "/status"
+	m := &Manager{Name: "prometheus", MinReloadInterval: 3600}
+	bc.Config.Managers = map[string]*Manager{"prometheus": m}
+	bc.reloadLimiter("prometheus").record(time.Now().Add(-time.Hour + 100*time.Millisecond))
+
+	bc.runLock.Lock()
+	mr := NewRunResult(0).AddManager("prometheus")
+	bc.reloadManager(bc.Context(), m, mr)
+	bc.runLock.Unlock()
+	c.Assert(mr.Deferred, Equals, true)
+
+	// The deferred run reloads the manager, as it is out of sync
+	for i := 0; i < 50 && !GetManagerStatus(bc.GetStatusFile(), "prometheus"); i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	c.Assert(GetManagerStatus(bc.GetStatusFile(), "prometheus"), Equals, true)
+	bc.runLock.Lock()
+	// The first reload has dropped out of the hour by now
+	c.Assert(bc.reloadLimiter("prometheus").reloads, HasLen, 1)
+	bc.runLock.Unlock()
+}
+
+func (s *ConfigTestSuite) TestParseConfigReloadLimits(c *C) {
+	config := fmt.Sprintf(TestLintConfig, c.MkDir())
+	limits := func(interval string, max string) []byte {
+		return []byte(strings.Replace(config, `  primary-config-name = "prometheus.yml"`, fmt.Sprintf(`  primary-config-name = "prometheus.yml"
+  min-reload-interval = "%s"
+  max-reloads-per-hour = "%s"`, interval, max), 1))
+	}
+
+	cs, err := ParseConfig(limits("300", "4"))
+	c.Assert(err, IsNil)
+	c.Assert(cs.Managers["prometheus"].MinReloadInterval, Equals, 300)
+	c.Assert(cs.Managers["prometheus"].MaxReloadsPerHour, Equals, 4)
+	c.Assert(lintIssues(limits("300", "4")), HasLen, 0)
+
+	_, err = ParseConfig(limits("5m", "4"))
+	c.Assert(err, ErrorMatches, ".*manager.min-reload-interval=5m is not a number of seconds for manager prometheus")
+	_, err = ParseConfig(limits("300", "-1"))
+	c.Assert(err, ErrorMatches, ".*manager.max-reloads-per-hour=-1 is not a number for manager prometheus")
+}
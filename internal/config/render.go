@@ -0,0 +1,105 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Render downloads, renders, validates and merges the configuration files of
+// a single manager, as a run would, and writes the result under outDir rather
+// than the dest-path of the manager. subs override the mustache-subs of the
+// manager. Nothing is reloaded, and the status file is left untouched.
+func (bc *ButlerConfig) Render(name string, outDir string, subs map[string]string) (*RunResult, error) {
+	bc.runLock.Lock()
+	defer bc.runLock.Unlock()
+	m := bc.GetManager(name)
+	if m == nil {
+		return nil, fmt.Errorf("%w %v", ErrUnknownManager, name)
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, err
+	}
+
+	// Work on a copy of the manager, so that the overrides do not leak into
+	// the running configuration.
+	rm := *m
+	rm.SetCounter(bc.cmHandlerCounter)
+	rm.DestPath = outDir
+	rm.MustacheSubs = make(map[string]string)
+	for k, v := range m.MustacheSubs {
+		rm.MustacheSubs[k] = v
+	}
+	for k, v := range subs {
+		rm.MustacheSubs[k] = v
+	}
+	return rm.render(bc.Context()), nil
+}
+
+func (bm *Manager) render(ctx context.Context) *RunResult {
+	log.WithField("manager", bm.Name).Infof("Manager::Render(): rendering into %v.", bm.DestPath)
+	result := NewRunResult(bm.count)
+	result.DryRun = true
+	mr := result.AddManager(bm.Name)
+
+	c1 := make(chan ChanEvent)
+	c2 := make(chan ChanEvent)
+	go bm.DownloadPrimaryConfigFiles(ctx, c1)
+	go bm.DownloadAdditionalConfigFiles(ctx, c2)
+	PrimaryChan, AdditionalChan := <-c1, <-c2
+	defer PrimaryChan.CleanTmpFiles()
+	defer AdditionalChan.CleanTmpFiles()
+	mr.AddFiles(PrimaryChan)
+	mr.AddFiles(AdditionalChan)
+
+	if !PrimaryChan.CanCopyFiles() || !AdditionalChan.CanCopyFiles() {
+		mr.SetError("could not retrieve all configuration files")
+		return result
+	}
+
+	if bm.PrimaryConfigName != "" {
+		path, err := PrimaryChan.RenderPrimaryConfigFiles(bm.ManagerOpts)
+		if err != nil {
+			mr.SetError(err.Error())
+			return result
+		}
+		mr.Changes = append(mr.Changes, FileChange{Path: path, Action: "render"})
+	}
+
+	paths, err := AdditionalChan.RenderAdditionalConfigFiles(bm.DestPath)
+	for _, path := range paths {
+		mr.Changes = append(mr.Changes, FileChange{Path: path, Action: "render"})
+	}
+	if err != nil {
+		mr.SetError(err.Error())
+	}
+	mr.Changed = len(mr.Changes) > 0
+	return result
+}
+
+// renderFile copies source to dest, stripping the butler header and footer,
+// and creating the directories leading up to dest.
+func renderFile(source string, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if err := CopyFile(source, dest); err != nil {
+		return fmt.Errorf("could not write %v. err=%v", dest, err)
+	}
+	return nil
+}
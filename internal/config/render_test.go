@@ -0,0 +1,87 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+
+	. "gopkg.in/check.v1"
+)
+
+var TestRenderConfig = `#butlerstart
+[globals]
+  config-managers = ["prometheus"]
+
+[prometheus]
+  repos = ["localhost"]
+  dest-path = "%v"
+  primary-config-name = "prometheus.yml"
+  mustache-subs = ["env=dev", "region=us-east-1"]
+
+  [prometheus.localhost]
+    method = "file"
+    repo-path = "%v"
+    primary-config = ["one.yml", "two.yml"]
+    additional-config = ["rules/alerts.yml"]
+
+    [prometheus.localhost.file]
+      path = "%v"
+#butlerend
+`
+
+func (s *ConfigTestSuite) TestRender(c *C) {
+	src, dest, out := c.MkDir(), c.MkDir(), c.MkDir()+"/rendered"
+	c.Assert(os.WriteFile(src+"/one.yml", []byte("#butlerstart\nglobal:\n  env: {{env}}\n#butlerend\n"), 0644), IsNil)
+	c.Assert(os.WriteFile(src+"/two.yml", []byte("#butlerstart\n  region: {{region}}\n#butlerend\n"), 0644), IsNil)
+	c.Assert(os.Mkdir(src+"/rules", 0755), IsNil)
+	c.Assert(os.WriteFile(src+"/rules/alerts.yml", []byte("#butlerstart\ngroups: []\n#butlerend\n"), 0644), IsNil)
+
+	bc := &ButlerConfig{Config: NewConfigSettings()}
+	c.Assert(bc.Config.ParseConfig([]byte(fmt.Sprintf(TestRenderConfig, dest, src, src))), IsNil)
+
+	res, err := bc.Render("prometheus", out, map[string]string{"env": "prod"})
+	c.Assert(err, IsNil)
+	c.Assert(res.Success, Equals, true)
+	c.Assert(res.Managers["prometheus"].Changes, DeepEquals, []FileChange{
+		{Path: out + "/prometheus.yml", Action: "render"},
+		{Path: out + "/rules/alerts.yml", Action: "render"},
+	})
+
+	data, err := os.ReadFile(out + "/prometheus.yml")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "global:\n  env: prod\n  region: us-east-1\n")
+	data, err = os.ReadFile(out + "/rules/alerts.yml")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "groups: []\n")
+
+	// The overrides must not leak into the configuration, and the dest-path
+	// must be left alone
+	c.Assert(bc.GetManager("prometheus").MustacheSubs["env"], Equals, "dev")
+	entries, err := os.ReadDir(dest)
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 0)
+
+	// A missing file fails the render
+	c.Assert(os.Remove(src+"/two.yml"), IsNil)
+	res, err = bc.Render("prometheus", out, nil)
+	c.Assert(err, IsNil)
+	c.Assert(res.Success, Equals, false)
+}
+
+func (s *ConfigTestSuite) TestRenderUnknownManager(c *C) {
+	bc := &ButlerConfig{Config: NewConfigSettings()}
+	_, err := bc.Render("nope", c.MkDir(), nil)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "unknown manager nope")
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// Shutdown stops the upstream watches and deferred reloads, and waits for any
+// run in flight to finish or abort. The root context of butler should be done
+// beforehand, so that the run in flight is abandoned rather than waited for.
+// Finally, the events still being sent to the notifiers are waited for, the
+// audit log is closed, the spans still buffered are exported, and the
+// temporary files left behind by the runs of bc are removed.
+func (bc *ButlerConfig) Shutdown() {
+	bc.runLock.Lock()
+	defer bc.runLock.Unlock()
+
+	log.Infof("ButlerConfig::Shutdown(): shutting down.")
+	if bc.configWatchCancel != nil {
+		bc.configWatchCancel()
+		bc.configWatchCancel = nil
+	}
+	bc.StopManagerWatches()
+	bc.stopDeferredReloads()
+	bc.getNotifier().Wait()
+	bc.getAuditLog().Close()
+	shutdownTracing(bc.swapTracer(nil))
+	bc.cleanTmpFiles()
+	log.Infof("ButlerConfig::Shutdown(): done.")
+}
+
+// trackTmpFiles keeps track of the events of the run in flight, so that
+// their temporary files are removed even if the run does not get to it. Only
+// the temporary files of bc are ever removed, since there may be other
+// butlers on the host. The run lock must be held.
+func (bc *ButlerConfig) trackTmpFiles(events ...ChanEvent) {
+	bc.tmpFiles = append(bc.tmpFiles, events...)
+}
+
+// cleanTmpFiles removes the temporary files of the events being tracked, and
+// stops tracking them. The run lock must be held.
+func (bc *ButlerConfig) cleanTmpFiles() {
+	for _, e := range bc.tmpFiles {
+		if err := e.CleanTmpFiles(); err != nil {
+			log.Warnf("ButlerConfig::cleanTmpFiles(): could not remove temporary files. err=%v", err)
+		}
+	}
+	bc.tmpFiles = nil
+}
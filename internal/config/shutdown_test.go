@@ -0,0 +1,88 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *ConfigTestSuite) TestCleanTmpFiles(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(dir+"/bcmsfile123", []byte("tmp"), 0644), IsNil)
+	c.Assert(os.WriteFile(dir+"/bcmsfile456", []byte("tmp"), 0644), IsNil)
+	event := NewConfigChanEvent()
+	event.SetSuccess("repo1", "prometheus.yml", nil)
+	c.Assert(event.SetTmpFile("repo1", "prometheus.yml", dir+"/bcmsfile123"), IsNil)
+
+	// Only the temporary files of the run are removed, and not those of any
+	// other butler on the host
+	bc := &ButlerConfig{Config: NewConfigSettings()}
+	bc.trackTmpFiles(event)
+	bc.Shutdown()
+	_, err := os.Stat(dir + "/bcmsfile123")
+	c.Assert(os.IsNotExist(err), Equals, true)
+	_, err = os.Stat(dir + "/bcmsfile456")
+	c.Assert(err, IsNil)
+	c.Assert(bc.tmpFiles, HasLen, 0)
+}
+
+func (s *ConfigTestSuite) TestRunCleanTmpFiles(c *C) {
+	repo, dest := c.MkDir(), c.MkDir()
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {}\n#butlerend\n"), 0644), IsNil)
+	config := strings.Replace(fmt.Sprintf(TestLintConfig, dest), "/butler", repo, -1)
+	bc := &ButlerConfig{Config: NewConfigSettings(), RawConfig: []byte(config)}
+	c.Assert(bc.Config.ParseConfig([]byte(config)), IsNil)
+	bc.Config.Globals.StatusFile = c.MkDir() + "/status"
+	bc.GetManager("prometheus").Reloader = &testReloader{}
+
+	_, err := bc.Run()
+	c.Assert(err, IsNil)
+	c.Assert(bc.tmpFiles, HasLen, 0)
+}
+
+func (s *ConfigTestSuite) TestShutdown(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bc := &ButlerConfig{ctx: ctx, Config: NewConfigSettings()}
+	watchCanceled := false
+	bc.managerWatchCancel = func() { watchCanceled = true }
+	cancel()
+
+	// Nothing is run once butler is shutting down
+	_, err := bc.Run()
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(bc.Handler(), Equals, context.Canceled)
+
+	bc.Shutdown()
+	c.Assert(watchCanceled, Equals, true)
+}
+
+func (s *ConfigTestSuite) TestReloadManagerCanceled(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bc := &ButlerConfig{ctx: ctx, Config: NewConfigSettings()}
+	bc.Config.Globals.StatusFile = c.MkDir() + "/status"
+	c.Assert(SetManagerStatus(bc.GetStatusFile(), "prometheus", true), IsNil)
+	cancel()
+
+	// The reload is skipped, and the manager marked out of sync so that it
+	// gets reloaded on the next start
+	mr := NewRunResult(0).AddManager("prometheus")
+	bc.reloadManager(bc.Context(), &Manager{Name: "prometheus"}, mr)
+	c.Assert(mr.Success, Equals, false)
+	c.Assert(mr.Reloaded, Equals, false)
+	c.Assert(GetManagerStatus(bc.GetStatusFile(), "prometheus"), Equals, false)
+}
@@ -22,6 +22,7 @@ import (
 
 type Status struct {
 	Manager map[string]bool `json:"manager"`
+	Paused  map[string]bool `json:"paused,omitempty"`
 }
 
 func ReadManagerStatusFile(statusFile string) (*Status, error) {
@@ -89,3 +90,34 @@ func SetManagerStatus(statusFile string, manager string, state bool) error {
 
 	return WriteManagerStatusFile(statusFile, *status)
 }
+
+// GetManagerPaused returns whether or not the manager has been paused. A
+// manager is not paused unless the status file says so.
+func GetManagerPaused(statusFile string, manager string) bool {
+	status, err := ReadManagerStatusFile(statusFile)
+	if err != nil {
+		log.Debugf("GetManagerPaused(): could not read manager %v, returning false", statusFile)
+		return false
+	}
+	return status.Paused[manager]
+}
+
+// SetManagerPaused records whether or not the manager is paused in the
+// status file, so that the pause survives a restart of butler.
+func SetManagerPaused(statusFile string, manager string, paused bool) error {
+	status, err := ReadManagerStatusFile(statusFile)
+	if (err != nil) || (status.Manager == nil) {
+		status.Manager = make(map[string]bool)
+	}
+	if status.Paused == nil {
+		status.Paused = make(map[string]bool)
+	}
+
+	if paused {
+		status.Paused[manager] = true
+	} else {
+		delete(status.Paused, manager)
+	}
+
+	return WriteManagerStatusFile(statusFile, *status)
+}
@@ -156,3 +156,64 @@ func (s *ConfigTestSuite) TestSetManagerStatusNewFile(c *C) {
 	result := GetManagerStatus(tmpFile.Name(), "newmanager")
 	c.Assert(result, Equals, true)
 }
+
+func (s *ConfigTestSuite) TestSetManagerPaused(c *C) {
+	tmpFile, err := os.CreateTemp("", "butler-status-paused-*.json")
+	c.Assert(err, IsNil)
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	// Nothing is paused unless the status file says so
+	c.Assert(GetManagerPaused(tmpFile.Name(), "alertmanager"), Equals, false)
+	c.Assert(GetManagerPaused("/nonexistent/status.json", "alertmanager"), Equals, false)
+
+	err = SetManagerStatus(tmpFile.Name(), "alertmanager", true)
+	c.Assert(err, IsNil)
+	err = SetManagerPaused(tmpFile.Name(), "alertmanager", true)
+	c.Assert(err, IsNil)
+	c.Assert(GetManagerPaused(tmpFile.Name(), "alertmanager"), Equals, true)
+	c.Assert(GetManagerPaused(tmpFile.Name(), "prometheus"), Equals, false)
+
+	// The pause and the manager status must not clobber each other
+	c.Assert(GetManagerStatus(tmpFile.Name(), "alertmanager"), Equals, true)
+	err = SetManagerStatus(tmpFile.Name(), "prometheus", true)
+	c.Assert(err, IsNil)
+	c.Assert(GetManagerPaused(tmpFile.Name(), "alertmanager"), Equals, true)
+
+	err = SetManagerPaused(tmpFile.Name(), "alertmanager", false)
+	c.Assert(err, IsNil)
+	c.Assert(GetManagerPaused(tmpFile.Name(), "alertmanager"), Equals, false)
+	c.Assert(GetManagerStatus(tmpFile.Name(), "alertmanager"), Equals, true)
+}
+
+func (s *ConfigTestSuite) TestPauseManager(c *C) {
+	tmpFile, err := os.CreateTemp("", "butler-status-pause-*.json")
+	c.Assert(err, IsNil)
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	bc := &ButlerConfig{Config: NewConfigSettings()}
+	bc.Config.Globals.StatusFile = tmpFile.Name()
+	bc.Config.Managers = map[string]*Manager{"alertmanager": &Manager{Name: "alertmanager"}}
+
+	c.Assert(bc.PauseManager("nope"), NotNil)
+	c.Assert(bc.PauseManager("alertmanager"), IsNil)
+	c.Assert(bc.GetManager("alertmanager").Paused, Equals, true)
+	c.Assert(GetManagerPaused(tmpFile.Name(), "alertmanager"), Equals, true)
+
+	// A paused manager is skipped by the run altogether
+	res, err := bc.RunManager("alertmanager")
+	c.Assert(err, IsNil)
+	c.Assert(res.Success, Equals, true)
+	c.Assert(res.Managers["alertmanager"].Paused, Equals, true)
+	c.Assert(res.Managers["alertmanager"].Files, HasLen, 0)
+
+	// The pause survives the managers being re-created
+	bc.Config.Managers = map[string]*Manager{"alertmanager": &Manager{Name: "alertmanager"}}
+	bc.loadPausedManagers()
+	c.Assert(bc.GetManager("alertmanager").Paused, Equals, true)
+
+	c.Assert(bc.ResumeManager("alertmanager"), IsNil)
+	c.Assert(bc.GetManager("alertmanager").Paused, Equals, false)
+	c.Assert(GetManagerPaused(tmpFile.Name(), "alertmanager"), Equals, false)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/adobe/butler/internal/audit"
+	"github.com/adobe/butler/internal/metrics"
+)
+
+// syncState keeps track of how far the applied configuration of a manager is
+// behind upstream. Like the reload limiter, it belongs to the ButlerConfig,
+// since the managers are re-created whenever the butler configuration
+// changes.
+type syncState struct {
+	// hash is the sha256 of the configuration files the manager has applied.
+	hash string
+	// behind is whether or not upstream has changed since the manager was
+	// last reloaded.
+	behind bool
+	// since is when upstream was first found to differ from the applied
+	// configuration, or zero when the manager is in sync.
+	since time.Time
+}
+
+// configHash returns the sha256 of a set of files, over the path and sha256
+// of each of them, so that it changes whenever any one of the files does.
+func configHash(files []string) string {
+	hashes := hashFiles(files)
+	paths := make([]string, 0, len(hashes))
+	for f := range hashes {
+		paths = append(paths, f)
+	}
+	sort.Strings(paths)
+
+	var data []byte
+	for _, f := range paths {
+		data = append(data, fmt.Sprintf("%s  %s\n", hashes[f], f)...)
+	}
+	return ComputeDataHash(data)
+}
+
+// syncState returns the sync state of the named manager. The run lock must
+// be held.
+func (bc *ButlerConfig) syncState(name string) *syncState {
+	if bc.syncStates == nil {
+		bc.syncStates = make(map[string]*syncState)
+	}
+	if bc.syncStates[name] == nil {
+		bc.syncStates[name] = &syncState{}
+	}
+	return bc.syncStates[name]
+}
+
+// observeSync works out, from the outcome of the run, whether or not the
+// managers have applied what is upstream. Once the files of a manager
+// change, it is behind upstream until a reload goes through, however many
+// runs that takes. When the files could not be retrieved, upstream is
+// unknown, and the manager is left as it was.
+func (bc *ButlerConfig) observeSync(managers []*Manager, result *RunResult, now time.Time) {
+	for _, m := range managers {
+		mr := result.Managers[m.Name]
+		st := bc.syncState(m.Name)
+		switch {
+		case mr.reload != nil:
+			st.behind = mr.reload.Outcome != audit.ReloadSuccess && mr.reload.Outcome != audit.ReloadTimeout
+		case mr.Changed || !GetManagerStatus(bc.GetStatusFile(), m.Name):
+			st.behind = true
+		}
+
+		if st.behind {
+			if st.since.IsZero() {
+				st.since = now
+			}
+			// A deferred reload is held back on purpose, and is shown by
+			// butler_manager_reload_pending instead.
+			metrics.SetButlerManagerOutOfSync(!mr.Deferred, m.Name)
+		} else {
+			// The files are only applied once they are reloaded, since a
+			// failed reload may still roll them back.
+			if st.hash == "" || mr.Changed || mr.reload != nil {
+				if hash := configHash(bc.Config.GetAllConfigLocalPaths(m.Name)); hash != st.hash {
+					// The first run after a restart only finds out what was
+					// applied before, unless it changes the files itself.
+					if st.hash != "" || mr.Changed || mr.reload != nil {
+						metrics.SetButlerManagerLastApply(m.Name)
+					}
+					st.hash = hash
+					metrics.SetButlerManagerConfigHash(st.hash, m.Name)
+				}
+			}
+			st.since = time.Time{}
+			metrics.SetButlerManagerOutOfSync(false, m.Name)
+		}
+
+		var age time.Duration
+		if !st.since.IsZero() {
+			age = now.Sub(st.since)
+		}
+		metrics.SetButlerManagerConfigAge(age, m.Name)
+	}
+}
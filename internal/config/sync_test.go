@@ -0,0 +1,134 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *ConfigTestSuite) TestConfigHash(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(dir+"/a.yml", []byte("a"), 0644), IsNil)
+	c.Assert(os.WriteFile(dir+"/b.yml", []byte("b"), 0644), IsNil)
+
+	hash := configHash([]string{dir + "/a.yml", dir + "/b.yml"})
+	c.Assert(hash, HasLen, 64)
+	c.Assert(configHash([]string{dir + "/b.yml", dir + "/a.yml"}), Equals, hash)
+
+	c.Assert(os.WriteFile(dir+"/b.yml", []byte("c"), 0644), IsNil)
+	c.Assert(configHash([]string{dir + "/a.yml", dir + "/b.yml"}), Not(Equals), hash)
+	c.Assert(configHash([]string{dir + "/a.yml"}), Not(Equals), hash)
+}
+
+// newTestSync returns a ButlerConfig with the manager name, which retrieves
+// prometheus.yml from the repo directory into dest. The manager is named after
+// the test, since the metrics are shared with the other tests.
+func newTestSync(c *C, name string, repo string, dest string, status string) (*ButlerConfig, *Manager) {
+	config := strings.Replace(fmt.Sprintf(TestLintConfig, dest), "/butler", repo, -1)
+	config = strings.Replace(config, "repo1.domain.com", "localhost", -1)
+	config = strings.Replace(config, "prometheus", name, -1)
+	config = strings.Replace(config, name+".yml", "prometheus.yml", -1)
+	bc := &ButlerConfig{Config: NewConfigSettings(), RawConfig: []byte(config)}
+	c.Assert(bc.Config.ParseConfig([]byte(config)), IsNil)
+	bc.Config.Globals.StatusFile = status
+	m := bc.GetManager(name)
+	c.Assert(m, NotNil)
+	m.Reloader = &testReloader{}
+	return bc, m
+}
+
+func (s *ConfigTestSuite) TestRunSync(c *C) {
+	repo, dest := c.MkDir(), c.MkDir()
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {}\n#butlerend\n"), 0644), IsNil)
+	bc, m := newTestSync(c, "sync", repo, dest, c.MkDir()+"/status")
+	labels := map[string]string{"manager": "sync"}
+
+	// The files are applied, and reloaded
+	start := float64(time.Now().Unix())
+	_, err := bc.Run()
+	c.Assert(err, IsNil)
+	applied := configHash(bc.Config.GetAllConfigLocalPaths("sync"))
+	c.Assert(metricValue(c, "butler_manager_config_info", map[string]string{"manager": "sync", "sha256": applied}), Equals, 1.0)
+	lastApply := metricValue(c, "butler_manager_last_apply_timestamp_seconds", labels)
+	c.Assert(lastApply >= start, Equals, true)
+	c.Assert(metricValue(c, "butler_manager_last_reload_timestamp_seconds", labels) >= start, Equals, true)
+	c.Assert(metricValue(c, "butler_manager_out_of_sync", labels), Equals, 0.0)
+	c.Assert(metricValue(c, "butler_manager_config_age_seconds", labels), Equals, 0.0)
+
+	// Upstream changes, but the reload fails. The manager stays on what it
+	// applied before.
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {scrape_interval: 30s}\n#butlerend\n"), 0644), IsNil)
+	m.Reloader = &testReloader{err: fmt.Errorf("connection refused")}
+	_, err = bc.Run()
+	c.Assert(err, IsNil)
+	c.Assert(metricValue(c, "butler_manager_config_info", map[string]string{"manager": "sync", "sha256": applied}), Equals, 1.0)
+	c.Assert(metricValue(c, "butler_manager_out_of_sync", labels), Equals, 1.0)
+	c.Assert(metricValue(c, "butler_manager_last_apply_timestamp_seconds", labels), Equals, lastApply)
+	since := bc.syncState("sync").since
+	c.Assert(since.IsZero(), Equals, false)
+
+	// The manager is still stuck on the next run, although nothing has
+	// changed upstream, and the config keeps on ageing.
+	_, err = bc.Run()
+	c.Assert(err, IsNil)
+	c.Assert(metricValue(c, "butler_manager_out_of_sync", labels), Equals, 1.0)
+	c.Assert(bc.syncState("sync").since, Equals, since)
+	result := NewRunResult(0)
+	result.AddManager("sync").SetError("connection refused")
+	bc.observeSync([]*Manager{m}, result, since.Add(time.Minute))
+	c.Assert(metricValue(c, "butler_manager_config_age_seconds", labels), Equals, 60.0)
+
+	// Once a reload succeeds, the manager is back in sync
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {scrape_interval: 60s}\n#butlerend\n"), 0644), IsNil)
+	m.Reloader = &testReloader{}
+	_, err = bc.Run()
+	c.Assert(err, IsNil)
+	c.Assert(metricValue(c, "butler_manager_config_info", map[string]string{"manager": "sync", "sha256": applied}), Equals, 0.0)
+	c.Assert(metricValue(c, "butler_manager_config_info", map[string]string{"manager": "sync", "sha256": configHash(bc.Config.GetAllConfigLocalPaths("sync"))}), Equals, 1.0)
+	c.Assert(metricValue(c, "butler_manager_out_of_sync", labels), Equals, 0.0)
+	c.Assert(metricValue(c, "butler_manager_config_age_seconds", labels), Equals, 0.0)
+	c.Assert(metricValue(c, "butler_manager_last_apply_timestamp_seconds", labels) > lastApply, Equals, true)
+	c.Assert(bc.syncState("sync").since.IsZero(), Equals, true)
+}
+
+func (s *ConfigTestSuite) TestRunSyncRestart(c *C) {
+	repo, dest, status := c.MkDir(), c.MkDir(), c.MkDir()+"/status"
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {}\n#butlerend\n"), 0644), IsNil)
+	labels := map[string]string{"manager": "syncrestart"}
+	bc, _ := newTestSync(c, "syncrestart", repo, dest, status)
+	_, err := bc.Run()
+	c.Assert(err, IsNil)
+	lastApply := metricValue(c, "butler_manager_last_apply_timestamp_seconds", labels)
+	c.Assert(lastApply > 0, Equals, true)
+
+	// butler restarts, and finds the files it applied before unchanged. They
+	// are not applied again.
+	bc, _ = newTestSync(c, "syncrestart", repo, dest, status)
+	_, err = bc.Run()
+	c.Assert(err, IsNil)
+	applied := configHash(bc.Config.GetAllConfigLocalPaths("syncrestart"))
+	c.Assert(metricValue(c, "butler_manager_config_info", map[string]string{"manager": "syncrestart", "sha256": applied}), Equals, 1.0)
+	c.Assert(metricValue(c, "butler_manager_out_of_sync", labels), Equals, 0.0)
+	c.Assert(metricValue(c, "butler_manager_last_apply_timestamp_seconds", labels), Equals, lastApply)
+
+	// Changes after the restart are applied
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {scrape_interval: 30s}\n#butlerend\n"), 0644), IsNil)
+	_, err = bc.Run()
+	c.Assert(err, IsNil)
+	c.Assert(metricValue(c, "butler_manager_last_apply_timestamp_seconds", labels) > lastApply, Equals, true)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"time"
+
+	"github.com/adobe/butler/internal/tracing"
+
+	log "github.com/sirupsen/logrus"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// getTracer returns the provider of the spans of bc, or nil when they go to
+// the default provider of the tracing package.
+func (bc *ButlerConfig) getTracer() *sdktrace.TracerProvider {
+	bc.globalsLock.Lock()
+	defer bc.globalsLock.Unlock()
+	return bc.tracer
+}
+
+// swapTracer makes p the provider of the spans of bc, and returns the
+// previous one, so that it can be shut down.
+func (bc *ButlerConfig) swapTracer(p *sdktrace.TracerProvider) *sdktrace.TracerProvider {
+	bc.globalsLock.Lock()
+	defer bc.globalsLock.Unlock()
+	prev := bc.tracer
+	bc.tracer = p
+	return prev
+}
+
+// setTracing exports the spans of bc to the tracing-endpoint of the globals,
+// or leaves them to the default provider when there is none, shutting down
+// the previous exporter of bc. The default provider, and so the spans of any
+// other butler in the process, are left alone.
+func (bc *ButlerConfig) setTracing(g ConfigGlobals) {
+	var p *sdktrace.TracerProvider
+	if g.TracingEndpoint != "" {
+		var err error
+		p, err = tracing.New(tracing.Opts{Endpoint: g.TracingEndpoint, Protocol: g.TracingProtocol, Insecure: g.TracingInsecure})
+		if err != nil {
+			log.Errorf("ButlerConfig::setTracing(): could not export spans to %v. err=%v", g.TracingEndpoint, err)
+		}
+	}
+	shutdownTracing(bc.swapTracer(p))
+}
+
+// shutdownTracing exports the spans which p still holds on to, and shuts it
+// down. It is given 5 seconds to do so.
+func shutdownTracing(p *sdktrace.TracerProvider) {
+	if p == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != nil {
+		log.Warnf("ButlerConfig::shutdownTracing(): could not export the remaining spans. err=%v", err)
+	}
+}
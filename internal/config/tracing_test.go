@@ -0,0 +1,147 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adobe/butler/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	. "gopkg.in/check.v1"
+)
+
+// spanAttrs returns the attributes of span by key.
+func spanAttrs(span tracetest.SpanStub) map[attribute.Key]string {
+	res := make(map[attribute.Key]string)
+	for _, kv := range span.Attributes {
+		res[kv.Key] = kv.Value.Emit()
+	}
+	return res
+}
+
+func (s *ConfigTestSuite) TestParseConfigTracing(c *C) {
+	config := fmt.Sprintf(TestLintConfig, c.MkDir())
+	cs := NewConfigSettings()
+	c.Assert(cs.ParseConfig([]byte(config)), IsNil)
+	c.Assert(cs.Globals.TracingEndpoint, Equals, "")
+	c.Assert(cs.Globals.TracingProtocol, Equals, tracing.ProtocolGRPC)
+	c.Assert(cs.Globals.TracingInsecure, Equals, false)
+
+	config = strings.Replace(config, `  scheduler-interval = "300"`, `  scheduler-interval = "300"
+  tracing-endpoint = "http://collector:4318"
+  tracing-protocol = "HTTP"
+  tracing-insecure = "true"`, 1)
+	c.Assert(cs.ParseConfig([]byte(config)), IsNil)
+	c.Assert(cs.Globals.TracingEndpoint, Equals, "http://collector:4318")
+	c.Assert(cs.Globals.TracingProtocol, Equals, tracing.ProtocolHTTP)
+	c.Assert(cs.Globals.TracingInsecure, Equals, true)
+	c.Assert(lintIssues([]byte(config)), HasLen, 0)
+
+	config = strings.Replace(config, `"HTTP"`, `"udp"`, 1)
+	err := cs.ParseConfig([]byte(config))
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "globals.tracing-protocol=udp is not a valid tracing protocol")
+}
+
+func (s *ConfigTestSuite) TestTracingPerButler(c *C) {
+	def := tracetest.NewInMemoryExporter()
+	tracing.SetProvider(tracing.NewProvider(sdktrace.WithSyncer(def)))
+	defer tracing.SetProvider(nil)
+
+	// Each butler has spans of its own, and those without a tracing-endpoint
+	// use the default provider
+	own := tracetest.NewInMemoryExporter()
+	bc1 := &ButlerConfig{Config: NewConfigSettings()}
+	bc1.swapTracer(tracing.NewProvider(sdktrace.WithSyncer(own)))
+	bc2 := &ButlerConfig{Config: NewConfigSettings()}
+	_, span := tracing.Start(bc1.Context(), "ButlerConfig.Handler")
+	span.End()
+	_, span = tracing.Start(bc2.Context(), "ButlerConfig.Handler")
+	span.End()
+	c.Assert(own.GetSpans(), HasLen, 1)
+	c.Assert(def.GetSpans(), HasLen, 1)
+
+	// Shutting down one butler leaves the spans of the others alone
+	bc2.Shutdown()
+	_, span = tracing.Start(bc1.Context(), "ButlerConfig.Handler")
+	span.End()
+	c.Assert(own.GetSpans(), HasLen, 2)
+	c.Assert(def.GetSpans(), HasLen, 1)
+
+	// Once shut down, the spans of the butler go to the default provider
+	bc1.Shutdown()
+	c.Assert(bc1.getTracer(), IsNil)
+	_, span = tracing.Start(bc1.Context(), "ButlerConfig.Handler")
+	span.End()
+	c.Assert(def.GetSpans(), HasLen, 2)
+}
+
+func (s *ConfigTestSuite) TestRunTracing(c *C) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetProvider(tracing.NewProvider(sdktrace.WithSyncer(exporter)))
+	defer tracing.SetProvider(nil)
+
+	repo, dest := c.MkDir(), c.MkDir()
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {}\n#butlerend\n"), 0644), IsNil)
+	config := strings.Replace(fmt.Sprintf(TestLintConfig, dest), "/butler", repo, -1)
+	config = strings.Replace(config, "repo1.domain.com", "localhost", -1)
+	bc := &ButlerConfig{Config: NewConfigSettings(), RawConfig: []byte(config)}
+	c.Assert(bc.Config.ParseConfig([]byte(config)), IsNil)
+	bc.Config.Globals.StatusFile = c.MkDir() + "/status"
+	m := bc.GetManager("prometheus")
+	m.Reloader = &testReloader{}
+
+	_, err := bc.Run()
+	c.Assert(err, IsNil)
+
+	// Every span of the run belongs to the trace of the run
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub)
+	var names []string
+	for _, span := range spans {
+		byName[span.Name] = span
+		names = append(names, span.Name)
+	}
+	c.Assert(names, DeepEquals, []string{"Method.Get", "RenderConfigMustache", "ValidateConfig", "Manager.CopyConfigFiles", "Reloader.Reload", "ButlerConfig.RunCMHandler"})
+	run := byName["ButlerConfig.RunCMHandler"]
+	for _, span := range spans[:len(spans)-1] {
+		c.Assert(span.Parent.SpanID(), Equals, run.SpanContext.SpanID())
+	}
+
+	get := spanAttrs(byName["Method.Get"])
+	c.Assert(get[tracing.ManagerKey], Equals, "prometheus")
+	c.Assert(get[tracing.RepoKey], Equals, "localhost")
+	c.Assert(get[tracing.MethodKey], Equals, "file")
+	c.Assert(get[tracing.FileKey], Equals, repo+"/prometheus.yml")
+	c.Assert(spanAttrs(byName["ValidateConfig"])[tracing.FileKey], Equals, "prometheus.yml")
+	c.Assert(spanAttrs(byName["Manager.CopyConfigFiles"])[tracing.ChangedKey], Equals, "true")
+	c.Assert(spanAttrs(byName["Reloader.Reload"])[tracing.ReloaderKey], Equals, "test")
+
+	// A failed reload fails its span
+	exporter.Reset()
+	c.Assert(os.WriteFile(repo+"/prometheus.yml", []byte("#butlerstart\nglobal: {scrape_interval: 30s}\n#butlerend\n"), 0644), IsNil)
+	m.Reloader = &testReloader{err: fmt.Errorf("connection refused")}
+	_, err = bc.Run()
+	c.Assert(err, IsNil)
+	spans = exporter.GetSpans()
+	reload := spans[len(spans)-2]
+	c.Assert(reload.Name, Equals, "Reloader.Reload")
+	c.Assert(reload.Status.Code, Equals, codes.Error)
+	c.Assert(reload.Status.Description, Equals, "connection refused")
+}
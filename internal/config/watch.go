@@ -0,0 +1,124 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+
+	"github.com/adobe/butler/internal/methods"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// watchTrigger coalesces upstream change notifications. Any number of
+// notifications which arrive while a run is in flight result in a single
+// follow up run.
+type watchTrigger struct {
+	c chan struct{}
+}
+
+func newWatchTrigger() *watchTrigger {
+	return &watchTrigger{c: make(chan struct{}, 1)}
+}
+
+// Notify flags that a run is needed. It never blocks.
+func (t *watchTrigger) Notify() {
+	select {
+	case t.c <- struct{}{}:
+	default:
+	}
+}
+
+// Run calls f once for each coalesced notification until ctx is done.
+func (t *watchTrigger) Run(ctx context.Context, f func()) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.c:
+			f()
+		}
+	}
+}
+
+// StartConfigWatch starts watching the butler configuration itself, if the
+// configuration client supports it and has watching enabled. A change to the
+// butler configuration re-runs the Handler, followed by the configuration
+// management handler. The regular scheduler keeps running as a safety net.
+func (bc *ButlerConfig) StartConfigWatch() {
+	if bc.Client == nil {
+		return
+	}
+	w, ok := bc.Client.Method.(methods.Watcher)
+	if !ok || !w.WatchEnabled() {
+		return
+	}
+
+	if bc.configWatchCancel != nil {
+		bc.configWatchCancel()
+	}
+	ctx, cancel := context.WithCancel(bc.Context())
+	bc.configWatchCancel = cancel
+
+	trigger := newWatchTrigger()
+	go trigger.Run(ctx, func() {
+		log.Infof("ButlerConfig::StartConfigWatch(): butler configuration changed upstream. running handlers.")
+		if err := bc.Handler(); err != nil {
+			log.Errorf("ButlerConfig::StartConfigWatch(): could not handle butler configuration. err=%v", err)
+			return
+		}
+		bc.RunCMHandler()
+	})
+	go w.WatchKey(ctx, bc.URL().Path, trigger.Notify)
+}
+
+// StartManagerWatches (re)starts the upstream watches for every manager
+// repository whose method supports it and has watching enabled. Watches which
+// were previously running are stopped first, since the managers may have
+// changed underneath them.
+func (bc *ButlerConfig) StartManagerWatches() {
+	bc.StopManagerWatches()
+	if bc.Config == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(bc.Context())
+	bc.managerWatchCancel = cancel
+
+	for _, m := range bc.GetManagers() {
+		var trigger *watchTrigger
+		name := m.Name
+		for _, opts := range m.ManagerOpts {
+			w, ok := opts.Opts.(methods.Watcher)
+			if !ok || !w.WatchEnabled() {
+				continue
+			}
+			if trigger == nil {
+				trigger = newWatchTrigger()
+				go trigger.Run(ctx, func() {
+					log.WithField("manager", name).Info("ButlerConfig::StartManagerWatches(): upstream change detected. running manager.")
+					bc.RunManager(name)
+				})
+			}
+			go w.WatchKey(ctx, opts.GetWatchKey(), trigger.Notify)
+		}
+	}
+}
+
+// StopManagerWatches stops all the running manager repository watches.
+func (bc *ButlerConfig) StopManagerWatches() {
+	if bc.managerWatchCancel != nil {
+		bc.managerWatchCancel()
+		bc.managerWatchCancel = nil
+	}
+}
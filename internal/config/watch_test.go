@@ -0,0 +1,52 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *ConfigTestSuite) TestWatchTriggerCoalesces(c *C) {
+	t := newWatchTrigger()
+	t.Notify()
+	t.Notify()
+	t.Notify()
+
+	runs := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		t.Run(ctx, func() {
+			runs++
+			cancel()
+		})
+		done <- true
+	}()
+	<-done
+	c.Assert(runs, Equals, 1)
+}
+
+func (s *ConfigTestSuite) TestManagerOptsGetWatchKey(c *C) {
+	c.Assert((&ManagerOpts{RepoPath: ""}).GetWatchKey(), Equals, "/")
+	c.Assert((&ManagerOpts{RepoPath: "butler/prometheus"}).GetWatchKey(), Equals, "/butler/prometheus")
+	c.Assert((&ManagerOpts{RepoPath: "/butler/prometheus"}).GetWatchKey(), Equals, "/butler/prometheus")
+}
+
+func (s *ConfigTestSuite) TestRunManagerUnknown(c *C) {
+	bc := &ButlerConfig{Config: NewConfigSettings()}
+	_, err := bc.RunManager("nope")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "unknown manager nope")
+}
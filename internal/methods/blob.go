@@ -13,6 +13,7 @@ governing permissions and limitations under the License.
 package methods
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -39,15 +40,15 @@ type BlobMethodOpts struct {
 	AccountKey  string
 }
 
-func NewBlobMethod(manager *string, entry *string) (Method, error) {
+func NewBlobMethod(v *viper.Viper, manager *string, entry *string) (Method, error) {
 	var (
 		client storage.Client
 		err    error
 		result BlobMethod
 	)
 
-	if (manager != nil) && (entry != nil) {
-		err = viper.UnmarshalKey(*entry, &result)
+	if (v != nil) && (manager != nil) && (entry != nil) {
+		err = v.UnmarshalKey(*entry, &result)
 		if err != nil {
 			return result, err
 		}
@@ -109,10 +110,15 @@ func NewBlobMethodWithAccountAndKey(account string, key string) (Method, error)
 	return result, err
 }
 
-func (b BlobMethod) Get(u *url.URL) (*Response, error) {
+func (b BlobMethod) Get(ctx context.Context, u *url.URL) (*Response, error) {
 	var (
 		res Response
 	)
+	// The blob storage client does not take a context, so the best we can do
+	// is to not start the download.
+	if err := ctx.Err(); err != nil {
+		return &Response{}, err
+	}
 	pathSplit := strings.Split(u.Path, "/")
 
 	if len(pathSplit) < 2 {
@@ -14,6 +14,7 @@ package methods
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -31,6 +32,7 @@ import (
 var _ = Suite(&BlobTestSuite{})
 
 type BlobTestSuite struct {
+	v *viper.Viper
 }
 
 var TestViperConfigBlob = []byte(`[test-manager]
@@ -87,7 +89,8 @@ var TestViperConfigBlobNoAccount = []byte(`[test-manager]
 `)
 
 func (s *BlobTestSuite) SetUpSuite(c *C) {
-	viper.SetConfigType("toml")
+	s.v = viper.New()
+	s.v.SetConfigType("toml")
 }
 
 func (s *BlobTestSuite) TearDownSuite(c *C) {
@@ -95,7 +98,7 @@ func (s *BlobTestSuite) TearDownSuite(c *C) {
 
 func (s *BlobTestSuite) TestNewBlobMethod(c *C) {
 	// load config
-	err := viper.ReadConfig(bytes.NewBuffer(TestViperConfigBlob))
+	err := s.v.ReadConfig(bytes.NewBuffer(TestViperConfigBlob))
 	c.Assert(err, IsNil)
 
 	// Reset some environment
@@ -106,7 +109,7 @@ func (s *BlobTestSuite) TestNewBlobMethod(c *C) {
 	manager := "test-manager"
 	entry := "test-manager.repo.blob"
 
-	method, err := NewBlobMethod(&manager, &entry)
+	method, err := NewBlobMethod(s.v, &manager, &entry)
 	m := method.(BlobMethod)
 	c.Assert(m.StorageAccount, Equals, "stegentestblobva7")
 	c.Assert(m.StorageKey, Equals, "aGl5YWhpeWFoaXlh")
@@ -114,12 +117,12 @@ func (s *BlobTestSuite) TestNewBlobMethod(c *C) {
 	c.Assert(err, IsNil)
 
 	// Let's reset the viper config
-	err = viper.ReadConfig(bytes.NewBuffer(TestViperConfigBlobNoAccount))
+	err = s.v.ReadConfig(bytes.NewBuffer(TestViperConfigBlobNoAccount))
 	c.Assert(err, IsNil)
 
 	// Let's override the storage account
 	os.Setenv("ACCOUNT_NAME", "newblob")
-	method, err = NewBlobMethod(&manager, &entry)
+	method, err = NewBlobMethod(s.v, &manager, &entry)
 	m = method.(BlobMethod)
 	c.Assert(m.StorageAccount, Equals, "")
 	c.Assert(m.AzureClient.HTTPClient, IsNil)
@@ -129,14 +132,14 @@ func (s *BlobTestSuite) TestNewBlobMethod(c *C) {
 	os.Unsetenv("ACCOUNT_KEY")
 
 	// test out the environment stuff
-	err = viper.ReadConfig(bytes.NewBuffer(TestViperConfigBlobEnv))
+	err = s.v.ReadConfig(bytes.NewBuffer(TestViperConfigBlobEnv))
 	c.Assert(err, IsNil)
-	method, err = NewBlobMethod(&manager, &entry)
+	method, err = NewBlobMethod(s.v, &manager, &entry)
 	c.Assert(err, NotNil)
 
 	os.Setenv("ACCOUNT_NAME", "boombam")
 	os.Setenv("ACCOUNT_KEY", "hiya")
-	method, err = NewBlobMethod(&manager, &entry)
+	method, err = NewBlobMethod(s.v, &manager, &entry)
 	c.Assert(err, IsNil)
 	m = method.(BlobMethod)
 	c.Assert(m.StorageAccount, Equals, "boombam")
@@ -146,7 +149,7 @@ func (s *BlobTestSuite) TestNewBlobMethod(c *C) {
 
 func (s *BlobTestSuite) TestNewBlobMethodWithAccountAndKey(c *C) {
 	// load config
-	err := viper.ReadConfig(bytes.NewBuffer(TestViperConfigBlob))
+	err := s.v.ReadConfig(bytes.NewBuffer(TestViperConfigBlob))
 	c.Assert(err, IsNil)
 
 	// setup some stuff
@@ -182,7 +185,7 @@ func (s *BlobTestSuite) TestNewBlobMethodWithAccountAndKey(c *C) {
 
 func (s *BlobTestSuite) TestGet(c *C) {
 	// load config
-	err := viper.ReadConfig(bytes.NewBuffer(TestViperConfigBlob))
+	err := s.v.ReadConfig(bytes.NewBuffer(TestViperConfigBlob))
 	c.Assert(err, IsNil)
 
 	// setup some stuff
@@ -192,12 +195,12 @@ func (s *BlobTestSuite) TestGet(c *C) {
 	os.Setenv("BUTLER_STORAGE_TOKEN", "hiya")
 
 	// This will error due to no BUTLER_STORAGE_TOKEN
-	method, err := NewBlobMethod(&manager, &entry)
+	method, err := NewBlobMethod(s.v, &manager, &entry)
 	c.Assert(err, IsNil)
 
 	u, err := url.Parse("none")
 	c.Assert(err, IsNil)
-	resp, err := method.Get(u)
+	resp, err := method.Get(context.Background(), u)
 	c.Assert(err, NotNil)
 
 	var b *storage.Blob
@@ -208,7 +211,7 @@ func (s *BlobTestSuite) TestGet(c *C) {
 
 	u, err = url.Parse("/foo/bar")
 	c.Assert(err, IsNil)
-	resp, err = method.Get(u)
+	resp, err = method.Get(context.Background(), u)
 	c.Assert(err, IsNil)
 	c.Assert(resp, NotNil)
 	out, err := ioutil.ReadAll(resp.GetResponseBody())
@@ -221,7 +224,7 @@ func (s *BlobTestSuite) TestGet(c *C) {
 	})
 	u, err = url.Parse("/foo/bar")
 	c.Assert(err, IsNil)
-	resp, err = method.Get(u)
+	resp, err = method.Get(context.Background(), u)
 	c.Assert(err, NotNil)
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Equals, "some error")
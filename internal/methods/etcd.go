@@ -15,7 +15,6 @@ package methods
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"errors"
 	"io/ioutil"
 	"net"
@@ -31,10 +30,27 @@ import (
 	"github.com/spf13/viper"
 )
 
+var (
+	// EtcdWatchBackoffMin and EtcdWatchBackoffMax bound the time we wait
+	// before re-establishing a watch which has failed.
+	EtcdWatchBackoffMin = 1 * time.Second
+	EtcdWatchBackoffMax = 60 * time.Second
+)
+
 type EtcdMethod struct {
 	Endpoints             []string       `mapstructure:"endpoints" json:"endpoints"`
 	CfgInsecureSkipVerify string         `mapstructure:"insecure-skip-verify" json:"-"`
 	InsecureSkipVerify    bool           `json:"insecure-skip-verify"`
+	CfgCAFile             string         `mapstructure:"ca-file" json:"-"`
+	CAFile                string         `json:"ca-file,omitempty"`
+	CfgClientCert         string         `mapstructure:"client-cert" json:"-"`
+	ClientCert            string         `json:"client-cert,omitempty"`
+	CfgClientKey          string         `mapstructure:"client-key" json:"-"`
+	ClientKey             string         `json:"client-key,omitempty"`
+	CfgServerName         string         `mapstructure:"server-name" json:"-"`
+	ServerName            string         `json:"server-name,omitempty"`
+	CfgWatch              string         `mapstructure:"watch" json:"-"`
+	Watch                 bool           `json:"watch"`
 	KeysAPI               client.KeysAPI `json:"-"`
 	Manager               *string        `json:"-"`
 }
@@ -42,9 +58,14 @@ type EtcdMethod struct {
 type EtcdMethodOpts struct {
 	Endpoints []string
 	Scheme    string
+	Watch     bool
 }
 
-func getTransport(insecureSkipVerify bool) *http.Transport {
+func getTransport(tlsOpts TLSOpts) (*http.Transport, error) {
+	tlsConfig, err := tlsOpts.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
 	return &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		Dial: (&net.Dialer{
@@ -52,19 +73,25 @@ func getTransport(insecureSkipVerify bool) *http.Transport {
 			KeepAlive: 30 * time.Second,
 		}).Dial,
 		TLSHandshakeTimeout: 10 * time.Second,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: insecureSkipVerify,
-		},
-	}
+		TLSClientConfig:     tlsConfig,
+	}, nil
+}
+
+func (e *EtcdMethod) setTLSOpts(o TLSOpts) {
+	e.CAFile = o.CAFile
+	e.ClientCert = o.ClientCert
+	e.ClientKey = o.ClientKey
+	e.ServerName = o.ServerName
+	e.InsecureSkipVerify = o.InsecureSkipVerify
 }
 
-func NewEtcdMethod(manager *string, entry *string) (Method, error) {
+func NewEtcdMethod(v *viper.Viper, manager *string, entry *string) (Method, error) {
 	var (
 		err    error
 		result EtcdMethod
 	)
-	if (manager != nil) && (entry != nil) {
-		err = viper.UnmarshalKey(*entry, &result)
+	if (v != nil) && (manager != nil) && (entry != nil) {
+		err = v.UnmarshalKey(*entry, &result)
 		if err != nil {
 			return result, err
 		}
@@ -74,59 +101,75 @@ func NewEtcdMethod(manager *string, entry *string) (Method, error) {
 		}
 		result.Endpoints = strings.Split(endpointsString, ",")
 
-		result.InsecureSkipVerify = strings.ToLower(environment.GetVar(result.CfgInsecureSkipVerify)) == "true"
+		tlsOpts := TLSOpts{
+			CAFile:             environment.GetVar(result.CfgCAFile),
+			ClientCert:         environment.GetVar(result.CfgClientCert),
+			ClientKey:          environment.GetVar(result.CfgClientKey),
+			ServerName:         environment.GetVar(result.CfgServerName),
+			InsecureSkipVerify: strings.ToLower(environment.GetVar(result.CfgInsecureSkipVerify)) == "true",
+		}
+		result.setTLSOpts(tlsOpts)
+		result.Watch = strings.ToLower(environment.GetVar(result.CfgWatch)) == "true"
+		transport, err := getTransport(tlsOpts)
+		if err != nil {
+			return result, err
+		}
 		cfg := client.Config{
 			Endpoints: result.Endpoints,
-			Transport: getTransport(result.InsecureSkipVerify),
+			Transport: transport,
 			// set timeout per request to fail fast when the target endpoint is unavailable
 			HeaderTimeoutPerRequest: time.Second,
 		}
 		c, err := client.New(cfg)
 		if err != nil {
-			log.Fatal(err)
+			log.WithField("method", "etcd").Fatal(err)
 			return EtcdMethod{}, errors.New("could not start etcd client")
 		}
 		result.Manager = manager
-		log.Debug("NewsKeyAPI configured for etcd")
+		log.WithField("method", "etcd").Debug("NewsKeyAPI configured for etcd")
 		result.KeysAPI = client.NewKeysAPI(c)
 	}
 
 	return result, err
 }
 
-func NewEtcdMethodWithEndpoints(endpoints []string, insecureSkipVerify bool) (Method, error) {
+func NewEtcdMethodWithEndpoints(endpoints []string, tlsOpts TLSOpts) (Method, error) {
 	var (
 		err    error
 		result EtcdMethod
 	)
+	transport, err := getTransport(tlsOpts)
+	if err != nil {
+		return result, err
+	}
 	cfg := client.Config{
 		Endpoints: endpoints,
-		Transport: getTransport(insecureSkipVerify),
+		Transport: transport,
 		// set timeout per request to fail fast when the target endpoint is unavailable
 		HeaderTimeoutPerRequest: time.Second,
 	}
 	c, err := client.New(cfg)
 	if err != nil {
-		log.Fatal(err)
+		log.WithField("method", "etcd").Fatal(err)
 		return EtcdMethod{}, errors.New("could not start etcd client")
 	}
-	log.Debugf("NewsKeyAPI configured with Endpoints %v", endpoints)
+	log.WithField("method", "etcd").Debugf("NewsKeyAPI configured with Endpoints %v", endpoints)
 	result.KeysAPI = client.NewKeysAPI(c)
 	result.Endpoints = endpoints
-	result.InsecureSkipVerify = insecureSkipVerify
+	result.setTLSOpts(tlsOpts)
 	return result, err
 }
 
-func (e EtcdMethod) Get(u *url.URL) (*Response, error) {
+func (e EtcdMethod) Get(ctx context.Context, u *url.URL) (*Response, error) {
 	var (
 		err      error
 		response Response
 	)
 	// get path key's value
-	log.Debugf("Getting file at %v", u)
-	resp, err := GetEtcdKey(context.Background(), e, u.Path, nil)
+	log.WithField("method", "etcd").Debugf("Getting file at %v", u)
+	resp, err := GetEtcdKey(ctx, e, u.Path, nil)
 	if err != nil {
-		log.Warnf("Error getting key %s from etcd at %s", u.Path, e.Endpoints)
+		log.WithField("method", "etcd").Warnf("Error getting key %s from etcd at %s", u.Path, e.Endpoints)
 		return &Response{statusCode: 404}, err
 	}
 	response.statusCode = 200
@@ -139,6 +182,61 @@ func GetEtcdKey(ctx context.Context, e EtcdMethod, key string, opts *client.GetO
 	return e.KeysAPI.Get(ctx, key, opts)
 }
 
+// WatchEnabled returns whether or not the watch option has been turned on
+// for this etcd method.
+func (e EtcdMethod) WatchEnabled() bool {
+	return e.Watch
+}
+
+// WatchKey holds a recursive watch on key and calls notify each time
+// something underneath it changes. If the watch fails, it is re-established
+// with an exponential backoff. WatchKey only returns once ctx is done.
+func (e EtcdMethod) WatchKey(ctx context.Context, key string, notify func()) {
+	var (
+		afterIndex uint64
+		backoff    = EtcdWatchBackoffMin
+	)
+
+	log.WithField("method", "etcd").Infof("EtcdMethod::WatchKey(): watching %v at %v", key, e.Endpoints)
+	watcher := e.KeysAPI.Watcher(key, &client.WatcherOptions{Recursive: true})
+	for {
+		resp, err := watcher.Next(ctx)
+		if ctx.Err() != nil {
+			log.WithField("method", "etcd").Debugf("EtcdMethod::WatchKey(): stopping watch on %v", key)
+			return
+		}
+		if err != nil {
+			if cerr, ok := err.(client.Error); ok && cerr.Code == client.ErrorCodeEventIndexCleared {
+				// etcd no longer has the history we were waiting on, so we
+				// may have missed changes. Start fresh and let butler check.
+				log.WithField("method", "etcd").Warnf("EtcdMethod::WatchKey(): watch index for %v has been cleared. resetting.", key)
+				afterIndex = 0
+				notify()
+			} else {
+				log.WithField("method", "etcd").Warnf("EtcdMethod::WatchKey(): lost watch on %v. retrying in %v. err=%v", key, backoff, err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff *= 2
+				if backoff > EtcdWatchBackoffMax {
+					backoff = EtcdWatchBackoffMax
+				}
+			}
+			watcher = e.KeysAPI.Watcher(key, &client.WatcherOptions{Recursive: true, AfterIndex: afterIndex})
+			continue
+		}
+
+		backoff = EtcdWatchBackoffMin
+		if resp.Node != nil {
+			afterIndex = resp.Node.ModifiedIndex
+		}
+		log.WithField("method", "etcd").Debugf("EtcdMethod::WatchKey(): caught %v on %v", resp.Action, key)
+		notify()
+	}
+}
+
 func (o EtcdMethodOpts) GetScheme() string {
 	return o.Scheme
 }
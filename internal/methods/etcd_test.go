@@ -15,9 +15,11 @@ package methods
 import (
 	"bytes"
 	"context"
+	"errors"
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	//log "github.com/sirupsen/logrus"
 
@@ -32,6 +34,7 @@ import (
 var _ = Suite(&EtcdTestSuite{})
 
 type EtcdTestSuite struct {
+	v *viper.Viper
 }
 
 var TestViperConfigEtcd = []byte(`[test-manager]
@@ -104,7 +107,8 @@ var TestViperConfigGetEtcd = []byte(`[test-manager]
 `)
 
 func (s *EtcdTestSuite) SetUpSuite(c *C) {
-	viper.SetConfigType("toml")
+	s.v = viper.New()
+	s.v.SetConfigType("toml")
 }
 
 func (s *EtcdTestSuite) TearDownSuite(c *C) {
@@ -112,12 +116,12 @@ func (s *EtcdTestSuite) TearDownSuite(c *C) {
 
 func (s *EtcdTestSuite) TestNewEtcdMethod(c *C) {
 	// Load config
-	err := viper.ReadConfig(bytes.NewBuffer(TestViperConfigEtcd))
+	err := s.v.ReadConfig(bytes.NewBuffer(TestViperConfigEtcd))
 	c.Assert(err, IsNil)
 
 	manager := "test-manager"
 	entry := "test-manager.repo.etcd"
-	method, err := NewEtcdMethod(&manager, &entry)
+	method, err := NewEtcdMethod(s.v, &manager, &entry)
 	m := method.(EtcdMethod)
 	c.Assert(m.Endpoints, DeepEquals, []string{"http://127.0.0.1:2379"})
 	c.Assert(m.InsecureSkipVerify, DeepEquals, false)
@@ -126,12 +130,12 @@ func (s *EtcdTestSuite) TestNewEtcdMethod(c *C) {
 
 func (s *EtcdTestSuite) TestNewEtcdMethodTLSInsecureSkipVerify(c *C) {
 	// Load config
-	err := viper.ReadConfig(bytes.NewBuffer(TestViperConfigEtcdTLS))
+	err := s.v.ReadConfig(bytes.NewBuffer(TestViperConfigEtcdTLS))
 	c.Assert(err, IsNil)
 
 	manager := "test-manager"
 	entry := "test-manager.repo.etcd"
-	method, err := NewEtcdMethod(&manager, &entry)
+	method, err := NewEtcdMethod(s.v, &manager, &entry)
 	m := method.(EtcdMethod)
 	c.Assert(m.Endpoints, DeepEquals, []string{"https://127.0.0.1:2379"})
 	c.Assert(m.InsecureSkipVerify, DeepEquals, true)
@@ -139,7 +143,7 @@ func (s *EtcdTestSuite) TestNewEtcdMethodTLSInsecureSkipVerify(c *C) {
 }
 
 func (s *EtcdTestSuite) TestNewEtcdMethodEnv(c *C) {
-	err := viper.ReadConfig(bytes.NewBuffer(TestViperConfigEnvEtcd))
+	err := s.v.ReadConfig(bytes.NewBuffer(TestViperConfigEnvEtcd))
 	c.Assert(err, IsNil)
 
 	endpoints := []string{"http://127.0.0.1:2379", "http://127.0.0.2:2379"}
@@ -147,7 +151,7 @@ func (s *EtcdTestSuite) TestNewEtcdMethodEnv(c *C) {
 	os.Setenv("ENDPOINTS", strings.Join(endpoints, ","))
 	manager := "test-manager"
 	entry := "test-manager.repo.etcd"
-	method, err := NewEtcdMethod(&manager, &entry)
+	method, err := NewEtcdMethod(s.v, &manager, &entry)
 	m := method.(EtcdMethod)
 	c.Assert(err, IsNil)
 	c.Assert(m.Endpoints, DeepEquals, endpoints)
@@ -157,7 +161,7 @@ func (s *EtcdTestSuite) TestNewEtcdMethodEnv(c *C) {
 
 func (s *EtcdTestSuite) TestNewEtcdMethodWithUrl(c *C) {
 	endpoints := []string{"http://127.0.0.2:2379", "http://127.0.0.1:2379"}
-	method, err := NewEtcdMethodWithEndpoints(endpoints, false)
+	method, err := NewEtcdMethodWithEndpoints(endpoints, TLSOpts{})
 	c.Assert(err, IsNil)
 	m := method.(EtcdMethod)
 	c.Assert(m.Endpoints, DeepEquals, endpoints)
@@ -166,7 +170,7 @@ func (s *EtcdTestSuite) TestNewEtcdMethodWithUrl(c *C) {
 
 func (s *EtcdTestSuite) TestNewEtcdMethodWithUrlTLS(c *C) {
 	endpoints := []string{"https://127.0.0.2:2379", "https://127.0.0.1:2379"}
-	method, err := NewEtcdMethodWithEndpoints(endpoints, true)
+	method, err := NewEtcdMethodWithEndpoints(endpoints, TLSOpts{InsecureSkipVerify: true})
 	c.Assert(err, IsNil)
 	m := method.(EtcdMethod)
 	c.Assert(m.Endpoints, DeepEquals, endpoints)
@@ -174,7 +178,7 @@ func (s *EtcdTestSuite) TestNewEtcdMethodWithUrlTLS(c *C) {
 }
 
 func (s *EtcdTestSuite) TestGetPass(c *C) {
-	err := viper.ReadConfig(bytes.NewBuffer(TestViperConfigGetEtcd))
+	err := s.v.ReadConfig(bytes.NewBuffer(TestViperConfigGetEtcd))
 	c.Assert(err, IsNil)
 	manager := "test-manager"
 	entry := "test-manager.repo.etcd"
@@ -191,13 +195,13 @@ func (s *EtcdTestSuite) TestGetPass(c *C) {
 	})
 	defer patch.Unpatch()
 
-	method1, err1 := NewEtcdMethodWithEndpoints(endpoints, false)
-	method2, err2 := NewEtcdMethod(&manager, &entry)
+	method1, err1 := NewEtcdMethodWithEndpoints(endpoints, TLSOpts{})
+	method2, err2 := NewEtcdMethod(s.v, &manager, &entry)
 	c.Assert(err1, IsNil)
 	c.Assert(err2, IsNil)
 
-	resp1, err1 := method1.Get(u)
-	resp2, err2 := method2.Get(u)
+	resp1, err1 := method1.Get(context.Background(), u)
+	resp2, err2 := method2.Get(context.Background(), u)
 	c.Assert(err1, IsNil)
 	c.Assert(err2, IsNil)
 	buf1 := new(bytes.Buffer)
@@ -212,20 +216,20 @@ func (s *EtcdTestSuite) TestGetPass(c *C) {
 }
 
 func (s *EtcdTestSuite) TestGetFail(c *C) {
-	err := viper.ReadConfig(bytes.NewBuffer(TestViperConfigGetEtcd))
+	err := s.v.ReadConfig(bytes.NewBuffer(TestViperConfigGetEtcd))
 	c.Assert(err, IsNil)
 	manager := "test-manager"
 	entry := "test-manager.repo.etcd"
 	u, err := url.Parse("none")
 	endpoints := []string{"http://127.0.0.3:2379"}
 	c.Assert(err, IsNil)
-	method1, err1 := NewEtcdMethodWithEndpoints(endpoints, false)
-	method2, err2 := NewEtcdMethod(&manager, &entry)
+	method1, err1 := NewEtcdMethodWithEndpoints(endpoints, TLSOpts{})
+	method2, err2 := NewEtcdMethod(s.v, &manager, &entry)
 	c.Assert(err1, IsNil)
 	c.Assert(err2, IsNil)
 
-	resp1, err1 := method1.Get(u)
-	resp2, err2 := method2.Get(u)
+	resp1, err1 := method1.Get(context.Background(), u)
+	resp2, err2 := method2.Get(context.Background(), u)
 	c.Assert(err1, NotNil)
 	c.Assert(err2, NotNil)
 
@@ -235,3 +239,90 @@ func (s *EtcdTestSuite) TestGetFail(c *C) {
 	c.Assert(resp2.GetResponseStatusCode(), Equals, 404)
 	c.Assert(resp2.GetResponseBody(), IsNil)
 }
+
+// fakeKeysAPI hands out watchers which replay the responses and errors
+// sent down its channel.
+type fakeKeysAPI struct {
+	client.KeysAPI
+	events   chan fakeWatchEvent
+	watchers chan *client.WatcherOptions
+}
+
+type fakeWatchEvent struct {
+	resp *client.Response
+	err  error
+}
+
+type fakeWatcher struct {
+	events chan fakeWatchEvent
+}
+
+func (k *fakeKeysAPI) Watcher(key string, opts *client.WatcherOptions) client.Watcher {
+	k.watchers <- opts
+	return &fakeWatcher{events: k.events}
+}
+
+func (w *fakeWatcher) Next(ctx context.Context) (*client.Response, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case e := <-w.events:
+		return e.resp, e.err
+	}
+}
+
+func (s *EtcdTestSuite) TestWatchEnabled(c *C) {
+	err := s.v.ReadConfig(bytes.NewBuffer([]byte(`[test-manager.repo.etcd]
+  endpoints = "http://127.0.0.1:2379"
+  watch = "true"
+`)))
+	c.Assert(err, IsNil)
+	manager := "test-manager"
+	entry := "test-manager.repo.etcd"
+	method, err := NewEtcdMethod(s.v, &manager, &entry)
+	c.Assert(err, IsNil)
+	w, ok := method.(Watcher)
+	c.Assert(ok, Equals, true)
+	c.Assert(w.WatchEnabled(), Equals, true)
+
+	method, err = NewEtcdMethodWithEndpoints([]string{"http://127.0.0.1:2379"}, TLSOpts{})
+	c.Assert(err, IsNil)
+	c.Assert(method.(Watcher).WatchEnabled(), Equals, false)
+}
+
+func (s *EtcdTestSuite) TestWatchKey(c *C) {
+	EtcdWatchBackoffMin = time.Millisecond
+	defer func() { EtcdWatchBackoffMin = time.Second }()
+
+	keys := &fakeKeysAPI{events: make(chan fakeWatchEvent), watchers: make(chan *client.WatcherOptions, 10)}
+	e := EtcdMethod{KeysAPI: keys, Watch: true}
+	notified := make(chan bool, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		e.WatchKey(ctx, "/butler", func() { notified <- true })
+		done <- true
+	}()
+
+	opts := <-keys.watchers
+	c.Assert(opts.Recursive, Equals, true)
+	c.Assert(opts.AfterIndex, Equals, uint64(0))
+
+	keys.events <- fakeWatchEvent{resp: &client.Response{Action: "set", Node: &client.Node{ModifiedIndex: 42}}}
+	c.Assert(<-notified, Equals, true)
+
+	// A broken watch gets re-established after the last index we saw
+	keys.events <- fakeWatchEvent{err: errors.New("connection reset")}
+	opts = <-keys.watchers
+	c.Assert(opts.AfterIndex, Equals, uint64(42))
+
+	// ... unless etcd has cleared that index, in which case we start over
+	// and let butler go and check.
+	keys.events <- fakeWatchEvent{err: client.Error{Code: client.ErrorCodeEventIndexCleared}}
+	c.Assert(<-notified, Equals, true)
+	opts = <-keys.watchers
+	c.Assert(opts.AfterIndex, Equals, uint64(0))
+
+	cancel()
+	<-done
+}
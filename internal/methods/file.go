@@ -14,6 +14,7 @@ package methods
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/url"
@@ -33,7 +34,7 @@ type FileMethodOpts struct {
 	Scheme string
 }
 
-func NewFileMethod(manager *string, entry *string) (Method, error) {
+func NewFileMethod(v *viper.Viper, manager *string, entry *string) (Method, error) {
 	var (
 		err    error
 		result FileMethod
@@ -41,8 +42,8 @@ func NewFileMethod(manager *string, entry *string) (Method, error) {
 	)
 
 	u = &url.URL{}
-	if (manager != nil) && (entry != nil) {
-		err = viper.UnmarshalKey(*entry, &result)
+	if (v != nil) && (manager != nil) && (entry != nil) {
+		err = v.UnmarshalKey(*entry, &result)
 		if err != nil {
 			return result, err
 		}
@@ -67,12 +68,15 @@ func NewFileMethodWithURL(u *url.URL) (Method, error) {
 	return result, err
 }
 
-func (f FileMethod) Get(u *url.URL) (*Response, error) {
+func (f FileMethod) Get(ctx context.Context, u *url.URL) (*Response, error) {
 	var (
 		err      error
 		fileData []byte
 		response Response
 	)
+	if err = ctx.Err(); err != nil {
+		return &Response{}, err
+	}
 	fileData, err = ioutil.ReadFile(fmt.Sprintf("%s%s", u.Host, u.Path))
 
 	if err != nil {
@@ -14,6 +14,7 @@ package methods
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"net/url"
 	"os"
@@ -30,6 +31,7 @@ func Test(t *testing.T) { TestingT(t) }
 var _ = Suite(&FileTestSuite{})
 
 type FileTestSuite struct {
+	v *viper.Viper
 }
 
 var TestViperConfig = []byte(`[test-manager]
@@ -84,7 +86,8 @@ var TestViperConfigGet = []byte(`[test-manager]
 `)
 
 func (s *FileTestSuite) SetUpSuite(c *C) {
-	viper.SetConfigType("toml")
+	s.v = viper.New()
+	s.v.SetConfigType("toml")
 }
 
 func (s *FileTestSuite) TearDownSuite(c *C) {
@@ -92,26 +95,26 @@ func (s *FileTestSuite) TearDownSuite(c *C) {
 
 func (s *FileTestSuite) TestNewFileMethod(c *C) {
 	// Load config
-	err := viper.ReadConfig(bytes.NewBuffer(TestViperConfig))
+	err := s.v.ReadConfig(bytes.NewBuffer(TestViperConfig))
 	c.Assert(err, IsNil)
 
 	manager := "test-manager"
 	entry := "test-manager.repo.file"
-	method, err := NewFileMethod(&manager, &entry)
+	method, err := NewFileMethod(s.v, &manager, &entry)
 	m := method.(FileMethod)
 	c.Assert(m.Path, Equals, "/var/www/html/butler/configs/prometheus")
 	c.Assert(err, IsNil)
 }
 
 func (s *FileTestSuite) TestNewFileMethodEnv(c *C) {
-	err := viper.ReadConfig(bytes.NewBuffer(TestViperConfigEnv))
+	err := s.v.ReadConfig(bytes.NewBuffer(TestViperConfigEnv))
 	c.Assert(err, IsNil)
 
 	path := "/var/www/html/butler/configs/hiya"
 	os.Setenv("BUTLER_PATH", path)
 	manager := "test-manager"
 	entry := "test-manager.repo.file"
-	method, err := NewFileMethod(&manager, &entry)
+	method, err := NewFileMethod(s.v, &manager, &entry)
 	m := method.(FileMethod)
 	c.Assert(err, IsNil)
 	c.Assert(m.Path, Equals, path)
@@ -134,7 +137,7 @@ func (s *FileTestSuite) TestGetPass(c *C) {
 	u, err := url.Parse("none")
 	c.Assert(err, IsNil)
 	method1, err1 := NewFileMethodWithURL(u)
-	method2, err2 := NewFileMethod(&manager, &entry)
+	method2, err2 := NewFileMethod(s.v, &manager, &entry)
 	c.Assert(err1, IsNil)
 	c.Assert(err2, IsNil)
 
@@ -143,8 +146,8 @@ func (s *FileTestSuite) TestGetPass(c *C) {
 	})
 	defer patch.Unpatch()
 
-	resp1, err1 := method1.Get(u)
-	resp2, err2 := method2.Get(u)
+	resp1, err1 := method1.Get(context.Background(), u)
+	resp2, err2 := method2.Get(context.Background(), u)
 	c.Assert(err1, IsNil)
 	c.Assert(err2, IsNil)
 	buf1 := new(bytes.Buffer)
@@ -164,12 +167,12 @@ func (s *FileTestSuite) TestGetFail(c *C) {
 	u, err := url.Parse("none")
 	c.Assert(err, IsNil)
 	method1, err1 := NewFileMethodWithURL(u)
-	method2, err2 := NewFileMethod(&manager, &entry)
+	method2, err2 := NewFileMethod(s.v, &manager, &entry)
 	c.Assert(err1, IsNil)
 	c.Assert(err2, IsNil)
 
-	resp1, err1 := method1.Get(u)
-	resp2, err2 := method2.Get(u)
+	resp1, err1 := method1.Get(context.Background(), u)
+	resp2, err2 := method2.Get(context.Background(), u)
 	c.Assert(err1, NotNil)
 	c.Assert(err2, NotNil)
 
@@ -179,3 +182,19 @@ func (s *FileTestSuite) TestGetFail(c *C) {
 	c.Assert(resp2.GetResponseStatusCode(), Equals, 504)
 	c.Assert(resp2.GetResponseBody(), IsNil)
 }
+
+func (s *FileTestSuite) TestGetCanceled(c *C) {
+	f, err := ioutil.TempFile("", "butler-file-test")
+	c.Assert(err, IsNil)
+	defer os.Remove(f.Name())
+	f.Close()
+	u, err := url.Parse(f.Name())
+	c.Assert(err, IsNil)
+	method, err := NewFileMethodWithURL(u)
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = method.Get(ctx, u)
+	c.Assert(err, Equals, context.Canceled)
+}
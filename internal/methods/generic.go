@@ -13,8 +13,11 @@ governing permissions and limitations under the License.
 package methods
 
 import (
+	"context"
 	"errors"
 	"net/url"
+
+	"github.com/spf13/viper"
 )
 
 type GenericMethod struct {
@@ -24,11 +27,11 @@ type GenericMethodOpts struct {
 	Scheme string
 }
 
-func NewGenericMethod(manager *string, entry *string) (Method, error) {
+func NewGenericMethod(v *viper.Viper, manager *string, entry *string) (Method, error) {
 	return GenericMethod{}, errors.New("Generic method handler is not very useful")
 }
 
-func (m GenericMethod) Get(u *url.URL) (*Response, error) {
+func (m GenericMethod) Get(ctx context.Context, u *url.URL) (*Response, error) {
 	var (
 		result *Response
 	)
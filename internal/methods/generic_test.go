@@ -13,6 +13,7 @@ governing permissions and limitations under the License.
 package methods
 
 import (
+	"context"
 	"net/url"
 	//"testing"
 	. "gopkg.in/check.v1"
@@ -24,19 +25,19 @@ type GenericTestSuite struct {
 }
 
 func (s *GenericTestSuite) TestNewGenericMethod(c *C) {
-	method, err := NewGenericMethod(nil, nil)
+	method, err := NewGenericMethod(nil, nil, nil)
 	c.Assert(err, NotNil)
 	c.Assert(method, Equals, GenericMethod{})
 }
 
 func (s *GenericTestSuite) TestGet(c *C) {
-	method, err := NewGenericMethod(nil, nil)
+	method, err := NewGenericMethod(nil, nil, nil)
 	c.Assert(err, NotNil)
 	c.Assert(method, Equals, GenericMethod{})
 
 	u, err := url.Parse("hiya")
 	c.Assert(err, IsNil)
-	resp, err2 := method.Get(u)
+	resp, err2 := method.Get(context.Background(), u)
 	c.Assert(err2, NotNil)
 	c.Assert(resp, IsNil)
 }
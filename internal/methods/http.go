@@ -16,19 +16,21 @@ import (
 	"context"
 	"crypto/md5"
 	"crypto/rand"
-	"crypto/tls"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/adobe/butler/internal/auth"
 	"github.com/adobe/butler/internal/environment"
 	"github.com/adobe/butler/internal/metrics"
+	"github.com/adobe/butler/internal/tracing"
 
 	"github.com/hashicorp/go-retryablehttp"
 	log "github.com/sirupsen/logrus"
@@ -43,18 +45,37 @@ const (
 )
 
 type HTTPMethod struct {
-	Client                *retryablehttp.Client `json:"-"`
-	Manager               *string               `json:"-"`
-	Host                  string                `mapstruecture:"host" json:"host,omitempty"`
-	Retries               string                `mapstructure:"retries" json:"retries"`
-	RetryWaitMax          string                `mapstructure:"retry-wait-max" json:"retry-wait-max"`
-	RetryWaitMin          string                `mapstructure:"retry-wait-min" json:"retry-wait-min"`
-	Timeout               string                `mapstructure:"timeout" json:"timeout"`
-	AuthType              string                `mapstructure:"auth-type" json:"auth-type,omitempty"`
-	AuthToken             string                `mapstructure:"auth-token" json:"-"`
-	AuthUser              string                `mapstructure:"auth-user" json:"auth-user,omitempty"`
-	CfgInsecureSkipVerify string                `mapstructure:"insecure-skip-verify" json:"-"`
-	InsecureSkipVerify    bool                  `json:"insecure-skip-verify"`
+	Client                     *retryablehttp.Client `json:"-"`
+	Manager                    *string               `json:"-"`
+	Host                       string                `mapstructure:"host" json:"host,omitempty"`
+	Socket                     string                `mapstructure:"socket" json:"socket,omitempty"`
+	Retries                    string                `mapstructure:"retries" json:"retries"`
+	RetryWaitMax               string                `mapstructure:"retry-wait-max" json:"retry-wait-max"`
+	RetryWaitMin               string                `mapstructure:"retry-wait-min" json:"retry-wait-min"`
+	Timeout                    string                `mapstructure:"timeout" json:"timeout"`
+	AuthType                   string                `mapstructure:"auth-type" json:"auth-type,omitempty"`
+	AuthToken                  string                `mapstructure:"auth-token" json:"-"`
+	AuthUser                   string                `mapstructure:"auth-user" json:"auth-user,omitempty"`
+	AuthTokenFile              string                `mapstructure:"auth-token-file" json:"auth-token-file,omitempty"`
+	TokenURL                   string                `mapstructure:"token-url" json:"token-url,omitempty"`
+	ClientID                   string                `mapstructure:"client-id" json:"client-id,omitempty"`
+	ClientSecret               string                `mapstructure:"client-secret" json:"-"`
+	Scopes                     []string              `mapstructure:"scopes" json:"scopes,omitempty"`
+	CfgTokenCAFile             string                `mapstructure:"token-ca-file" json:"-"`
+	TokenCAFile                string                `json:"token-ca-file,omitempty"`
+	CfgTokenInsecureSkipVerify string                `mapstructure:"token-insecure-skip-verify" json:"-"`
+	TokenInsecureSkipVerify    bool                  `json:"token-insecure-skip-verify,omitempty"`
+	Authorizer                 auth.Authorizer       `json:"-"`
+	CfgInsecureSkipVerify      string                `mapstructure:"insecure-skip-verify" json:"-"`
+	InsecureSkipVerify         bool                  `json:"insecure-skip-verify"`
+	CfgCAFile                  string                `mapstructure:"ca-file" json:"-"`
+	CAFile                     string                `json:"ca-file,omitempty"`
+	CfgClientCert              string                `mapstructure:"client-cert" json:"-"`
+	ClientCert                 string                `json:"client-cert,omitempty"`
+	CfgClientKey               string                `mapstructure:"client-key" json:"-"`
+	ClientKey                  string                `json:"client-key,omitempty"`
+	CfgServerName              string                `mapstructure:"server-name" json:"-"`
+	ServerName                 string                `json:"server-name,omitempty"`
 }
 
 type HTTPMethodOpts struct {
@@ -68,14 +89,14 @@ type HTTPMethodOpts struct {
 	Timeout       int
 }
 
-func NewHTTPMethod(manager *string, entry *string) (Method, error) {
+func NewHTTPMethod(v *viper.Viper, manager *string, entry *string) (Method, error) {
 	var (
 		err    error
 		result HTTPMethod
 	)
 
-	if (manager != nil) && (entry != nil) {
-		err = viper.UnmarshalKey(*entry, &result)
+	if (v != nil) && (manager != nil) && (entry != nil) {
+		err = v.UnmarshalKey(*entry, &result)
 		if err != nil {
 			return result, err
 		}
@@ -83,37 +104,58 @@ func NewHTTPMethod(manager *string, entry *string) (Method, error) {
 
 	newTimeout, _ := strconv.Atoi(environment.GetVar(result.Timeout))
 	if newTimeout == 0 {
-		log.Warnf("NewHttpMethod(): could not convert %v to integer for timeout, defaulting to %v. This is probably undesired.", result.Timeout, defaultTimeout)
+		log.WithField("method", "http").Warnf("NewHttpMethod(): could not convert %v to integer for timeout, defaulting to %v. This is probably undesired.", result.Timeout, defaultTimeout)
 		newTimeout = defaultTimeout
 	}
 
 	newRetries, _ := strconv.Atoi(environment.GetVar(result.Retries))
 	if newRetries == 0 {
-		log.Warnf("NewHttpMethod(): could not convert %v to integer for retries, defaulting to %v. This is probably undesired.", result.Retries, defaultRetries)
+		log.WithField("method", "http").Warnf("NewHttpMethod(): could not convert %v to integer for retries, defaulting to %v. This is probably undesired.", result.Retries, defaultRetries)
 		newRetries = defaultRetries
 	}
 
 	newRetryWaitMax, _ := strconv.Atoi(environment.GetVar(result.RetryWaitMax))
 	if newRetryWaitMax == 0 {
-		log.Warnf("NewHttpMethod(): could not convert %v to integer for retry-wait-max, defaulting to %v. This is probably undesired.", result.RetryWaitMax, defaultRetryWaitMax)
+		log.WithField("method", "http").Warnf("NewHttpMethod(): could not convert %v to integer for retry-wait-max, defaulting to %v. This is probably undesired.", result.RetryWaitMax, defaultRetryWaitMax)
 		newRetryWaitMax = defaultRetryWaitMax
 	}
 
 	newRetryWaitMin, _ := strconv.Atoi(environment.GetVar(result.RetryWaitMin))
 	if newRetryWaitMin == 0 {
-		log.Warnf("NewHttpMethod(): could not convert %v to integer for retry-wait-min, defaulting to %v. This is probably undesired.", result.RetryWaitMin, defaultRetryWaitMin)
+		log.WithField("method", "http").Warnf("NewHttpMethod(): could not convert %v to integer for retry-wait-min, defaulting to %v. This is probably undesired.", result.RetryWaitMin, defaultRetryWaitMin)
 		newRetryWaitMin = defaultRetryWaitMin
 	}
 
-	result.InsecureSkipVerify = strings.ToLower(environment.GetVar(result.CfgInsecureSkipVerify)) == "true"
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: result.InsecureSkipVerify},
-	}
-
+	result.Socket = environment.GetVar(result.Socket)
 	result.Client = retryablehttp.NewClient()
 	result.Client.Logger = nil
 	result.Client.HTTPClient.Timeout = time.Duration(newTimeout) * time.Second
-	result.Client.HTTPClient.Transport = transport
+	err = result.SetTLSOpts(TLSOpts{
+		CAFile:             environment.GetVar(result.CfgCAFile),
+		ClientCert:         environment.GetVar(result.CfgClientCert),
+		ClientKey:          environment.GetVar(result.CfgClientKey),
+		ServerName:         environment.GetVar(result.CfgServerName),
+		InsecureSkipVerify: strings.ToLower(environment.GetVar(result.CfgInsecureSkipVerify)) == "true",
+	})
+	if err != nil {
+		return result, err
+	}
+	switch strings.ToLower(environment.GetVar(result.AuthType)) {
+	case "bearer":
+		result.Authorizer, err = auth.NewBearer(environment.GetVar(result.AuthToken), environment.GetVar(result.AuthTokenFile))
+	case "oauth2-client-credentials":
+		var client *http.Client
+		result.TokenCAFile = environment.GetVar(result.CfgTokenCAFile)
+		result.TokenInsecureSkipVerify = strings.ToLower(environment.GetVar(result.CfgTokenInsecureSkipVerify)) == "true"
+		client, err = NewTokenClient(result.Client.HTTPClient.Timeout, result.TokenCAFile, result.TokenInsecureSkipVerify)
+		if err == nil {
+			result.Authorizer, err = auth.NewClientCredentials(environment.GetVar(result.TokenURL), environment.GetVar(result.ClientID),
+				environment.GetVar(result.ClientSecret), result.Scopes, client)
+		}
+	}
+	if err != nil {
+		return result, err
+	}
 	result.Client.RetryMax = newRetries
 	result.Client.RetryWaitMax = time.Duration(newRetryWaitMax) * time.Second
 	result.Client.RetryWaitMin = time.Duration(newRetryWaitMin) * time.Second
@@ -122,7 +164,7 @@ func NewHTTPMethod(manager *string, entry *string) (Method, error) {
 	return result, err
 }
 
-func (h HTTPMethod) Get(u *url.URL) (*Response, error) {
+func (h HTTPMethod) Get(ctx context.Context, u *url.URL) (*Response, error) {
 	var (
 		err       error
 		r         *http.Response
@@ -138,15 +180,22 @@ func (h HTTPMethod) Get(u *url.URL) (*Response, error) {
 	if (h.Host != "") && (h.Host != u.Host) {
 		u.Host = h.Host
 	}
-	req, err := retryablehttp.NewRequest("GET", u.String(), nil)
+	req, err := retryablehttp.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
 		return &Response{}, err
 	}
+	tracing.Inject(ctx, req.Header)
 
-	if h.AuthUser != "" && h.AuthToken != "" {
+	if h.Authorizer != nil {
+		authorization, err := h.Authorizer.Authorization(ctx)
+		if err != nil {
+			return &Response{}, err
+		}
+		req.Header.Set("Authorization", authorization)
+	} else if h.AuthUser != "" && h.AuthToken != "" {
 		authType = strings.ToLower(environment.GetVar(h.AuthType))
 		if authType == "" {
-			log.Debugf("HttpMethod::Get(): found authentication tokens but auth-type is empty. Setting auth-type to basic.")
+			log.WithField("method", "http").Debugf("HttpMethod::Get(): found authentication tokens but auth-type is empty. Setting auth-type to basic.")
 			authType = "basic"
 		}
 		authUser = environment.GetVar(h.AuthUser)
@@ -180,11 +229,12 @@ func (h HTTPMethod) Get(u *url.URL) (*Response, error) {
 	}
 
 	// h.Client.HTTPClient.Transport is a http.RoundTripper? Have to fudge some items.
-	// This check has to happen when you specify -tls.insecure-skip-verify on command line
-	if (h.InsecureSkipVerify == true) && (h.Client.HTTPClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify == false) {
-		h.Client.HTTPClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: h.InsecureSkipVerify},
-		}
+	// This check has to happen when you specify -tls.insecure-skip-verify on command line.
+	// The rest of the TLS options are kept.
+	if t, ok := h.Client.HTTPClient.Transport.(*http.Transport); ok && h.InsecureSkipVerify && !t.TLSClientConfig.InsecureSkipVerify {
+		t = t.Clone()
+		t.TLSClientConfig.InsecureSkipVerify = true
+		h.Client.HTTPClient.Transport = t
 	}
 
 	r, err = h.Client.Do(req)
@@ -196,9 +246,55 @@ func (h HTTPMethod) Get(u *url.URL) (*Response, error) {
 	return &res, err
 }
 
+// SetTLSOpts replaces the TLS options of the client.
+func (h *HTTPMethod) SetTLSOpts(o TLSOpts) error {
+	cfg, err := o.TLSConfig()
+	if err != nil {
+		return err
+	}
+	h.CAFile = o.CAFile
+	h.ClientCert = o.ClientCert
+	h.ClientKey = o.ClientKey
+	h.ServerName = o.ServerName
+	h.InsecureSkipVerify = o.InsecureSkipVerify
+	transport := &http.Transport{TLSClientConfig: cfg}
+	if h.Socket != "" {
+		transport.DialContext = UnixSocketDialer(h.Socket)
+	}
+	h.Client.HTTPClient.Transport = transport
+	return nil
+}
+
+// NewTokenClient returns the client for the token-url of the
+// oauth2-client-credentials auth. The token server is usually a third party
+// identity provider, so it shares none of the TLS options of the endpoint the
+// token is for: it is verified against the system roots, or token-ca-file,
+// under its own name, and is never presented the client certificate. Neither
+// does it use the unix socket, since it is reached over the network.
+func NewTokenClient(timeout time.Duration, caFile string, insecureSkipVerify bool) (*http.Client, error) {
+	cfg, err := TLSOpts{CAFile: caFile, InsecureSkipVerify: insecureSkipVerify}.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Timeout: timeout, Transport: &http.Transport{TLSClientConfig: cfg}}, nil
+}
+
+// UnixSocketDialer returns a dialer for http.Transport which connects to the
+// unix socket at path, whatever the host of the request.
+func UnixSocketDialer(path string) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+}
+
 func (h *HTTPMethod) MethodRetryPolicy(ctx context.Context, resp *http.Response, err error) (bool, error) {
 	// This is actually the default RetryPolicy from the go-retryablehttp library. The only
 	// change is the metrics monitor. We want to keep track of all the reload failures.
+	// Do not retry when butler is shutting down.
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
 	if (err != nil) && (h.Manager != nil) {
 		opErr := err.(*url.Error)
 		metrics.SetButlerContactRetryVal(metrics.SUCCESS, *h.Manager, metrics.GetStatsLabel(opErr.URL))
@@ -13,8 +13,22 @@ governing permissions and limitations under the License.
 package methods
 
 import (
-	. "gopkg.in/check.v1"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+
+	"github.com/adobe/butler/internal/tracing"
+
+	"github.com/spf13/viper"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	. "gopkg.in/check.v1"
 )
 
 var _ = Suite(&HTTPTestSuite{})
@@ -65,3 +79,181 @@ func (s *HTTPTestSuite) TestdigestDigestParts(c *C) {
 	c.Assert(res["nonce"], Equals, "5b25940d5b154da5")
 	c.Assert(len(res), Equals, 3)
 }
+
+var TestViperConfigHTTPAuth = `[test-manager]
+  repos = ["repo"]
+  [test-manager.repo]
+    method = "http"
+    repo-path = "/butler/configs"
+    primary-config = ["prometheus.yml"]
+    [test-manager.repo.bearer]
+      retries = "1"
+      timeout = "5"
+      auth-type = "bearer"
+      auth-token-file = "%v"
+    [test-manager.repo.oauth2]
+      retries = "1"
+      timeout = "5"
+      auth-type = "oauth2-client-credentials"
+      token-url = "%v/token"
+      client-id = "butler"
+      client-secret = "env:BUTLER_TEST_CLIENT_SECRET"
+      scopes = ["artifacts:read"]
+`
+
+func (s *HTTPTestSuite) TestHTTPMethodGetTokenAuth(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if _, pass, _ := r.BasicAuth(); pass != "s3cr3t" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"access_token": "oauth2-token", "token_type": "bearer", "expires_in": 3600}`)
+			return
+		}
+		switch r.Header.Get("Authorization") {
+		case "Bearer projected-token", "Bearer oauth2-token":
+			w.Write([]byte("hello"))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	tokenFile := c.MkDir() + "/token"
+	c.Assert(os.WriteFile(tokenFile, []byte("projected-token\n"), 0600), IsNil)
+	os.Setenv("BUTLER_TEST_CLIENT_SECRET", "s3cr3t")
+	defer os.Unsetenv("BUTLER_TEST_CLIENT_SECRET")
+
+	v := viper.New()
+	v.SetConfigType("toml")
+	c.Assert(v.ReadConfig(bytes.NewBufferString(fmt.Sprintf(TestViperConfigHTTPAuth, tokenFile, server.URL))), IsNil)
+	u, err := url.Parse(server.URL + "/prometheus.yml")
+	c.Assert(err, IsNil)
+	manager := "test-manager"
+
+	for _, entry := range []string{"test-manager.repo.bearer", "test-manager.repo.oauth2"} {
+		method, err := NewHTTPMethod(v, &manager, &entry)
+		c.Assert(err, IsNil)
+		resp, err := method.Get(context.Background(), u)
+		c.Assert(err, IsNil)
+		c.Assert(resp.GetResponseStatusCode(), Equals, http.StatusOK, Commentf("entry=%v", entry))
+	}
+
+	// A rotated token is picked up by the next request
+	entry := "test-manager.repo.bearer"
+	method, err := NewHTTPMethod(v, &manager, &entry)
+	c.Assert(err, IsNil)
+	c.Assert(os.WriteFile(tokenFile, []byte("expired-token"), 0600), IsNil)
+	resp, err := method.Get(context.Background(), u)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetResponseStatusCode(), Equals, http.StatusUnauthorized)
+}
+
+func (s *HTTPTestSuite) TestHTTPMethodGetSocket(c *C) {
+	socket := c.MkDir() + "/configs.sock"
+	l, err := net.Listen("unix", socket)
+	c.Assert(err, IsNil)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + r.URL.Path))
+	}))
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+
+	v := viper.New()
+	v.SetConfigType("toml")
+	c.Assert(v.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`[test-manager.repo.http]
+  retries = "1"
+  timeout = "5"
+  socket = "%v"
+`, socket))), IsNil)
+	manager := "test-manager"
+	entry := "test-manager.repo.http"
+	method, err := NewHTTPMethod(v, &manager, &entry)
+	c.Assert(err, IsNil)
+	c.Assert(method.(HTTPMethod).Socket, Equals, socket)
+
+	u, err := url.Parse("http://sidecar/configs/prometheus.yml")
+	c.Assert(err, IsNil)
+	resp, err := method.Get(context.Background(), u)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetResponseStatusCode(), Equals, http.StatusOK)
+	body, err := io.ReadAll(resp.GetResponseBody())
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, "sidecar/configs/prometheus.yml")
+}
+
+func (s *HTTPTestSuite) TestHTTPMethodGetSocketTokenAuth(c *C) {
+	// The token comes from the token server over the network, while the
+	// configs come from the unix socket
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token": "oauth2-token", "token_type": "bearer", "expires_in": 3600}`)
+	}))
+	defer tokens.Close()
+	socket := c.MkDir() + "/configs.sock"
+	l, err := net.Listen("unix", socket)
+	c.Assert(err, IsNil)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" || r.Header.Get("Authorization") != "Bearer oauth2-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+
+	v := viper.New()
+	v.SetConfigType("toml")
+	c.Assert(v.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`[test-manager.repo.http]
+  retries = "1"
+  timeout = "5"
+  socket = "%v"
+  auth-type = "oauth2-client-credentials"
+  token-url = "%v/token"
+  client-id = "butler"
+  client-secret = "s3cr3t"
+`, socket, tokens.URL))), IsNil)
+	manager := "test-manager"
+	entry := "test-manager.repo.http"
+	method, err := NewHTTPMethod(v, &manager, &entry)
+	c.Assert(err, IsNil)
+
+	u, err := url.Parse("http://sidecar/configs/prometheus.yml")
+	c.Assert(err, IsNil)
+	resp, err := method.Get(context.Background(), u)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetResponseStatusCode(), Equals, http.StatusOK)
+}
+
+func (s *HTTPTestSuite) TestHTTPMethodGetTraceContext(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Traceparent")))
+	}))
+	defer server.Close()
+	tracing.SetProvider(tracing.NewProvider(sdktrace.WithSyncer(tracetest.NewInMemoryExporter())))
+	defer tracing.SetProvider(nil)
+
+	v := viper.New()
+	v.SetConfigType("toml")
+	c.Assert(v.ReadConfig(bytes.NewBufferString(`[test-manager.repo.http]
+  retries = "1"
+  timeout = "5"
+`)), IsNil)
+	manager := "test-manager"
+	entry := "test-manager.repo.http"
+	method, err := NewHTTPMethod(v, &manager, &entry)
+	c.Assert(err, IsNil)
+	u, err := url.Parse(server.URL + "/prometheus.yml")
+	c.Assert(err, IsNil)
+
+	// The server carries on with the trace of the download
+	ctx, span := tracing.Start(context.Background(), "Method.Get")
+	defer span.End()
+	resp, err := method.Get(ctx, u)
+	c.Assert(err, IsNil)
+	body, err := io.ReadAll(resp.GetResponseBody())
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Matches, "00-"+span.SpanContext().TraceID().String()+"-"+span.SpanContext().SpanID().String()+"-01")
+}
@@ -14,13 +14,25 @@ package methods
 
 import (
 	//log "github.com/sirupsen/logrus"
+	"context"
 	"io"
 	"net/url"
 	"strings"
+
+	"github.com/spf13/viper"
 )
 
+// Method retrieves files for butler. The context is used to abandon the
+// retrieval when butler is shutting down.
 type Method interface {
-	Get(*url.URL) (*Response, error)
+	Get(context.Context, *url.URL) (*Response, error)
+}
+
+// Watcher is implemented by methods which are able to tell butler about
+// upstream changes as they happen, rather than waiting for the next poll.
+type Watcher interface {
+	WatchEnabled() bool
+	WatchKey(context.Context, string, func())
 }
 
 type MethodOpts interface {
@@ -40,20 +52,22 @@ func (r Response) GetResponseStatusCode() int {
 	return r.statusCode
 }
 
-func New(manager *string, method string, entry *string) (Method, error) {
+// New returns the Method for method, configured from the entry key of v. When
+// v, manager or entry are nil, the method is returned unconfigured.
+func New(v *viper.Viper, manager *string, method string, entry *string) (Method, error) {
 	method = strings.ToLower(method)
 	switch method {
 	case "http", "https":
-		return NewHTTPMethod(manager, entry)
+		return NewHTTPMethod(v, manager, entry)
 	case "s3":
-		return NewS3Method(manager, entry)
+		return NewS3Method(v, manager, entry)
 	case "file":
-		return NewFileMethod(manager, entry)
+		return NewFileMethod(v, manager, entry)
 	case "blob":
-		return NewBlobMethod(manager, entry)
+		return NewBlobMethod(v, manager, entry)
 	case "etcd":
-		return NewEtcdMethod(manager, entry)
+		return NewEtcdMethod(v, manager, entry)
 	default:
-		return NewGenericMethod(manager, entry)
+		return NewGenericMethod(v, manager, entry)
 	}
 }
@@ -16,6 +16,7 @@ import (
 	"io"
 	"strings"
 
+	"github.com/spf13/viper"
 	. "gopkg.in/check.v1"
 )
 
@@ -46,7 +47,7 @@ func (s *MethodsTestSuite) TestResponseGetResponseStatusCode(c *C) {
 func (s *MethodsTestSuite) TestNewMethodHTTP(c *C) {
 	manager := "test-manager"
 	entry := "test-entry"
-	method, err := New(&manager, "http", &entry)
+	method, err := New(viper.New(), &manager, "http", &entry)
 	// HTTP method requires proper configuration, so it may return an error
 	// but should not panic
 	_ = method
@@ -56,7 +57,7 @@ func (s *MethodsTestSuite) TestNewMethodHTTP(c *C) {
 func (s *MethodsTestSuite) TestNewMethodHTTPS(c *C) {
 	manager := "test-manager"
 	entry := "test-entry"
-	method, err := New(&manager, "https", &entry)
+	method, err := New(viper.New(), &manager, "https", &entry)
 	_ = method
 	_ = err
 }
@@ -64,7 +65,7 @@ func (s *MethodsTestSuite) TestNewMethodHTTPS(c *C) {
 func (s *MethodsTestSuite) TestNewMethodS3(c *C) {
 	manager := "test-manager"
 	entry := "test-entry"
-	method, err := New(&manager, "s3", &entry)
+	method, err := New(viper.New(), &manager, "s3", &entry)
 	_ = method
 	_ = err
 }
@@ -72,7 +73,7 @@ func (s *MethodsTestSuite) TestNewMethodS3(c *C) {
 func (s *MethodsTestSuite) TestNewMethodFile(c *C) {
 	manager := "test-manager"
 	entry := "test-entry"
-	method, err := New(&manager, "file", &entry)
+	method, err := New(viper.New(), &manager, "file", &entry)
 	_ = method
 	_ = err
 }
@@ -80,7 +81,7 @@ func (s *MethodsTestSuite) TestNewMethodFile(c *C) {
 func (s *MethodsTestSuite) TestNewMethodBlob(c *C) {
 	manager := "test-manager"
 	entry := "test-entry"
-	method, err := New(&manager, "blob", &entry)
+	method, err := New(viper.New(), &manager, "blob", &entry)
 	_ = method
 	_ = err
 }
@@ -88,7 +89,7 @@ func (s *MethodsTestSuite) TestNewMethodBlob(c *C) {
 func (s *MethodsTestSuite) TestNewMethodEtcd(c *C) {
 	manager := "test-manager"
 	entry := "test-entry"
-	method, err := New(&manager, "etcd", &entry)
+	method, err := New(viper.New(), &manager, "etcd", &entry)
 	_ = method
 	_ = err
 }
@@ -96,7 +97,7 @@ func (s *MethodsTestSuite) TestNewMethodEtcd(c *C) {
 func (s *MethodsTestSuite) TestNewMethodDefault(c *C) {
 	manager := "test-manager"
 	entry := "test-entry"
-	method, err := New(&manager, "unknown", &entry)
+	method, err := New(viper.New(), &manager, "unknown", &entry)
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Equals, "Generic method handler is not very useful")
 	_ = method
@@ -107,8 +108,8 @@ func (s *MethodsTestSuite) TestNewMethodCaseInsensitive(c *C) {
 	entry := "test-entry"
 
 	// Test uppercase
-	method1, _ := New(&manager, "HTTP", &entry)
-	method2, _ := New(&manager, "http", &entry)
+	method1, _ := New(viper.New(), &manager, "HTTP", &entry)
+	method2, _ := New(viper.New(), &manager, "http", &entry)
 
 	// Both should create the same type of method
 	_ = method1
@@ -117,14 +118,22 @@ func (s *MethodsTestSuite) TestNewMethodCaseInsensitive(c *C) {
 
 func (s *MethodsTestSuite) TestNewMethodNilManager(c *C) {
 	entry := "test-entry"
-	method, err := New(nil, "http", &entry)
+	method, err := New(viper.New(), nil, "http", &entry)
 	_ = method
 	_ = err
 }
 
 func (s *MethodsTestSuite) TestNewMethodNilEntry(c *C) {
 	manager := "test-manager"
-	method, err := New(&manager, "http", nil)
+	method, err := New(viper.New(), &manager, "http", nil)
 	_ = method
 	_ = err
 }
+
+func (s *MethodsTestSuite) TestNewMethodNilSource(c *C) {
+	manager := "test-manager"
+	entry := "test-entry"
+	method, err := New(nil, &manager, "file", &entry)
+	c.Assert(err, IsNil)
+	c.Assert(method.(FileMethod).Path, Equals, "")
+}
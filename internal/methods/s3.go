@@ -14,6 +14,7 @@ package methods
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -51,15 +52,15 @@ type S3MethodOpts struct {
 	SessionToken    string
 }
 
-func NewS3Method(manager *string, entry *string) (Method, error) {
+func NewS3Method(v *viper.Viper, manager *string, entry *string) (Method, error) {
 	var (
 		err    error
 		result S3Method
 	)
 
-	if (manager != nil) && (entry != nil) {
+	if (v != nil) && (manager != nil) && (entry != nil) {
 
-		err = viper.UnmarshalKey(*entry, &result)
+		err = v.UnmarshalKey(*entry, &result)
 		if err != nil {
 			return result, err
 		}
@@ -127,7 +128,7 @@ func NewS3MethodWithOpts(opts S3MethodOpts) (Method, error) {
 	return result, err
 }
 
-func (s S3Method) Get(u *url.URL) (*Response, error) {
+func (s S3Method) Get(ctx context.Context, u *url.URL) (*Response, error) {
 	var (
 		response Response
 	)
@@ -137,8 +138,8 @@ func (s S3Method) Get(u *url.URL) (*Response, error) {
 		return &Response{}, fmt.Errorf("S3Method::Get(): could not create temp file err=%v", err)
 	}
 
-	log.Debugf("S3Method::Get(): going to download s3 region=%v, bucket=%v, key=%v", s.Region, s.Bucket, u.Path)
-	_, err = s.Downloader.Download(tmpFile,
+	log.WithField("method", "s3").Debugf("S3Method::Get(): going to download s3 region=%v, bucket=%v, key=%v", s.Region, s.Bucket, u.Path)
+	_, err = s.Downloader.DownloadWithContext(ctx, tmpFile,
 		&s3.GetObjectInput{
 			Bucket: aws.String(s.Bucket),
 			Key:    aws.String(u.Path),
@@ -0,0 +1,68 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package methods
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSOpts are the client TLS options of the http and etcd methods.
+type TLSOpts struct {
+	// CAFile is a PEM bundle of the CAs to verify the server against,
+	// instead of the system roots.
+	CAFile string
+	// ClientCert and ClientKey are the PEM certificate and key presented to
+	// servers which require client certificates.
+	ClientCert string
+	ClientKey  string
+	// ServerName overrides the name the server certificate is verified
+	// against, and which is sent as SNI.
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// TLSConfig builds the tls.Config for the options.
+func (o TLSOpts) TLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		InsecureSkipVerify: o.InsecureSkipVerify,
+		ServerName:         o.ServerName,
+	}
+
+	if o.CAFile != "" {
+		data, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read ca-file %v. err=%v", o.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in ca-file %v", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if (o.ClientCert == "") != (o.ClientKey == "") {
+		return nil, errors.New("client-cert and client-key must be set together")
+	}
+	if o.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(o.ClientCert, o.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client-cert %v and client-key %v. err=%v", o.ClientCert, o.ClientKey, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
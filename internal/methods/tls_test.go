@@ -0,0 +1,248 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package methods
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&TLSTestSuite{})
+
+type TLSTestSuite struct {
+	dir    string
+	server *httptest.Server
+}
+
+var TestViperConfigHTTPTLS = []byte(`[test-manager]
+  repos = ["repo"]
+  [test-manager.repo]
+    method = "https"
+    repo-path = "/butler/configs"
+    primary-config = ["prometheus.yml"]
+    [test-manager.repo.https]
+      retries = "1"
+      timeout = "5"
+      ca-file = "env:BUTLER_TEST_CA_FILE"
+      client-cert = "env:BUTLER_TEST_CLIENT_CERT"
+      client-key = "env:BUTLER_TEST_CLIENT_KEY"
+      server-name = "example.com"
+`)
+
+// writeClientCert writes a self signed client certificate and its key into
+// dir, and returns the certificate.
+func writeClientCert(c *C, dir string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "butler"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	c.Assert(err, IsNil)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, IsNil)
+	c.Assert(os.WriteFile(dir+"/client.crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644), IsNil)
+	c.Assert(os.WriteFile(dir+"/client.key", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600), IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+	return cert
+}
+
+func (s *TLSTestSuite) SetUpSuite(c *C) {
+	s.dir = c.MkDir()
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(writeClientCert(c, s.dir))
+
+	s.server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	s.server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	s.server.StartTLS()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.server.Certificate().Raw})
+	c.Assert(os.WriteFile(s.dir+"/ca.crt", ca, 0644), IsNil)
+}
+
+func (s *TLSTestSuite) TearDownSuite(c *C) {
+	s.server.Close()
+}
+
+func (s *TLSTestSuite) get(c *C, m Method) (*Response, error) {
+	u, err := url.Parse(s.server.URL + "/prometheus.yml")
+	c.Assert(err, IsNil)
+	return m.Get(context.Background(), u)
+}
+
+func (s *TLSTestSuite) TestTLSConfig(c *C) {
+	cfg, err := TLSOpts{}.TLSConfig()
+	c.Assert(err, IsNil)
+	c.Assert(cfg.RootCAs, IsNil)
+	c.Assert(cfg.Certificates, HasLen, 0)
+
+	cfg, err = TLSOpts{
+		CAFile:     s.dir + "/ca.crt",
+		ClientCert: s.dir + "/client.crt",
+		ClientKey:  s.dir + "/client.key",
+		ServerName: "example.com",
+	}.TLSConfig()
+	c.Assert(err, IsNil)
+	c.Assert(cfg.RootCAs, NotNil)
+	c.Assert(cfg.Certificates, HasLen, 1)
+	c.Assert(cfg.ServerName, Equals, "example.com")
+}
+
+func (s *TLSTestSuite) TestTLSConfigErrors(c *C) {
+	_, err := TLSOpts{CAFile: s.dir + "/missing.crt"}.TLSConfig()
+	c.Assert(err, ErrorMatches, "could not read ca-file .*")
+	_, err = TLSOpts{CAFile: s.dir + "/client.key"}.TLSConfig()
+	c.Assert(err, ErrorMatches, "no certificates found in ca-file .*")
+	_, err = TLSOpts{ClientCert: s.dir + "/client.crt"}.TLSConfig()
+	c.Assert(err, ErrorMatches, "client-cert and client-key must be set together")
+	_, err = TLSOpts{ClientCert: s.dir + "/ca.crt", ClientKey: s.dir + "/client.key"}.TLSConfig()
+	c.Assert(err, ErrorMatches, "could not load client-cert .*")
+}
+
+func (s *TLSTestSuite) TestNewHTTPMethodTLS(c *C) {
+	os.Setenv("BUTLER_TEST_CA_FILE", s.dir+"/ca.crt")
+	os.Setenv("BUTLER_TEST_CLIENT_CERT", s.dir+"/client.crt")
+	os.Setenv("BUTLER_TEST_CLIENT_KEY", s.dir+"/client.key")
+	defer os.Unsetenv("BUTLER_TEST_CA_FILE")
+	defer os.Unsetenv("BUTLER_TEST_CLIENT_CERT")
+	defer os.Unsetenv("BUTLER_TEST_CLIENT_KEY")
+
+	v := viper.New()
+	v.SetConfigType("toml")
+	c.Assert(v.ReadConfig(bytes.NewBuffer(TestViperConfigHTTPTLS)), IsNil)
+	manager := "test-manager"
+	entry := "test-manager.repo.https"
+	method, err := NewHTTPMethod(v, &manager, &entry)
+	c.Assert(err, IsNil)
+	m := method.(HTTPMethod)
+	c.Assert(m.CAFile, Equals, s.dir+"/ca.crt")
+	c.Assert(m.ClientCert, Equals, s.dir+"/client.crt")
+	c.Assert(m.ClientKey, Equals, s.dir+"/client.key")
+	c.Assert(m.ServerName, Equals, "example.com")
+
+	// The test server certificate is for example.com, and it requires a
+	// client certificate.
+	resp, err := s.get(c, m)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetResponseStatusCode(), Equals, http.StatusOK)
+
+	os.Setenv("BUTLER_TEST_CLIENT_KEY", s.dir+"/missing.key")
+	_, err = NewHTTPMethod(v, &manager, &entry)
+	c.Assert(err, ErrorMatches, "could not load client-cert .*")
+}
+
+func (s *TLSTestSuite) TestNewHTTPMethodTLSOAuth2(c *C) {
+	var (
+		serverName  string
+		clientCerts int
+	)
+	// The token server asks for client certificates, but does not require them
+	tokens := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverName = r.TLS.ServerName
+		clientCerts = len(r.TLS.PeerCertificates)
+		fmt.Fprintf(w, `{"access_token": "oauth2-token", "token_type": "bearer", "expires_in": 3600}`)
+	}))
+	tokens.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	tokens.StartTLS()
+	defer tokens.Close()
+	os.Setenv("BUTLER_TEST_CA_FILE", s.dir+"/ca.crt")
+	os.Setenv("BUTLER_TEST_CLIENT_CERT", s.dir+"/client.crt")
+	os.Setenv("BUTLER_TEST_CLIENT_KEY", s.dir+"/client.key")
+	defer os.Unsetenv("BUTLER_TEST_CA_FILE")
+	defer os.Unsetenv("BUTLER_TEST_CLIENT_CERT")
+	defer os.Unsetenv("BUTLER_TEST_CLIENT_KEY")
+
+	config := string(TestViperConfigHTTPTLS) + fmt.Sprintf(`      auth-type = "oauth2-client-credentials"
+      token-url = "%v"
+      client-id = "butler"
+      client-secret = "s3cr3t"
+`, tokens.URL)
+	manager := "test-manager"
+	entry := "test-manager.repo.https"
+
+	// The ca-file of the repository is not used for the token server, even
+	// though it would verify it.
+	v := viper.New()
+	v.SetConfigType("toml")
+	c.Assert(v.ReadConfig(bytes.NewBufferString(config)), IsNil)
+	method, err := NewHTTPMethod(v, &manager, &entry)
+	c.Assert(err, IsNil)
+	_, err = s.get(c, method)
+	c.Assert(err, ErrorMatches, ".*certificate signed by unknown authority.*")
+
+	v = viper.New()
+	v.SetConfigType("toml")
+	c.Assert(v.ReadConfig(bytes.NewBufferString(config+`      token-ca-file = "env:BUTLER_TEST_CA_FILE"
+`)), IsNil)
+	method, err = NewHTTPMethod(v, &manager, &entry)
+	c.Assert(err, IsNil)
+	c.Assert(method.(HTTPMethod).TokenCAFile, Equals, s.dir+"/ca.crt")
+	resp, err := s.get(c, method)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetResponseStatusCode(), Equals, http.StatusOK)
+	// Neither the server-name nor the client certificate of the repository
+	// went to the token server.
+	c.Assert(serverName, Equals, "")
+	c.Assert(clientCerts, Equals, 0)
+}
+
+func (s *TLSTestSuite) TestHTTPMethodSetTLSOpts(c *C) {
+	method, err := NewHTTPMethod(nil, nil, nil)
+	c.Assert(err, IsNil)
+	m := method.(HTTPMethod)
+
+	// Neither the private CA nor the client certificate
+	_, err = s.get(c, m)
+	c.Assert(err, NotNil)
+
+	// The private CA, but no client certificate
+	c.Assert(m.SetTLSOpts(TLSOpts{CAFile: s.dir + "/ca.crt", ServerName: "example.com"}), IsNil)
+	_, err = s.get(c, m)
+	c.Assert(err, NotNil)
+
+	// -tls.insecure-skip-verify keeps the client certificate
+	c.Assert(m.SetTLSOpts(TLSOpts{ClientCert: s.dir + "/client.crt", ClientKey: s.dir + "/client.key"}), IsNil)
+	m.InsecureSkipVerify = true
+	resp, err := s.get(c, m)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetResponseStatusCode(), Equals, http.StatusOK)
+}
@@ -13,7 +13,9 @@ governing permissions and limitations under the License.
 package metrics
 
 import (
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	//log "github.com/sirupsen/logrus"
@@ -30,6 +32,22 @@ const (
 	SUCCESS
 )
 
+// The values of the result label of the counters and histograms.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	// ResultTimeout is a reload which timed out, with manager-timeout-ok set.
+	ResultTimeout = "timeout"
+)
+
+// resultLabel returns the result label for the SUCCESS or FAILURE res.
+func resultLabel(res float64) string {
+	if res == SUCCESS {
+		return ResultSuccess
+	}
+	return ResultFailure
+}
+
 // Prometheus metrics
 var (
 	butlerConfigValid       *prometheus.GaugeVec
@@ -39,7 +57,9 @@ var (
 	butlerContactTime       *prometheus.GaugeVec
 	butlerKnownGoodCached   *prometheus.GaugeVec
 	butlerKnownGoodRestored *prometheus.GaugeVec
+	butlerManagerPaused     *prometheus.GaugeVec
 	butlerReloadCount       *prometheus.GaugeVec
+	butlerReloadPending     *prometheus.GaugeVec
 	butlerReloadSuccess     *prometheus.GaugeVec
 	butlerReloadTime        *prometheus.GaugeVec
 	butlerReloaderRetry     *prometheus.GaugeVec
@@ -50,6 +70,27 @@ var (
 	butlerRepoInSync        *prometheus.GaugeVec
 	butlerWriteSuccess      *prometheus.GaugeVec
 	butlerWriteTime         *prometheus.GaugeVec
+	butlerBuildInfo         *prometheus.GaugeVec
+
+	// The counters and histograms, which failure rates and latencies can be
+	// computed from.
+	butlerRuns               *prometheus.CounterVec
+	butlerRunDuration        prometheus.Histogram
+	butlerManagerRuns        *prometheus.CounterVec
+	butlerDownloads          *prometheus.CounterVec
+	butlerDownloadDuration   *prometheus.HistogramVec
+	butlerFileSize           *prometheus.HistogramVec
+	butlerValidationFailures *prometheus.CounterVec
+	butlerReloads            *prometheus.CounterVec
+	butlerReloadDuration     *prometheus.HistogramVec
+	butlerRollbacks          *prometheus.CounterVec
+
+	// How far the applied configuration of the managers is behind upstream.
+	butlerManagerConfigInfo *prometheus.GaugeVec
+	butlerManagerLastApply  *prometheus.GaugeVec
+	butlerManagerLastReload *prometheus.GaugeVec
+	butlerManagerConfigAge  *prometheus.GaugeVec
+	butlerManagerOutOfSync  *prometheus.GaugeVec
 )
 
 func init() {
@@ -88,11 +129,21 @@ func init() {
 		Help: "Did butler restore the known good configuration",
 	}, []string{"manager"})
 
+	butlerManagerPaused = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "butler_manager_paused",
+		Help: "Is the manager paused",
+	}, []string{"manager"})
+
 	butlerReloadCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "butler_localconfig_reload_count",
 		Help: "butler reload counter",
 	}, []string{"manager"})
 
+	butlerReloadPending = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "butler_manager_reload_pending",
+		Help: "Is a reload of the manager deferred by its reload limits",
+	}, []string{"manager"})
+
 	butlerReloadSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "butler_localconfig_reload_success",
 		Help: "Did butler successfully reload prometheus",
@@ -143,6 +194,90 @@ func init() {
 		Help: "Time that butler successfully write the configuration",
 	}, []string{"config_file"})
 
+	butlerBuildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "butler_build_info",
+		Help: "The version of butler, and the go version it was built with",
+	}, []string{"version", "goversion"})
+
+	butlerRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "butler_runs_total",
+		Help: "How many configuration management runs butler has done",
+	}, []string{"result"})
+
+	butlerRunDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "butler_run_duration_seconds",
+		Help:    "How long the configuration management runs took, from the first download to the last reload",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	})
+
+	butlerManagerRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "butler_manager_runs_total",
+		Help: "How many times butler has updated the manager",
+	}, []string{"manager", "result"})
+
+	butlerDownloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "butler_remoterepo_downloads_total",
+		Help: "How many files butler has downloaded from the remote repository",
+	}, []string{"manager", "repo", "result"})
+
+	butlerDownloadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "butler_remoterepo_download_duration_seconds",
+		Help:    "How long the downloads from the remote repository took, retries included",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"manager", "repo", "result"})
+
+	butlerFileSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "butler_remoterepo_file_size_bytes",
+		Help:    "The size of the files downloaded from the remote repository",
+		Buckets: prometheus.ExponentialBuckets(1024, 4, 8),
+	}, []string{"manager", "repo"})
+
+	butlerValidationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "butler_remoterepo_validation_failures_total",
+		Help: "How many downloaded files failed to render or validate",
+	}, []string{"manager", "repo"})
+
+	butlerReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "butler_manager_reloads_total",
+		Help: "How many times butler has reloaded the manager",
+	}, []string{"manager", "result"})
+
+	butlerReloadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "butler_manager_reload_duration_seconds",
+		Help:    "How long the reloads of the manager took, retries included",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"manager", "result"})
+
+	butlerRollbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "butler_lastknowngood_rollbacks_total",
+		Help: "How many times butler has rolled the manager back to its known good configuration",
+	}, []string{"manager", "result"})
+
+	butlerManagerConfigInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "butler_manager_config_info",
+		Help: "The sha256 of the configuration files which the manager has applied",
+	}, []string{"manager", "sha256"})
+
+	butlerManagerLastApply = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "butler_manager_last_apply_timestamp_seconds",
+		Help: "Time that butler last applied updated configuration files for the manager, once they were reloaded",
+	}, []string{"manager"})
+
+	butlerManagerLastReload = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "butler_manager_last_reload_timestamp_seconds",
+		Help: "Time that butler last successfully reloaded the manager",
+	}, []string{"manager"})
+
+	butlerManagerConfigAge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "butler_manager_config_age_seconds",
+		Help: "How long the applied configuration of the manager has been behind upstream, as of the last run",
+	}, []string{"manager"})
+
+	butlerManagerOutOfSync = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "butler_manager_out_of_sync",
+		Help: "Does upstream differ from the applied configuration of the manager, after a failed apply or reload",
+	}, []string{"manager"})
+
 	prometheus.MustRegister(butlerConfigValid)
 	prometheus.MustRegister(butlerContactRetry)
 	prometheus.MustRegister(butlerContactRetryTime)
@@ -150,7 +285,9 @@ func init() {
 	prometheus.MustRegister(butlerContactTime)
 	prometheus.MustRegister(butlerKnownGoodCached)
 	prometheus.MustRegister(butlerKnownGoodRestored)
+	prometheus.MustRegister(butlerManagerPaused)
 	prometheus.MustRegister(butlerReloadCount)
+	prometheus.MustRegister(butlerReloadPending)
 	prometheus.MustRegister(butlerReloadSuccess)
 	prometheus.MustRegister(butlerReloadTime)
 	prometheus.MustRegister(butlerReloaderRetry)
@@ -161,6 +298,104 @@ func init() {
 	prometheus.MustRegister(butlerRepoInSync)
 	prometheus.MustRegister(butlerWriteTime)
 	prometheus.MustRegister(butlerWriteSuccess)
+	prometheus.MustRegister(butlerBuildInfo)
+	prometheus.MustRegister(butlerRuns)
+	prometheus.MustRegister(butlerRunDuration)
+	prometheus.MustRegister(butlerManagerRuns)
+	prometheus.MustRegister(butlerDownloads)
+	prometheus.MustRegister(butlerDownloadDuration)
+	prometheus.MustRegister(butlerFileSize)
+	prometheus.MustRegister(butlerValidationFailures)
+	prometheus.MustRegister(butlerReloads)
+	prometheus.MustRegister(butlerReloadDuration)
+	prometheus.MustRegister(butlerRollbacks)
+	prometheus.MustRegister(butlerManagerConfigInfo)
+	prometheus.MustRegister(butlerManagerLastApply)
+	prometheus.MustRegister(butlerManagerLastReload)
+	prometheus.MustRegister(butlerManagerConfigAge)
+	prometheus.MustRegister(butlerManagerOutOfSync)
+}
+
+// SetButlerBuildInfo sets butler_build_info, which is always 1, for the
+// version of butler.
+func SetButlerBuildInfo(version string) {
+	butlerBuildInfo.Reset()
+	butlerBuildInfo.With(prometheus.Labels{"version": version, "goversion": runtime.Version()}).Set(1)
+}
+
+// ObserveButlerRun counts a configuration management run, which took d.
+func ObserveButlerRun(res float64, d time.Duration) {
+	butlerRuns.With(prometheus.Labels{"result": resultLabel(res)}).Inc()
+	butlerRunDuration.Observe(d.Seconds())
+}
+
+// IncButlerManagerRun counts an update of the manager during a run.
+func IncButlerManagerRun(res float64, manager string) {
+	butlerManagerRuns.With(prometheus.Labels{"manager": manager, "result": resultLabel(res)}).Inc()
+}
+
+// ObserveButlerDownload counts a download from the repo, which took d. The
+// size, in bytes, is only observed for a successful download.
+func ObserveButlerDownload(res float64, manager string, repo string, d time.Duration, size int64) {
+	result := resultLabel(res)
+	butlerDownloads.With(prometheus.Labels{"manager": manager, "repo": repo, "result": result}).Inc()
+	butlerDownloadDuration.With(prometheus.Labels{"manager": manager, "repo": repo, "result": result}).Observe(d.Seconds())
+	if res == SUCCESS {
+		butlerFileSize.With(prometheus.Labels{"manager": manager, "repo": repo}).Observe(float64(size))
+	}
+}
+
+// IncButlerValidationFailure counts a file from the repo which failed to
+// render or validate.
+func IncButlerValidationFailure(manager string, repo string) {
+	butlerValidationFailures.With(prometheus.Labels{"manager": manager, "repo": repo}).Inc()
+}
+
+// ObserveButlerReload counts a reload of the manager, which took d. result
+// is one of ResultSuccess, ResultFailure or ResultTimeout.
+func ObserveButlerReload(result string, manager string, d time.Duration) {
+	butlerReloads.With(prometheus.Labels{"manager": manager, "result": result}).Inc()
+	butlerReloadDuration.With(prometheus.Labels{"manager": manager, "result": result}).Observe(d.Seconds())
+}
+
+// IncButlerRollback counts a rollback of the manager to its known good
+// configuration. It fails when there is no known good configuration.
+func IncButlerRollback(res float64, manager string) {
+	butlerRollbacks.With(prometheus.Labels{"manager": manager, "result": resultLabel(res)}).Inc()
+}
+
+// SetButlerManagerConfigHash sets butler_manager_config_info, which is always
+// 1, for the sha256 of the configuration files applied by the manager. The
+// previous sha256 of the manager is dropped.
+func SetButlerManagerConfigHash(hash string, manager string) {
+	butlerManagerConfigInfo.DeletePartialMatch(prometheus.Labels{"manager": manager})
+	butlerManagerConfigInfo.With(prometheus.Labels{"manager": manager, "sha256": hash}).Set(1)
+}
+
+// SetButlerManagerLastApply records that updated configuration files have
+// just been applied for the manager, that is written and reloaded.
+func SetButlerManagerLastApply(manager string) {
+	butlerManagerLastApply.With(prometheus.Labels{"manager": manager}).SetToCurrentTime()
+}
+
+// SetButlerManagerLastReload records that the manager has just been
+// successfully reloaded.
+func SetButlerManagerLastReload(manager string) {
+	butlerManagerLastReload.With(prometheus.Labels{"manager": manager}).SetToCurrentTime()
+}
+
+// SetButlerManagerConfigAge sets how long the applied configuration of the
+// manager has been behind upstream, 0 when it is in sync.
+func SetButlerManagerConfigAge(age time.Duration, manager string) {
+	butlerManagerConfigAge.With(prometheus.Labels{"manager": manager}).Set(age.Seconds())
+}
+
+func SetButlerManagerOutOfSync(outOfSync bool, manager string) {
+	if outOfSync {
+		butlerManagerOutOfSync.With(prometheus.Labels{"manager": manager}).Set(1)
+	} else {
+		butlerManagerOutOfSync.With(prometheus.Labels{"manager": manager}).Set(0)
+	}
 }
 
 func SetButlerReloadVal(res float64, label string) {
@@ -243,6 +478,22 @@ func SetButlerKnownGoodRestoredVal(res float64, label string) {
 	}
 }
 
+func SetButlerManagerPaused(paused bool, manager string) {
+	if paused {
+		butlerManagerPaused.With(prometheus.Labels{"manager": manager}).Set(1)
+	} else {
+		butlerManagerPaused.With(prometheus.Labels{"manager": manager}).Set(0)
+	}
+}
+
+func SetButlerReloadPending(pending bool, manager string) {
+	if pending {
+		butlerReloadPending.With(prometheus.Labels{"manager": manager}).Set(1)
+	} else {
+		butlerReloadPending.With(prometheus.Labels{"manager": manager}).Set(0)
+	}
+}
+
 func SetButlerReloaderRetry(res float64, manager string) {
 	butlerReloaderRetry.With(prometheus.Labels{"manager": manager}).Inc()
 }
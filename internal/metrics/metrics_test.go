@@ -13,8 +13,10 @@ governing permissions and limitations under the License.
 package metrics
 
 import (
+	"runtime"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_model/go"
 	log "github.com/sirupsen/logrus"
 	. "gopkg.in/check.v1"
@@ -242,3 +244,148 @@ func (s *ButlerStatsTestSuite) TestSetButlerWriteVal(c *C) {
 func (s *ButlerStatsTestSuite) TestGetStatsLabel(c *C) {
 	c.Assert(GetStatsLabel(s.TestFile), Equals, s.TestFileResult)
 }
+
+func (s *ButlerStatsTestSuite) TestSetButlerManagerPaused(c *C) {
+	metric := io_prometheus_client.Metric{}
+
+	SetButlerManagerPaused(true, "alertmanager")
+	butlerManagerPausedMetric, err := butlerManagerPaused.GetMetricWithLabelValues("alertmanager")
+	c.Assert(err, IsNil)
+	c.Assert(butlerManagerPausedMetric.Desc().String(), Matches, `Desc\{fqName: "butler_manager_paused", help: "Is the manager paused", constLabels: \{\}, variableLabels: .*manager.*\}`)
+	butlerManagerPausedMetric.Write(&metric)
+	c.Assert(*metric.Gauge.Value, Equals, 1.0)
+
+	SetButlerManagerPaused(false, "alertmanager")
+	butlerManagerPausedMetric.Write(&metric)
+	c.Assert(*metric.Gauge.Value, Equals, 0.0)
+}
+
+func (s *ButlerStatsTestSuite) TestSetButlerReloadPending(c *C) {
+	metric := io_prometheus_client.Metric{}
+
+	SetButlerReloadPending(true, "prometheus")
+	butlerReloadPendingMetric, err := butlerReloadPending.GetMetricWithLabelValues("prometheus")
+	c.Assert(err, IsNil)
+	c.Assert(butlerReloadPendingMetric.Desc().String(), Matches, `Desc\{fqName: "butler_manager_reload_pending", help: "Is a reload of the manager deferred by its reload limits", constLabels: \{\}, variableLabels: .*manager.*\}`)
+	butlerReloadPendingMetric.Write(&metric)
+	c.Assert(*metric.Gauge.Value, Equals, 1.0)
+
+	SetButlerReloadPending(false, "prometheus")
+	butlerReloadPendingMetric.Write(&metric)
+	c.Assert(*metric.Gauge.Value, Equals, 0.0)
+}
+
+func (s *ButlerStatsTestSuite) TestSetButlerBuildInfo(c *C) {
+	metric := io_prometheus_client.Metric{}
+
+	SetButlerBuildInfo("v1.2.3")
+	SetButlerBuildInfo("v1.2.4")
+	c.Assert(testCount(butlerBuildInfo), Equals, 1)
+	butlerBuildInfoMetric, err := butlerBuildInfo.GetMetricWithLabelValues("v1.2.4", runtime.Version())
+	c.Assert(err, IsNil)
+	butlerBuildInfoMetric.Write(&metric)
+	c.Assert(*metric.Gauge.Value, Equals, 1.0)
+}
+
+// testCount returns the number of label sets of the collector c.
+func testCount(c prometheus.Collector) int {
+	ch := make(chan prometheus.Metric, 100)
+	c.Collect(ch)
+	close(ch)
+	return len(ch)
+}
+
+func (s *ButlerStatsTestSuite) TestObserveButlerRun(c *C) {
+	metric := io_prometheus_client.Metric{}
+
+	ObserveButlerRun(SUCCESS, 2*time.Second)
+	ObserveButlerRun(FAILURE, time.Second)
+	ObserveButlerRun(FAILURE, time.Second)
+	butlerRuns.WithLabelValues(ResultFailure).Write(&metric)
+	c.Assert(*metric.Counter.Value, Equals, 2.0)
+	butlerRunDuration.Write(&metric)
+	c.Assert(*metric.Histogram.SampleCount, Equals, uint64(3))
+	c.Assert(*metric.Histogram.SampleSum, Equals, 4.0)
+
+	IncButlerManagerRun(FAILURE, "prometheus")
+	butlerManagerRuns.WithLabelValues("prometheus", ResultFailure).Write(&metric)
+	c.Assert(*metric.Counter.Value, Equals, 1.0)
+}
+
+func (s *ButlerStatsTestSuite) TestObserveButlerDownload(c *C) {
+	metric := io_prometheus_client.Metric{}
+
+	ObserveButlerDownload(SUCCESS, "prometheus", "repo1", 100*time.Millisecond, 2048)
+	ObserveButlerDownload(FAILURE, "prometheus", "repo1", 5*time.Second, 0)
+	butlerDownloads.WithLabelValues("prometheus", "repo1", ResultSuccess).Write(&metric)
+	c.Assert(*metric.Counter.Value, Equals, 1.0)
+	butlerDownloads.WithLabelValues("prometheus", "repo1", ResultFailure).Write(&metric)
+	c.Assert(*metric.Counter.Value, Equals, 1.0)
+	butlerDownloadDuration.WithLabelValues("prometheus", "repo1", ResultFailure).(prometheus.Histogram).Write(&metric)
+	c.Assert(*metric.Histogram.SampleSum, Equals, 5.0)
+
+	// Only the successful download has a size
+	butlerFileSize.WithLabelValues("prometheus", "repo1").(prometheus.Histogram).Write(&metric)
+	c.Assert(*metric.Histogram.SampleCount, Equals, uint64(1))
+	c.Assert(*metric.Histogram.SampleSum, Equals, 2048.0)
+
+	IncButlerValidationFailure("prometheus", "repo1")
+	butlerValidationFailures.WithLabelValues("prometheus", "repo1").Write(&metric)
+	c.Assert(*metric.Counter.Value, Equals, 1.0)
+}
+
+func (s *ButlerStatsTestSuite) TestObserveButlerReload(c *C) {
+	metric := io_prometheus_client.Metric{}
+
+	ObserveButlerReload(ResultSuccess, "alertmanager", time.Second)
+	ObserveButlerReload(ResultTimeout, "alertmanager", 30*time.Second)
+	butlerReloads.WithLabelValues("alertmanager", ResultTimeout).Write(&metric)
+	c.Assert(*metric.Counter.Value, Equals, 1.0)
+	butlerReloadDuration.WithLabelValues("alertmanager", ResultSuccess).(prometheus.Histogram).Write(&metric)
+	c.Assert(*metric.Histogram.SampleSum, Equals, 1.0)
+
+	IncButlerRollback(SUCCESS, "alertmanager")
+	IncButlerRollback(FAILURE, "alertmanager")
+	butlerRollbacks.WithLabelValues("alertmanager", ResultSuccess).Write(&metric)
+	c.Assert(*metric.Counter.Value, Equals, 1.0)
+}
+
+func (s *ButlerStatsTestSuite) TestSetButlerManagerConfigHash(c *C) {
+	metric := io_prometheus_client.Metric{}
+
+	// Only the last sha256 of a manager is kept
+	SetButlerManagerConfigHash("aaaa", "alertmanager")
+	SetButlerManagerConfigHash("bbbb", "alertmanager")
+	SetButlerManagerConfigHash("cccc", "prometheus")
+	c.Assert(testCount(butlerManagerConfigInfo), Equals, 2)
+	butlerManagerConfigInfoMetric, err := butlerManagerConfigInfo.GetMetricWithLabelValues("alertmanager", "bbbb")
+	c.Assert(err, IsNil)
+	butlerManagerConfigInfoMetric.Write(&metric)
+	c.Assert(*metric.Gauge.Value, Equals, 1.0)
+}
+
+func (s *ButlerStatsTestSuite) TestSetButlerManagerSync(c *C) {
+	metric := io_prometheus_client.Metric{}
+
+	now := float64(time.Now().Unix())
+	SetButlerManagerLastApply("alertmanager")
+	butlerManagerLastApply.WithLabelValues("alertmanager").Write(&metric)
+	c.Assert(*metric.Gauge.Value >= now, Equals, true)
+	SetButlerManagerLastReload("alertmanager")
+	butlerManagerLastReload.WithLabelValues("alertmanager").Write(&metric)
+	c.Assert(*metric.Gauge.Value >= now, Equals, true)
+
+	SetButlerManagerConfigAge(90*time.Second, "alertmanager")
+	butlerManagerConfigAge.WithLabelValues("alertmanager").Write(&metric)
+	c.Assert(*metric.Gauge.Value, Equals, 90.0)
+
+	SetButlerManagerOutOfSync(true, "alertmanager")
+	butlerManagerOutOfSyncMetric, err := butlerManagerOutOfSync.GetMetricWithLabelValues("alertmanager")
+	c.Assert(err, IsNil)
+	c.Assert(butlerManagerOutOfSyncMetric.Desc().String(), Matches, `Desc\{fqName: "butler_manager_out_of_sync", .*variableLabels: .*manager.*\}`)
+	butlerManagerOutOfSyncMetric.Write(&metric)
+	c.Assert(*metric.Gauge.Value, Equals, 1.0)
+	SetButlerManagerOutOfSync(false, "alertmanager")
+	butlerManagerOutOfSyncMetric.Write(&metric)
+	c.Assert(*metric.Gauge.Value, Equals, 0.0)
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package monitor
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/adobe/butler/internal/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// AdminRunPath is the admin api endpoint which triggers a run of all the
+	// configured managers.
+	AdminRunPath = "/api/v1/run"
+	// AdminPlanPath is the admin api endpoint which does a dry run of all the
+	// configured managers.
+	AdminPlanPath = "/api/v1/plan"
+	// AdminManagersPath is the prefix of the admin api endpoints which act
+	// on a single manager, eg: /api/v1/managers/<name>/run
+	AdminManagersPath = "/api/v1/managers/"
+)
+
+// AdminError is the json body returned by the admin api on failure.
+type AdminError struct {
+	Error string `json:"error"`
+}
+
+// adminAuthorized checks the request for a valid admin bearer token. The
+// admin api is disabled altogether when there is no admin-token configured.
+func (m *Monitor) adminAuthorized(w http.ResponseWriter, r *http.Request) bool {
+	token := ""
+	if m.config != nil {
+		token = m.config.AdminToken()
+	}
+	if token == "" {
+		writeAdminJSON(w, http.StatusForbidden, AdminError{Error: "admin api is disabled"})
+		return false
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeAdminJSON(w, http.StatusUnauthorized, AdminError{Error: "unauthorized"})
+		return false
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeAdminJSON(w, http.StatusMethodNotAllowed, AdminError{Error: "method not allowed"})
+		return false
+	}
+	return true
+}
+
+// AdminRunHandler is the handler function for the /api/v1/run admin
+// endpoint. It runs all the configured managers, waits for the run to
+// complete and returns the json result of the run.
+func (m *Monitor) AdminRunHandler(w http.ResponseWriter, r *http.Request) {
+	if !m.adminAuthorized(w, r) {
+		return
+	}
+	log.Infof("Monitor::AdminRunHandler(): run requested by %v", r.RemoteAddr)
+	writeRunResult(w, m.config.Run)
+}
+
+// AdminPlanHandler is the handler function for the /api/v1/plan admin
+// endpoint. It does a dry run of all the configured managers, and returns the
+// json result, including the diff of every file which would change.
+func (m *Monitor) AdminPlanHandler(w http.ResponseWriter, r *http.Request) {
+	if !m.adminAuthorized(w, r) {
+		return
+	}
+	log.Infof("Monitor::AdminPlanHandler(): plan requested by %v", r.RemoteAddr)
+	writeRunResult(w, m.config.Plan)
+}
+
+// AdminManagersHandler is the handler function for the /api/v1/managers/
+// admin endpoints, which act on a single manager:
+//
+//	POST /api/v1/managers/<name>/run runs the manager, waits for the run to
+//	complete and returns the json result of the run.
+//	POST /api/v1/managers/<name>/plan does a dry run of the manager.
+//	POST /api/v1/managers/<name>/pause pauses the manager.
+//	POST /api/v1/managers/<name>/resume resumes the manager.
+func (m *Monitor) AdminManagersHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, AdminManagersPath), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		writeAdminJSON(w, http.StatusNotFound, AdminError{Error: "not found"})
+		return
+	}
+	name, action := parts[0], parts[1]
+	if action != "run" && action != "plan" && action != "pause" && action != "resume" {
+		writeAdminJSON(w, http.StatusNotFound, AdminError{Error: "not found"})
+		return
+	}
+	if !m.adminAuthorized(w, r) {
+		return
+	}
+
+	log.WithField("manager", name).Infof("Monitor::AdminManagersHandler(): %v requested by %v", action, r.RemoteAddr)
+
+	switch action {
+	case "run":
+		writeRunResult(w, func() (*config.RunResult, error) { return m.config.RunManager(name) })
+	case "plan":
+		writeRunResult(w, func() (*config.RunResult, error) { return m.config.PlanManager(name) })
+	case "pause":
+		writePauseResult(w, name, true, m.config.PauseManager(name))
+	case "resume":
+		writePauseResult(w, name, false, m.config.ResumeManager(name))
+	}
+}
+
+// PauseResult is the json body returned by the pause and resume admin
+// endpoints.
+type PauseResult struct {
+	Name   string `json:"name"`
+	Paused bool   `json:"paused"`
+}
+
+func writePauseResult(w http.ResponseWriter, name string, paused bool, err error) {
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, PauseResult{Name: name, Paused: paused})
+}
+
+// writeRunResult runs f and writes its result. The response status is 200
+// when every manager was successful, and 500 otherwise.
+func writeRunResult(w http.ResponseWriter, f func() (*config.RunResult, error)) {
+	res, err := f()
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if !res.Success {
+		status = http.StatusInternalServerError
+	}
+	writeAdminJSON(w, status, res)
+}
+
+// writeAdminError writes err, with a 404 for a manager which does not exist,
+// and a 500 otherwise.
+func writeAdminError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, config.ErrUnknownManager) {
+		status = http.StatusNotFound
+	}
+	writeAdminJSON(w, status, AdminError{Error: err.Error()})
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "could not marshal json: %v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(resp)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package monitor
+
+import (
+	. "gopkg.in/check.v1"
+
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+
+	"github.com/adobe/butler/internal/config"
+)
+
+type AdminTestSuite struct {
+	m *Monitor
+}
+
+var _ = Suite(&AdminTestSuite{})
+
+func (s *AdminTestSuite) SetUpTest(c *C) {
+	u, err := url.Parse("http://localhost/butler.toml")
+	c.Assert(err, IsNil)
+	bc, err := config.NewButlerConfig(&config.ButlerConfigOpts{URL: u})
+	c.Assert(err, IsNil)
+	bc.Config = config.NewConfigSettings()
+	bc.Config.Managers = make(map[string]*config.Manager)
+	bc.SetAdminToken("s3cr3t")
+	bc.Config.Globals.StatusFile = c.MkDir() + "/butler.status"
+	s.m = NewMonitor().WithOpts(&Opts{Config: bc, Version: "1.2.3"})
+}
+
+func adminRequest(h http.HandlerFunc, method string, path string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	h(rr, req)
+	return rr
+}
+
+func (s *AdminTestSuite) TestAdminDisabled(c *C) {
+	s.m.config.SetAdminToken("")
+	rr := adminRequest(s.m.AdminRunHandler, http.MethodPost, AdminRunPath, "s3cr3t")
+	c.Assert(rr.Code, Equals, http.StatusForbidden)
+}
+
+func (s *AdminTestSuite) TestAdminUnauthorized(c *C) {
+	rr := adminRequest(s.m.AdminRunHandler, http.MethodPost, AdminRunPath, "")
+	c.Assert(rr.Code, Equals, http.StatusUnauthorized)
+	rr = adminRequest(s.m.AdminRunHandler, http.MethodPost, AdminRunPath, "wrong")
+	c.Assert(rr.Code, Equals, http.StatusUnauthorized)
+	rr = adminRequest(s.m.AdminManagersHandler, http.MethodPost, AdminManagersPath+"foo/run", "wrong")
+	c.Assert(rr.Code, Equals, http.StatusUnauthorized)
+}
+
+func (s *AdminTestSuite) TestAdminMethodNotAllowed(c *C) {
+	rr := adminRequest(s.m.AdminRunHandler, http.MethodGet, AdminRunPath, "s3cr3t")
+	c.Assert(rr.Code, Equals, http.StatusMethodNotAllowed)
+	c.Assert(rr.Header().Get("Allow"), Equals, http.MethodPost)
+}
+
+func (s *AdminTestSuite) TestAdminRun(c *C) {
+	rr := adminRequest(s.m.AdminRunHandler, http.MethodPost, AdminRunPath, "s3cr3t")
+	c.Assert(rr.Code, Equals, http.StatusOK)
+	c.Assert(rr.Header().Get("Content-Type"), Equals, "application/json")
+	var res config.RunResult
+	c.Assert(json.Unmarshal(rr.Body.Bytes(), &res), IsNil)
+	c.Assert(res.Success, Equals, true)
+	c.Assert(res.Managers, HasLen, 0)
+}
+
+func (s *AdminTestSuite) TestAdminManagersNotFound(c *C) {
+	rr := adminRequest(s.m.AdminManagersHandler, http.MethodPost, AdminManagersPath+"nope/run", "s3cr3t")
+	c.Assert(rr.Code, Equals, http.StatusNotFound)
+	c.Assert(rr.Body.String(), Matches, `.*unknown manager nope.*`)
+	rr = adminRequest(s.m.AdminManagersHandler, http.MethodPost, AdminManagersPath+"nope", "s3cr3t")
+	c.Assert(rr.Code, Equals, http.StatusNotFound)
+	rr = adminRequest(s.m.AdminManagersHandler, http.MethodPost, AdminManagersPath+"nope/reload", "s3cr3t")
+	c.Assert(rr.Code, Equals, http.StatusNotFound)
+}
+
+func (s *AdminTestSuite) TestAdminPauseResume(c *C) {
+	s.m.config.Config.Managers["alertmanager"] = &config.Manager{Name: "alertmanager"}
+
+	rr := adminRequest(s.m.AdminManagersHandler, http.MethodPost, AdminManagersPath+"alertmanager/pause", "s3cr3t")
+	c.Assert(rr.Code, Equals, http.StatusOK)
+	c.Assert(rr.Body.String(), Equals, `{"name":"alertmanager","paused":true}`)
+	c.Assert(s.m.config.GetManager("alertmanager").Paused, Equals, true)
+	_, err := os.Stat(s.m.config.GetStatusFile())
+	c.Assert(err, IsNil)
+
+	rr = adminRequest(s.m.AdminManagersHandler, http.MethodPost, AdminManagersPath+"alertmanager/resume", "s3cr3t")
+	c.Assert(rr.Code, Equals, http.StatusOK)
+	c.Assert(rr.Body.String(), Equals, `{"name":"alertmanager","paused":false}`)
+	c.Assert(s.m.config.GetManager("alertmanager").Paused, Equals, false)
+
+	rr = adminRequest(s.m.AdminManagersHandler, http.MethodPost, AdminManagersPath+"nope/pause", "s3cr3t")
+	c.Assert(rr.Code, Equals, http.StatusNotFound)
+	rr = adminRequest(s.m.AdminManagersHandler, http.MethodPost, AdminManagersPath+"nope/resume", "s3cr3t")
+	c.Assert(rr.Code, Equals, http.StatusNotFound)
+	c.Assert(rr.Body.String(), Equals, `{"error":"unknown manager nope"}`)
+}
+
+func (s *AdminTestSuite) TestAdminPlan(c *C) {
+	rr := adminRequest(s.m.AdminPlanHandler, http.MethodPost, AdminPlanPath, "s3cr3t")
+	c.Assert(rr.Code, Equals, http.StatusOK)
+	var res config.RunResult
+	c.Assert(json.Unmarshal(rr.Body.Bytes(), &res), IsNil)
+	c.Assert(res.DryRun, Equals, true)
+	c.Assert(res.Success, Equals, true)
+
+	rr = adminRequest(s.m.AdminManagersHandler, http.MethodPost, AdminManagersPath+"nope/plan", "s3cr3t")
+	c.Assert(rr.Code, Equals, http.StatusNotFound)
+}
@@ -5,8 +5,10 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/adobe/butler/internal/alog"
@@ -33,7 +35,10 @@ func (m *Monitor) WithOpts(opts *Opts) *Monitor {
 // Monitor is the empty structure to be used for starting up the monitor
 // health check and prometheus metrics http endpoints.
 type Monitor struct {
+	certs   *certReloader
 	config  *config.ButlerConfig
+	globals config.ConfigGlobals
+	lock    sync.Mutex
 	mux     *http.ServeMux
 	server  *http.Server
 	version string
@@ -62,73 +67,138 @@ type Output struct {
 
 // Start turns up the http server for monitoring butler.
 func (m *Monitor) Start() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if err := m.start(m.config.Config.Globals); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (m *Monitor) start(g config.ConfigGlobals) error {
 	var (
 		err      error
 		listener net.Listener
-		mux      *http.ServeMux
-		server   *http.Server
 	)
 	if m.mux == nil {
-		mux = http.DefaultServeMux
+		mux := http.NewServeMux()
 		mux.HandleFunc("/health-check", m.Handler)
 		mux.Handle("/metrics", promhttp.Handler())
+		mux.HandleFunc(AdminRunPath, m.AdminRunHandler)
+		mux.HandleFunc(AdminPlanPath, m.AdminPlanHandler)
+		mux.HandleFunc(AdminManagersPath, m.AdminManagersHandler)
+		// net/http/pprof registers itself with the default mux
+		mux.Handle("/debug/pprof/", http.DefaultServeMux)
 		m.mux = mux
 	}
 
-	if m.config.Config.Globals.EnableHTTPLog {
-		loggingHandler := alog.NewApacheLoggingHandler(mux, m.config)
-		server = &http.Server{
-			Handler: loggingHandler,
-		}
-	} else {
-		server = &http.Server{}
-	}
-	m.server = server
-	if m.config.Config.Globals.HTTPProto == "https" {
-		cer, err := tls.LoadX509KeyPair(m.config.Config.Globals.HTTPTLSCert, m.config.Config.Globals.HTTPTLSKey)
-		if err != nil {
-			log.Fatalf("Error loading ssl certificate/key data: %s", err.Error())
+	if g.HTTPProto == "https" {
+		// The certificate is served through the reloader, so that a rotated
+		// certificate is picked up without restarting butler.
+		certs, cerr := newCertReloader(g.HTTPTLSCert, g.HTTPTLSKey, g.HTTPTLSClientCA)
+		if cerr != nil {
+			return fmt.Errorf("Error loading ssl certificate/key data: %s", cerr.Error())
 		}
-		config := &tls.Config{Certificates: []tls.Certificate{cer}}
-		listener, err = tls.Listen("tcp", fmt.Sprintf(":%v", m.config.Config.Globals.HTTPPort), config)
-		if err != nil {
-			log.Fatalf("Error creating listener: %s", err.Error())
+		listener, err = tls.Listen("tcp", fmt.Sprintf(":%v", g.HTTPPort), certs.TLSConfig())
+		if err == nil {
+			m.certs = certs
+			go certs.watch(CertReloadInterval)
 		}
 	} else {
-		listener, err = net.Listen("tcp", fmt.Sprintf(":%v", m.config.Config.Globals.HTTPPort))
+		listener, err = net.Listen("tcp", fmt.Sprintf(":%v", g.HTTPPort))
+	}
+	if err != nil {
+		return fmt.Errorf("Error creating listener: %s", err.Error())
+	}
 
-		if err != nil {
-			log.Fatalf("Error creating listener: %s", err.Error())
-		}
+	// The logging handler checks the http log options on every request, so
+	// that they can be switched without restarting the webserver.
+	m.server = &http.Server{
+		Handler: alog.NewApacheLoggingHandler(m.mux, m.config),
 	}
-	go server.Serve(listener)
+	m.globals = g
+	go m.server.Serve(listener)
+	return nil
 }
 
 // Stop is to shut down the butler webserver used for the monitor and health
-// checking. This is really for testing purposes only.
+// checking. Requests in flight are given 5 seconds to complete.
 func (m *Monitor) Stop() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.stop()
+}
+
+func (m *Monitor) stop() error {
 	timeout := 5
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 	defer cancel()
+	if m.certs != nil {
+		m.certs.Stop()
+		m.certs = nil
+	}
 	if m.server != nil {
 		err := m.server.Shutdown(ctx)
 
 		if err != nil {
 			return err
 		}
+		// The logging handler holds on to the http-log-file, if there is one
+		if h, ok := m.server.Handler.(io.Closer); ok {
+			h.Close()
+		}
 	}
 	m.server = nil
 	return nil
 }
 
 // Update is used to update the butler configuration for the webserver.
-// It takes a butler configuration as the argumnt. It then set s the
-// Monitor.config with the argument. Finally "restarts" the webserver.
-// This is realy for testing purposes only.
+// It takes a butler configuration as the argument, and restarts the
+// webserver with its globals. Should the webserver not come up with the new
+// globals, eg: because the port is taken, it is brought back up with the
+// previous ones.
 func (m *Monitor) Update(bc *config.ButlerConfig) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	prev, running := m.globals, m.server != nil
 	m.config = bc
-	m.Stop()
-	m.Start()
+	if err := m.stop(); err != nil {
+		log.Warnf("Monitor::Update(): could not stop the webserver cleanly. err=%v", err)
+	}
+	if err := m.start(bc.Config.Globals); err != nil {
+		log.Errorf("Monitor::Update(): could not restart the webserver. err=%v", err)
+		if !running {
+			return
+		}
+		if err := m.start(prev); err != nil {
+			log.Errorf("Monitor::Update(): could not restart the webserver with the previous settings. err=%v", err)
+			return
+		}
+		log.Warnf("Monitor::Update(): webserver restarted with the previous settings on port %v.", prev.HTTPPort)
+		return
+	}
+	log.Infof("Monitor::Update(): webserver restarted. http-proto=%v http-port=%v", bc.Config.Globals.HTTPProto, bc.Config.Globals.HTTPPort)
+}
+
+// GlobalsChanged restarts the webserver when the globals it listens with
+// have changed. It is meant to be registered with
+// ButlerConfig.OnGlobalsChange.
+func (m *Monitor) GlobalsChanged(prev config.ConfigGlobals, cur config.ConfigGlobals) {
+	m.lock.Lock()
+	restart := m.server != nil && listenerChanged(m.globals, cur)
+	m.lock.Unlock()
+	if restart {
+		m.Update(m.config)
+	}
+}
+
+// listenerChanged tells whether the webserver has to be restarted to pick up
+// the change from a to b.
+func listenerChanged(a config.ConfigGlobals, b config.ConfigGlobals) bool {
+	return a.HTTPProto != b.HTTPProto ||
+		a.HTTPPort != b.HTTPPort ||
+		a.HTTPTLSCert != b.HTTPTLSCert ||
+		a.HTTPTLSKey != b.HTTPTLSKey ||
+		a.HTTPTLSClientCA != b.HTTPTLSClientCA
 }
 
 // Handler is the handler function for the /health-check monitor
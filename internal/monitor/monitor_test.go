@@ -19,6 +19,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	//"net/http/httptest"
 	"net/url"
@@ -154,3 +155,47 @@ func (s *ButlerTestSuite) TestStartHTTPs(c *C) {
 	buf.ReadFrom(resp.Body)
 	c.Assert(buf.String(), Matches, `.*"http-proto\":\"https\",\"http-port\":58532,.*`)
 }
+
+func (s *ButlerTestSuite) TestGlobalsChanged(c *C) {
+	u, err := url.Parse("http://localhost/butler.toml")
+	c.Assert(err, IsNil)
+	bc, err := config.NewButlerConfig(&config.ButlerConfigOpts{URL: u})
+	c.Assert(err, IsNil)
+	bc.Config = config.NewConfigSettings()
+	bc.Config.Globals.HTTPProto = "http"
+	bc.Config.Globals.HTTPPort = 58540
+	m := NewMonitor().WithOpts(&Opts{Config: bc, Version: "1.2.3"})
+	m.Start()
+	defer m.Stop()
+
+	get := func(port int) error {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%v/health-check", port))
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	c.Assert(get(58540), IsNil)
+
+	// Only the http logging changes, which does not need a restart
+	prev := bc.Config.Globals
+	bc.Config.Globals.EnableHTTPLog = true
+	m.GlobalsChanged(prev, bc.Config.Globals)
+	c.Assert(get(58540), IsNil)
+
+	// The monitor moves over to the new port
+	prev = bc.Config.Globals
+	bc.Config.Globals.HTTPPort = 58541
+	m.GlobalsChanged(prev, bc.Config.Globals)
+	c.Assert(get(58541), IsNil)
+	c.Assert(get(58540), NotNil)
+
+	// The monitor stays where it is when the new port is taken
+	l, err := net.Listen("tcp", ":58542")
+	c.Assert(err, IsNil)
+	defer l.Close()
+	prev = bc.Config.Globals
+	bc.Config.Globals.HTTPPort = 58542
+	m.GlobalsChanged(prev, bc.Config.Globals)
+	c.Assert(get(58541), IsNil)
+}
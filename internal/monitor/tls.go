@@ -0,0 +1,151 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package monitor
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CertReloadInterval is how often the monitor checks the certificate, key
+// and client CA files for changes.
+var CertReloadInterval = 30 * time.Second
+
+// certReloader serves the monitor certificate, and picks up the new one
+// whenever the certificate, key or client CA files change, eg: when
+// cert-manager rotates them. Should the new files not load, the previous
+// certificate is served until they do.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	lock     sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	data     []byte
+	done     chan struct{}
+}
+
+func newCertReloader(certFile string, keyFile string, caFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, caFile: caFile, done: make(chan struct{})}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns the tls configuration for the monitor listener. Client
+// certificates are required when there is a client CA.
+func (r *certReloader) TLSConfig() *tls.Config {
+	config := &tls.Config{GetCertificate: r.GetCertificate}
+	if r.caFile != "" {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.GetConfigForClient = r.GetConfigForClient
+	}
+	return config
+}
+
+// GetCertificate returns the current certificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.cert, nil
+}
+
+// GetConfigForClient returns the tls configuration with the current client CA.
+func (r *certReloader) GetConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return &tls.Config{
+		GetCertificate: r.GetCertificate,
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+		ClientCAs:      r.clientCA,
+	}, nil
+}
+
+// reload loads the files if they have changed since they were last loaded,
+// and tells whether they had.
+func (r *certReloader) reload() (bool, error) {
+	certPEM, err := os.ReadFile(r.certFile)
+	if err != nil {
+		return false, err
+	}
+	keyPEM, err := os.ReadFile(r.keyFile)
+	if err != nil {
+		return false, err
+	}
+	var caPEM []byte
+	if r.caFile != "" {
+		if caPEM, err = os.ReadFile(r.caFile); err != nil {
+			return false, err
+		}
+	}
+	data := bytes.Join([][]byte{certPEM, keyPEM, caPEM}, nil)
+
+	r.lock.RLock()
+	unchanged := bytes.Equal(data, r.data)
+	r.lock.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, err
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return false, fmt.Errorf("no certificates found in %v", r.caFile)
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cert = &cert
+	r.clientCA = pool
+	r.data = data
+	return true, nil
+}
+
+// watch checks the files for changes every interval, until Stop is called.
+func (r *certReloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			changed, err := r.reload()
+			if err != nil {
+				log.Errorf("Monitor::certReloader(): could not reload %v. Serving the previous certificate. err=%v", r.certFile, err)
+			} else if changed {
+				log.Infof("Monitor::certReloader(): reloaded %v.", r.certFile)
+			}
+		}
+	}
+}
+
+// Stop stops watching the files.
+func (r *certReloader) Stop() {
+	close(r.done)
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package monitor
+
+import (
+	. "gopkg.in/check.v1"
+
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/adobe/butler/internal/config"
+)
+
+type TLSTestSuite struct{}
+
+var _ = Suite(&TLSTestSuite{})
+
+// testCert returns a certificate for cn, signed by parent, or self signed if
+// parent is nil, along with its key.
+func testCert(c *C, cn string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	signer, signerKey := tmpl, interface{}(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	c.Assert(err, IsNil)
+	leaf, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func writeTestCert(c *C, cert tls.Certificate, certFile string, keyFile string) {
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	c.Assert(err, IsNil)
+	c.Assert(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0644), IsNil)
+	c.Assert(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600), IsNil)
+}
+
+func (s *TLSTestSuite) TestCertReloader(c *C) {
+	dir := c.MkDir()
+	certFile, keyFile := dir+"/tls.crt", dir+"/tls.key"
+	writeTestCert(c, testCert(c, "one", nil), certFile, keyFile)
+
+	r, err := newCertReloader(certFile, keyFile, "")
+	c.Assert(err, IsNil)
+	cert, err := r.GetCertificate(nil)
+	c.Assert(err, IsNil)
+	c.Assert(cert.Leaf.Subject.CommonName, Equals, "one")
+	changed, err := r.reload()
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, false)
+
+	writeTestCert(c, testCert(c, "two", nil), certFile, keyFile)
+	changed, err = r.reload()
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, true)
+	cert, _ = r.GetCertificate(nil)
+	c.Assert(cert.Leaf.Subject.CommonName, Equals, "two")
+
+	// A broken certificate leaves the previous one in place
+	c.Assert(os.WriteFile(certFile, []byte("broken"), 0644), IsNil)
+	_, err = r.reload()
+	c.Assert(err, NotNil)
+	cert, _ = r.GetCertificate(nil)
+	c.Assert(cert.Leaf.Subject.CommonName, Equals, "two")
+
+	_, err = newCertReloader(certFile, keyFile, "")
+	c.Assert(err, NotNil)
+	_, err = newCertReloader(dir+"/missing.crt", keyFile, "")
+	c.Assert(err, NotNil)
+}
+
+func testTLSMonitor(c *C, port int, certFile string, keyFile string, caFile string) *Monitor {
+	u, err := url.Parse("http://localhost/butler.toml")
+	c.Assert(err, IsNil)
+	bc, err := config.NewButlerConfig(&config.ButlerConfigOpts{URL: u})
+	c.Assert(err, IsNil)
+	bc.Config = config.NewConfigSettings()
+	bc.Config.Globals.HTTPProto = "https"
+	bc.Config.Globals.HTTPPort = port
+	bc.Config.Globals.HTTPTLSCert = certFile
+	bc.Config.Globals.HTTPTLSKey = keyFile
+	bc.Config.Globals.HTTPTLSClientCA = caFile
+	m := NewMonitor().WithOpts(&Opts{Config: bc, Version: "1.2.3"})
+	m.Start()
+	return m
+}
+
+func (s *TLSTestSuite) TestStartHTTPsCertRotation(c *C) {
+	interval := CertReloadInterval
+	CertReloadInterval = 10 * time.Millisecond
+	defer func() { CertReloadInterval = interval }()
+
+	dir := c.MkDir()
+	certFile, keyFile := dir+"/tls.crt", dir+"/tls.key"
+	writeTestCert(c, testCert(c, "one", nil), certFile, keyFile)
+	m := testTLSMonitor(c, 58550, certFile, keyFile, "")
+	defer m.Stop()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	}}
+	servedCN := func() string {
+		resp, err := client.Get("https://127.0.0.1:58550/health-check")
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	c.Assert(servedCN(), Equals, "one")
+
+	writeTestCert(c, testCert(c, "two", nil), certFile, keyFile)
+	for i := 0; i < 200 && servedCN() != "two"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(servedCN(), Equals, "two")
+}
+
+func (s *TLSTestSuite) TestStartHTTPsClientCA(c *C) {
+	dir := c.MkDir()
+	ca := testCert(c, "ca", nil)
+	writeTestCert(c, ca, dir+"/ca.crt", dir+"/ca.key")
+	writeTestCert(c, testCert(c, "server", &ca), dir+"/tls.crt", dir+"/tls.key")
+	m := testTLSMonitor(c, 58551, dir+"/tls.crt", dir+"/tls.key", dir+"/ca.crt")
+	defer m.Stop()
+
+	get := func(certs ...tls.Certificate) error {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: certs},
+		}}
+		resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%v/metrics", 58551))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	// No client certificate, or one from another CA, is turned away
+	c.Assert(get(), NotNil)
+	c.Assert(get(testCert(c, "stranger", nil)), NotNil)
+	c.Assert(get(testCert(c, "prometheus", &ca)), IsNil)
+}
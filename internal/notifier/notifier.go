@@ -0,0 +1,320 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+// Package notifier posts the events of butler, eg: changed files and failed
+// reloads, to webhooks.
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adobe/butler/internal/environment"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The events which are sent to the webhooks.
+const (
+	EventFilesChanged       = "files-changed"
+	EventReloadSuccess      = "reload-success"
+	EventReloadFailure      = "reload-failure"
+	EventRollback           = "rollback"
+	EventConfigParseFailure = "config-parse-failure"
+)
+
+// Events are all of the events, in the order they are documented in.
+var Events = []string{EventFilesChanged, EventReloadSuccess, EventReloadFailure, EventRollback, EventConfigParseFailure}
+
+// The types of webhooks.
+const (
+	TypeGeneric      = "generic"
+	TypeSlack        = "slack"
+	TypeAlertmanager = "alertmanager"
+)
+
+const defaultTimeout = 10
+
+// Event is something which happened to butler, or to one of its managers.
+type Event struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Host    string    `json:"host"`
+	Manager string    `json:"manager,omitempty"`
+	Files   []File    `json:"files,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// File is a configuration file, and the sha256 of its content. The hash is
+// empty for a file which has been removed.
+type File struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+}
+
+// Summary returns a one line description of the event.
+func (e Event) Summary() string {
+	var what string
+	switch e.Type {
+	case EventFilesChanged:
+		var files []string
+		for _, f := range e.Files {
+			files = append(files, f.Path)
+		}
+		what = fmt.Sprintf("files changed: %v", strings.Join(files, ", "))
+	case EventReloadSuccess:
+		what = "reloaded successfully"
+	case EventReloadFailure:
+		what = "reload failed"
+	case EventRollback:
+		what = "rolled back to the last known good configuration"
+	case EventConfigParseFailure:
+		what = "could not parse butler.toml"
+	default:
+		what = e.Type
+	}
+	if e.Manager != "" {
+		what = fmt.Sprintf("manager %v %v", e.Manager, what)
+	}
+	if e.Error != "" {
+		what = fmt.Sprintf("%v. err=%v", what, e.Error)
+	}
+	return fmt.Sprintf("butler on %v: %v", e.Host, what)
+}
+
+// Opts are the options of a webhook, which are configured as an array of
+// tables under [[globals.notifiers]].
+type Opts struct {
+	Name string `mapstructure:"name" json:"name"`
+	// Type is one of generic, slack or alertmanager.
+	Type string `mapstructure:"type" json:"type"`
+	// URL and Headers tend to hold credentials, so they are not shown.
+	URL     string            `mapstructure:"url" json:"-"`
+	Headers map[string]string `mapstructure:"headers" json:"-"`
+	// Events are the events to send. All of them are sent when it is empty.
+	Events  []string `mapstructure:"events" json:"events,omitempty"`
+	Timeout string   `mapstructure:"timeout" json:"timeout,omitempty"`
+}
+
+// Webhook posts events to a url, in the format of its type.
+type Webhook struct {
+	Name    string
+	Type    string
+	URL     string
+	Headers map[string]string
+	Events  map[string]bool
+	Client  *http.Client
+}
+
+// NewWebhook returns the Webhook for o. It returns an error if o is not valid.
+func NewWebhook(o Opts) (*Webhook, error) {
+	w := &Webhook{
+		Name:    o.Name,
+		Type:    strings.ToLower(environment.GetVar(o.Type)),
+		URL:     environment.GetVar(o.URL),
+		Headers: make(map[string]string),
+		Events:  make(map[string]bool),
+	}
+	if w.Type == "" {
+		w.Type = TypeGeneric
+	}
+	if w.Name == "" {
+		w.Name = w.Type
+	}
+
+	switch w.Type {
+	case TypeGeneric, TypeSlack, TypeAlertmanager:
+	default:
+		return nil, fmt.Errorf("notifier %v: unsupported type %v. It must be one of %v, %v or %v", w.Name, w.Type, TypeGeneric, TypeSlack, TypeAlertmanager)
+	}
+	if w.URL == "" {
+		return nil, fmt.Errorf("notifier %v: no url has been defined", w.Name)
+	}
+
+	for _, e := range o.Events {
+		e = strings.ToLower(e)
+		known := false
+		for _, k := range Events {
+			known = known || e == k
+		}
+		if !known {
+			return nil, fmt.Errorf("notifier %v: unknown event %v. It must be one of %v", w.Name, e, strings.Join(Events, ", "))
+		}
+		w.Events[e] = true
+	}
+
+	for k, v := range o.Headers {
+		w.Headers[k] = environment.GetVar(v)
+	}
+
+	timeout := defaultTimeout
+	if t := environment.GetVar(o.Timeout); t != "" {
+		var err error
+		timeout, err = strconv.Atoi(t)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("notifier %v: timeout %v is not a positive number of seconds", w.Name, o.Timeout)
+		}
+	}
+	w.Client = &http.Client{Timeout: time.Duration(timeout) * time.Second}
+	return w, nil
+}
+
+// Wants returns whether the webhook is interested in events of type t.
+func (w *Webhook) Wants(t string) bool {
+	return len(w.Events) == 0 || w.Events[t]
+}
+
+// Send posts the event to the webhook.
+func (w *Webhook) Send(ctx context.Context, e Event) error {
+	var payload interface{}
+	switch w.Type {
+	case TypeSlack:
+		payload = map[string]string{"text": e.Summary()}
+	case TypeAlertmanager:
+		payload = []alert{newAlert(e)}
+	default:
+		payload = e
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", w.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("received bad response from webhook. http_code=%d", resp.StatusCode)
+	}
+	return nil
+}
+
+// alert is an alert of the Alertmanager v2 API.
+type alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+}
+
+func newAlert(e Event) alert {
+	severity := "info"
+	switch e.Type {
+	case EventReloadFailure, EventRollback, EventConfigParseFailure:
+		severity = "warning"
+	}
+	a := alert{
+		Labels:      map[string]string{"alertname": "ButlerEvent", "event": e.Type, "instance": e.Host, "severity": severity},
+		Annotations: map[string]string{"summary": e.Summary()},
+		StartsAt:    e.Time,
+	}
+	if e.Manager != "" {
+		a.Labels["manager"] = e.Manager
+	}
+	if e.Error != "" {
+		a.Annotations["error"] = e.Error
+	}
+	return a
+}
+
+// Notifier sends events to all of its webhooks. The events are sent in the
+// background, so that a slow webhook does not hold up butler, but each
+// webhook gets them in the order they happened.
+type Notifier struct {
+	Webhooks []*Webhook
+	host     string
+	lock     sync.Mutex
+	sent     map[*Webhook]chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New returns the Notifier for the webhooks in opts, or nil if there are
+// none.
+func New(opts []Opts) (*Notifier, error) {
+	if len(opts) == 0 {
+		return nil, nil
+	}
+	n := &Notifier{sent: make(map[*Webhook]chan struct{})}
+	n.host, _ = os.Hostname()
+	for _, o := range opts {
+		w, err := NewWebhook(o)
+		if err != nil {
+			return nil, err
+		}
+		n.Webhooks = append(n.Webhooks, w)
+	}
+	return n, nil
+}
+
+// Notify sends e to the webhooks which want it. It is safe to call on a nil
+// Notifier, which does nothing.
+func (n *Notifier) Notify(e Event) {
+	if n == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Host == "" {
+		e.Host = n.host
+	}
+	for _, w := range n.Webhooks {
+		if !w.Wants(e.Type) {
+			continue
+		}
+		// Each event is sent once the previous one to the webhook is done
+		n.lock.Lock()
+		prev, done := n.sent[w], make(chan struct{})
+		n.sent[w] = done
+		n.lock.Unlock()
+		n.wg.Add(1)
+		go func(w *Webhook) {
+			defer n.wg.Done()
+			defer close(done)
+			if prev != nil {
+				<-prev
+			}
+			if err := w.Send(context.Background(), e); err != nil {
+				log.WithField("notifier", w.Name).Errorf("Notifier::Notify(): could not send %v event. err=%v", e.Type, err)
+				return
+			}
+			log.WithField("notifier", w.Name).Debugf("Notifier::Notify(): sent %v event.", e.Type)
+		}(w)
+	}
+}
+
+// Wait waits for the events in flight to be sent.
+func (n *Notifier) Wait() {
+	if n == nil {
+		return
+	}
+	n.wg.Wait()
+}
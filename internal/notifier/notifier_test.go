@@ -0,0 +1,201 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&NotifierTestSuite{})
+
+type NotifierTestSuite struct{}
+
+// webhookServer records the requests it receives.
+type webhookServer struct {
+	server  *httptest.Server
+	lock    sync.Mutex
+	bodies  []string
+	headers []http.Header
+	status  int
+}
+
+func newWebhookServer() *webhookServer {
+	s := &webhookServer{status: http.StatusOK}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.bodies = append(s.bodies, string(body))
+		s.headers = append(s.headers, r.Header)
+		w.WriteHeader(s.status)
+	}))
+	return s
+}
+
+var testEvent = Event{
+	Type:    EventReloadFailure,
+	Time:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	Host:    "butler-0",
+	Manager: "prometheus",
+	Error:   "received bad response from server. code=500",
+}
+
+func (s *NotifierTestSuite) TestNewWebhook(c *C) {
+	os.Setenv("BUTLER_NOTIFIER_TEST_URL", "http://localhost/hook")
+	defer os.Unsetenv("BUTLER_NOTIFIER_TEST_URL")
+
+	w, err := NewWebhook(Opts{URL: "env:BUTLER_NOTIFIER_TEST_URL", Events: []string{"Rollback"}})
+	c.Assert(err, IsNil)
+	c.Assert(w.Type, Equals, TypeGeneric)
+	c.Assert(w.URL, Equals, "http://localhost/hook")
+	c.Assert(w.Client.Timeout, Equals, 10*time.Second)
+	c.Assert(w.Wants(EventRollback), Equals, true)
+	c.Assert(w.Wants(EventReloadSuccess), Equals, false)
+
+	w, err = NewWebhook(Opts{Type: "slack", URL: "http://localhost/hook", Timeout: "3"})
+	c.Assert(err, IsNil)
+	c.Assert(w.Name, Equals, "slack")
+	c.Assert(w.Client.Timeout, Equals, 3*time.Second)
+	c.Assert(w.Wants(EventReloadSuccess), Equals, true)
+
+	for _, t := range []struct {
+		opts Opts
+		err  string
+	}{
+		{Opts{Name: "ops", Type: "pagerduty", URL: "http://localhost"}, "notifier ops: unsupported type pagerduty.*"},
+		{Opts{Name: "ops"}, "notifier ops: no url has been defined"},
+		{Opts{Name: "ops", URL: "http://localhost", Events: []string{"reloaded"}}, "notifier ops: unknown event reloaded.*"},
+		{Opts{Name: "ops", URL: "http://localhost", Timeout: "soon"}, "notifier ops: timeout soon is not a positive number of seconds"},
+	} {
+		_, err := NewWebhook(t.opts)
+		c.Assert(err, ErrorMatches, t.err)
+	}
+}
+
+func (s *NotifierTestSuite) TestEventSummary(c *C) {
+	c.Assert(testEvent.Summary(), Equals, "butler on butler-0: manager prometheus reload failed. err=received bad response from server. code=500")
+	e := Event{Type: EventFilesChanged, Host: "butler-0", Manager: "prometheus", Files: []File{{Path: "/etc/prometheus/prometheus.yml"}, {Path: "/etc/prometheus/rules.yml"}}}
+	c.Assert(e.Summary(), Equals, "butler on butler-0: manager prometheus files changed: /etc/prometheus/prometheus.yml, /etc/prometheus/rules.yml")
+	e = Event{Type: EventConfigParseFailure, Host: "butler-0", Error: "bad toml"}
+	c.Assert(e.Summary(), Equals, "butler on butler-0: could not parse butler.toml. err=bad toml")
+}
+
+func (s *NotifierTestSuite) TestSendGeneric(c *C) {
+	server := newWebhookServer()
+	defer server.server.Close()
+
+	w, err := NewWebhook(Opts{URL: server.server.URL, Headers: map[string]string{"x-token": "secret"}})
+	c.Assert(err, IsNil)
+	c.Assert(w.Send(context.Background(), testEvent), IsNil)
+	c.Assert(server.bodies, HasLen, 1)
+	c.Assert(server.bodies[0], Equals, `{"type":"reload-failure","time":"2026-01-02T03:04:05Z","host":"butler-0","manager":"prometheus","error":"received bad response from server. code=500"}`)
+	c.Assert(server.headers[0].Get("X-Token"), Equals, "secret")
+	c.Assert(server.headers[0].Get("Content-Type"), Equals, "application/json")
+
+	server.status = http.StatusBadGateway
+	c.Assert(w.Send(context.Background(), testEvent), ErrorMatches, "received bad response from webhook. http_code=502")
+}
+
+func (s *NotifierTestSuite) TestSendSlack(c *C) {
+	server := newWebhookServer()
+	defer server.server.Close()
+
+	w, err := NewWebhook(Opts{Type: TypeSlack, URL: server.server.URL})
+	c.Assert(err, IsNil)
+	c.Assert(w.Send(context.Background(), testEvent), IsNil)
+	c.Assert(server.bodies[0], Equals, `{"text":"butler on butler-0: manager prometheus reload failed. err=received bad response from server. code=500"}`)
+}
+
+func (s *NotifierTestSuite) TestSendAlertmanager(c *C) {
+	server := newWebhookServer()
+	defer server.server.Close()
+
+	w, err := NewWebhook(Opts{Type: TypeAlertmanager, URL: server.server.URL})
+	c.Assert(err, IsNil)
+	c.Assert(w.Send(context.Background(), testEvent), IsNil)
+
+	var alerts []alert
+	c.Assert(json.Unmarshal([]byte(server.bodies[0]), &alerts), IsNil)
+	c.Assert(alerts, HasLen, 1)
+	c.Assert(alerts[0].Labels, DeepEquals, map[string]string{
+		"alertname": "ButlerEvent",
+		"event":     EventReloadFailure,
+		"instance":  "butler-0",
+		"manager":   "prometheus",
+		"severity":  "warning",
+	})
+	c.Assert(alerts[0].Annotations["error"], Equals, testEvent.Error)
+	c.Assert(alerts[0].StartsAt.Equal(testEvent.Time), Equals, true)
+}
+
+func (s *NotifierTestSuite) TestNotify(c *C) {
+	all, rollbacks := newWebhookServer(), newWebhookServer()
+	defer all.server.Close()
+	defer rollbacks.server.Close()
+
+	n, err := New(nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, IsNil)
+	// A nil notifier does nothing
+	n.Notify(testEvent)
+	n.Wait()
+
+	_, err = New([]Opts{{Name: "ops"}})
+	c.Assert(err, NotNil)
+
+	n, err = New([]Opts{{URL: all.server.URL}, {URL: rollbacks.server.URL, Events: []string{EventRollback}}})
+	c.Assert(err, IsNil)
+	n.Notify(Event{Type: EventReloadSuccess, Manager: "prometheus"})
+	n.Notify(Event{Type: EventRollback, Manager: "prometheus"})
+	n.Wait()
+	c.Assert(all.bodies, HasLen, 2)
+	c.Assert(rollbacks.bodies, HasLen, 1)
+
+	var e Event
+	c.Assert(json.Unmarshal([]byte(rollbacks.bodies[0]), &e), IsNil)
+	c.Assert(e.Type, Equals, EventRollback)
+	c.Assert(e.Time.IsZero(), Equals, false)
+	hostname, _ := os.Hostname()
+	c.Assert(e.Host, Equals, hostname)
+}
+
+func (s *NotifierTestSuite) TestNotifyInOrder(c *C) {
+	ws := newWebhookServer()
+	defer ws.server.Close()
+
+	n, err := New([]Opts{{URL: ws.server.URL}})
+	c.Assert(err, IsNil)
+	for i := 0; i < 20; i++ {
+		n.Notify(Event{Type: EventFilesChanged, Manager: fmt.Sprintf("manager-%d", i)})
+	}
+	n.Wait()
+	c.Assert(ws.bodies, HasLen, 20)
+	for i, body := range ws.bodies {
+		var e Event
+		c.Assert(json.Unmarshal([]byte(body), &e), IsNil)
+		c.Assert(e.Manager, Equals, fmt.Sprintf("manager-%d", i))
+	}
+}
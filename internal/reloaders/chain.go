@@ -0,0 +1,195 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package reloaders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adobe/butler/internal/environment"
+)
+
+// The on-failure policies of a reloader chain step.
+const (
+	// OnFailureAbort stops the chain, and fails the reload, which rolls the
+	// configuration back.
+	OnFailureAbort = "abort-and-rollback"
+	// OnFailureContinue runs the remaining steps, and then fails the reload.
+	OnFailureContinue = "continue"
+	// OnFailureIgnore carries on as if the step had succeeded.
+	OnFailureIgnore = "ignore"
+)
+
+func NewChainReloader(manager string, method string, entry []byte) (Reloader, error) {
+	var (
+		result ChainReloader
+		opts   struct {
+			Steps []map[string]json.RawMessage `json:"steps"`
+		}
+	)
+
+	err := json.Unmarshal(entry, &opts)
+	if err != nil {
+		return result, err
+	}
+	if len(opts.Steps) == 0 {
+		return result, errors.New("the reloader chain has no steps")
+	}
+
+	for i, raw := range opts.Steps {
+		var step ChainStep
+		for k, v := range map[string]*string{"name": &step.Name, "method": &step.Method, "on-failure": &step.OnFailure, "timeout": &step.Timeout} {
+			if raw[k] == nil {
+				continue
+			}
+			if err := json.Unmarshal(raw[k], v); err != nil {
+				return result, fmt.Errorf("step %d: could not parse %v. err=%v", i+1, k, err)
+			}
+		}
+		step.Method = strings.ToLower(step.Method)
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("%d", i+1)
+		}
+
+		step.OnFailure = strings.ToLower(environment.GetVar(step.OnFailure))
+		switch step.OnFailure {
+		case "":
+			step.OnFailure = OnFailureAbort
+		case OnFailureAbort, OnFailureContinue, OnFailureIgnore:
+		default:
+			return result, fmt.Errorf("step %v: unsupported on-failure %v. It must be one of %v, %v or %v", step.Name, step.OnFailure, OnFailureAbort, OnFailureContinue, OnFailureIgnore)
+		}
+
+		if t := environment.GetVar(step.Timeout); t != "" {
+			seconds, err := strconv.Atoi(t)
+			if err != nil || seconds <= 0 {
+				return result, fmt.Errorf("step %v: timeout %v is not a positive number of seconds", step.Name, step.Timeout)
+			}
+			step.timeout = time.Duration(seconds) * time.Second
+		}
+
+		switch {
+		case step.Method == "":
+			return result, fmt.Errorf("step %v: no method has been defined", step.Name)
+		case step.Method == "chain":
+			return result, fmt.Errorf("step %v: a reloader chain cannot be nested", step.Name)
+		case raw[step.Method] == nil:
+			return result, fmt.Errorf("step %v: no reloader configuration has been defined for method %v", step.Name, step.Method)
+		}
+		step.Reloader, err = newReloader(manager, step.Method, raw[step.Method])
+		if err != nil {
+			return result, fmt.Errorf("step %v: %v", step.Name, err)
+		}
+		result.Opts.Steps = append(result.Opts.Steps, step)
+	}
+
+	result.Method = method
+	result.Manager = manager
+	return result, nil
+}
+
+// ChainReloader runs a list of reloaders in order, eg: to validate the
+// configuration, reload the application, and then poke a sidecar.
+type ChainReloader struct {
+	Manager string            `json:"-"`
+	Counter int               `json:"-"`
+	Method  string            `json:"method"`
+	Opts    ChainReloaderOpts `json:"opts"`
+}
+
+type ChainReloaderOpts struct {
+	Steps []ChainStep `json:"steps"`
+}
+
+// ChainStep is a step of a reloader chain. The options of the reloader go
+// under the name of its method, as they would for a single reloader.
+type ChainStep struct {
+	Name      string   `json:"name"`
+	Method    string   `json:"method"`
+	OnFailure string   `json:"on-failure"`
+	Timeout   string   `json:"timeout,omitempty"`
+	Reloader  Reloader `json:"reloader"`
+	timeout   time.Duration
+}
+
+func (r ChainReloader) Reload(ctx context.Context) error {
+	var failed *ReloaderError
+	for i, step := range r.Opts.Steps {
+		logEntry(r.Counter, r.Manager, "chain").Debugf("ChainReloader::Reload(): running step %v (%v).", step.Name, step.Method)
+		err := step.reload(ctx)
+		if err == nil {
+			continue
+		}
+
+		rerr := toReloaderError(err).WithStep(fmt.Sprintf("%d/%d (%v)", i+1, len(r.Opts.Steps), step.Name))
+		switch step.OnFailure {
+		case OnFailureIgnore:
+			logEntry(r.Counter, r.Manager, "chain").Warnf("ChainReloader::Reload(): ignoring failure. err=%v", rerr)
+		case OnFailureContinue:
+			logEntry(r.Counter, r.Manager, "chain").Errorf("ChainReloader::Reload(): continuing with the next step. err=%v", rerr)
+			if failed == nil {
+				failed = rerr
+			}
+		default:
+			logEntry(r.Counter, r.Manager, "chain").Errorf("ChainReloader::Reload(): aborting the chain. err=%v", rerr)
+			return rerr
+		}
+		// Steps after a failure are still abandoned when butler shuts down.
+		if ctx.Err() != nil {
+			return toReloaderError(ctx.Err()).WithStep(fmt.Sprintf("%d/%d (%v)", i+1, len(r.Opts.Steps), step.Name))
+		}
+	}
+	if failed != nil {
+		return failed
+	}
+	return nil
+}
+
+func (s ChainStep) reload(ctx context.Context) error {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	return s.Reloader.Reload(ctx)
+}
+
+func (r ChainReloader) GetMethod() string {
+	return r.Method
+}
+
+func (r ChainReloader) GetOpts() ReloaderOpts {
+	return r.Opts
+}
+
+func (r ChainReloader) SetOpts(opts ReloaderOpts) bool {
+	r.Opts = opts.(ChainReloaderOpts)
+	return true
+}
+
+// SetCounter sets the counter of the chain, and of each of its steps.
+func (r ChainReloader) SetCounter(c int) Reloader {
+	r.Counter = c
+	steps := make([]ChainStep, len(r.Opts.Steps))
+	for i, step := range r.Opts.Steps {
+		step.Reloader = step.Reloader.SetCounter(c)
+		steps[i] = step
+	}
+	r.Opts.Steps = steps
+	return r
+}
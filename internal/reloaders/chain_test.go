@@ -0,0 +1,197 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package reloaders
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	. "gopkg.in/check.v1"
+)
+
+// chainServer records the paths it has been called on. It fails on /fail, and
+// is slow on /slow.
+type chainServer struct {
+	server *httptest.Server
+	lock   sync.Mutex
+	calls  []string
+}
+
+func newChainServer() *chainServer {
+	s := &chainServer{}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		s.calls = append(s.calls, r.URL.Path)
+		s.lock.Unlock()
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusBadRequest)
+		case "/slow":
+			time.Sleep(2 * time.Second)
+		}
+	}))
+	return s
+}
+
+// step returns the json of a chain step, which calls uri on the server.
+func (s *chainServer) step(name string, onFailure string, timeout string, uri string) string {
+	return fmt.Sprintf(`{"name": %q, "method": "http", "on-failure": %q, "timeout": %q,
+		"http": {"host": "127.0.0.1", "port": "%d", "uri": %q, "method": "post", "timeout": "10", "retries": "0"}}`,
+		name, onFailure, timeout, getPortFromURL(s.server.URL), uri)
+}
+
+func (s *chainServer) reloader(c *C, steps ...string) Reloader {
+	reloader, err := NewChainReloader("test-manager", "chain", []byte(`{"steps": [`+strings.Join(steps, ",")+`]}`))
+	c.Assert(err, IsNil)
+	return reloader
+}
+
+func (s *ReloaderTestSuite) TestNewChainReloader(c *C) {
+	server := newChainServer()
+	defer server.server.Close()
+
+	reloader := server.reloader(c, server.step("validate", "", "5", "/validate"), server.step("", "ignore", "", "/reload"))
+	c.Assert(reloader.GetMethod(), Equals, "chain")
+	steps := reloader.GetOpts().(ChainReloaderOpts).Steps
+	c.Assert(steps, HasLen, 2)
+	c.Assert(steps[0].Name, Equals, "validate")
+	c.Assert(steps[0].OnFailure, Equals, OnFailureAbort)
+	c.Assert(steps[0].timeout, Equals, 5*time.Second)
+	c.Assert(steps[0].Reloader.GetMethod(), Equals, "http")
+	c.Assert(steps[1].Name, Equals, "2")
+	c.Assert(steps[1].OnFailure, Equals, OnFailureIgnore)
+	c.Assert(steps[1].timeout, Equals, time.Duration(0))
+
+	reloader = reloader.SetCounter(3)
+	c.Assert(reloader.(ChainReloader).Counter, Equals, 3)
+	c.Assert(reloader.GetOpts().(ChainReloaderOpts).Steps[1].Reloader.(HTTPReloader).Counter, Equals, 3)
+}
+
+func (s *ReloaderTestSuite) TestNewChainReloaderErrors(c *C) {
+	for _, t := range []struct{ entry, err string }{
+		{`{`, ".*unexpected end of JSON input.*"},
+		{`{"steps": []}`, "the reloader chain has no steps"},
+		{`{"steps": [{"method": "http", "on-failure": "retry", "http": {}}]}`, "step 1: unsupported on-failure retry.*"},
+		{`{"steps": [{"method": "http", "timeout": "soon", "http": {}}]}`, "step 1: timeout soon is not a positive number of seconds"},
+		{`{"steps": [{"name": "nested", "method": "chain", "chain": {}}]}`, "step nested: a reloader chain cannot be nested"},
+		{`{"steps": [{"method": "http"}]}`, "step 1: no reloader configuration has been defined for method http"},
+		{`{"steps": [{"http": {}}]}`, "step 1: no method has been defined"},
+		{`{"steps": [{"method": "docker", "docker": {"action": "signal"}}]}`, "step 1: the docker reloader needs exactly one of container and label"},
+	} {
+		_, err := NewChainReloader("test-manager", "chain", []byte(t.entry))
+		c.Assert(err, ErrorMatches, t.err, Commentf("entry=%v", t.entry))
+	}
+}
+
+func (s *ReloaderTestSuite) TestNewChainReloaderFromConfig(c *C) {
+	v := viper.New()
+	v.SetConfigType("toml")
+	c.Assert(v.ReadConfig(bytes.NewBufferString(`[test-manager.reloader]
+  method = "chain"
+  [[test-manager.reloader.chain.steps]]
+    name = "validate"
+    method = "docker"
+    timeout = "10"
+    [test-manager.reloader.chain.steps.docker]
+      container = "prometheus"
+      action = "exec"
+      command = ["promtool", "check", "config", "/etc/prometheus/prometheus.yml"]
+  [[test-manager.reloader.chain.steps]]
+    name = "reload"
+    method = "http"
+    on-failure = "continue"
+    http = { host = "localhost", port = "9090", uri = "/-/reload", method = "post" }
+`)), IsNil)
+	reloader, err := New(v, "test-manager")
+	c.Assert(err, IsNil)
+	steps := reloader.GetOpts().(ChainReloaderOpts).Steps
+	c.Assert(steps, HasLen, 2)
+	c.Assert(steps[0].Reloader.GetOpts().(DockerReloaderOpts).Command, DeepEquals, []string{"promtool", "check", "config", "/etc/prometheus/prometheus.yml"})
+	c.Assert(steps[1].OnFailure, Equals, OnFailureContinue)
+	c.Assert(steps[1].Reloader.GetOpts().(HTTPReloaderOpts).URI, Equals, "/-/reload")
+}
+
+func (s *ReloaderTestSuite) TestChainReloaderReload(c *C) {
+	server := newChainServer()
+	defer server.server.Close()
+
+	reloader := server.reloader(c, server.step("one", "", "", "/one"), server.step("two", "", "", "/two"), server.step("three", "", "", "/three"))
+	c.Assert(reloader.Reload(context.Background()), IsNil)
+	c.Assert(server.calls, DeepEquals, []string{"/one", "/two", "/three"})
+}
+
+func (s *ReloaderTestSuite) TestChainReloaderReloadAbort(c *C) {
+	server := newChainServer()
+	defer server.server.Close()
+
+	reloader := server.reloader(c, server.step("one", "", "", "/one"), server.step("validate", OnFailureAbort, "", "/fail"), server.step("three", "", "", "/three"))
+	err := reloader.Reload(context.Background())
+	var rerr *ReloaderError
+	c.Assert(errors.As(err, &rerr), Equals, true)
+	c.Assert(rerr.Step, Equals, "2/3 (validate)")
+	c.Assert(rerr.Code, Equals, http.StatusBadRequest)
+	c.Assert(err, ErrorMatches, "step 2/3 \\(validate\\) failed: .*code=400")
+	c.Assert(server.calls, DeepEquals, []string{"/one", "/fail"})
+}
+
+func (s *ReloaderTestSuite) TestChainReloaderReloadContinue(c *C) {
+	server := newChainServer()
+	defer server.server.Close()
+
+	reloader := server.reloader(c, server.step("one", OnFailureContinue, "", "/fail"), server.step("two", OnFailureContinue, "", "/fail"), server.step("three", "", "", "/three"))
+	err := reloader.Reload(context.Background())
+	c.Assert(err, NotNil)
+	c.Assert(err.(*ReloaderError).Step, Equals, "1/3 (one)")
+	c.Assert(server.calls, DeepEquals, []string{"/fail", "/fail", "/three"})
+}
+
+func (s *ReloaderTestSuite) TestChainReloaderReloadIgnore(c *C) {
+	server := newChainServer()
+	defer server.server.Close()
+
+	reloader := server.reloader(c, server.step("one", OnFailureIgnore, "", "/fail"), server.step("two", "", "", "/two"))
+	c.Assert(reloader.Reload(context.Background()), IsNil)
+	c.Assert(server.calls, DeepEquals, []string{"/fail", "/two"})
+}
+
+func (s *ReloaderTestSuite) TestChainReloaderReloadTimeout(c *C) {
+	server := newChainServer()
+	defer server.server.Close()
+
+	reloader := server.reloader(c, server.step("slow", OnFailureIgnore, "1", "/slow"), server.step("two", "", "1", "/slow"))
+	start := time.Now()
+	err := reloader.Reload(context.Background())
+	c.Assert(time.Since(start) < 3*time.Second, Equals, true)
+	c.Assert(err, NotNil)
+	c.Assert(err.(*ReloaderError).Step, Equals, "2/2 (two)")
+}
+
+func (s *ReloaderTestSuite) TestChainReloaderReloadExec(c *C) {
+	server := newChainServer()
+	defer server.server.Close()
+
+	validate := `{"name": "validate", "method": "exec", "exec": {"command": ["false"]}}`
+	reloader := server.reloader(c, validate, server.step("reload", "", "", "/reload"))
+	err := reloader.Reload(context.Background())
+	c.Assert(err, ErrorMatches, "step 1/2 \\(validate\\) failed: false exited with 1. code=1")
+	c.Assert(err.(*ReloaderError).IsTimeout(), Equals, false)
+	c.Assert(server.calls, HasLen, 0)
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package reloaders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adobe/butler/internal/environment"
+	"github.com/adobe/butler/internal/methods"
+)
+
+const (
+	defaultDockerSocket  = "/var/run/docker.sock"
+	defaultDockerSignal  = "SIGHUP"
+	defaultDockerTimeout = 30
+)
+
+// DockerExecPollInterval is how often the docker reloader checks whether an
+// exec has finished.
+var DockerExecPollInterval = 100 * time.Millisecond
+
+func NewDockerReloader(manager string, method string, entry []byte) (Reloader, error) {
+	var (
+		err    error
+		result DockerReloader
+		opts   DockerReloaderOpts
+	)
+
+	err = json.Unmarshal(entry, &opts)
+	if err != nil {
+		return result, err
+	}
+
+	opts.Socket = environment.GetVar(opts.Socket)
+	if opts.Socket == "" {
+		opts.Socket = defaultDockerSocket
+	}
+	opts.Container = environment.GetVar(opts.Container)
+	opts.Label = environment.GetVar(opts.Label)
+	if (opts.Container == "") == (opts.Label == "") {
+		return result, errors.New("the docker reloader needs exactly one of container and label")
+	}
+	opts.Action = strings.ToLower(environment.GetVar(opts.Action))
+	switch opts.Action {
+	case "signal":
+		opts.Signal = environment.GetVar(opts.Signal)
+		if opts.Signal == "" {
+			opts.Signal = defaultDockerSignal
+		}
+	case "restart":
+	case "exec":
+		if len(opts.Command) == 0 {
+			return result, errors.New("the docker reloader needs a command to exec")
+		}
+	default:
+		return result, fmt.Errorf("unsupported docker reloader action %v. It must be one of signal, restart or exec", opts.Action)
+	}
+
+	newTimeout, _ := strconv.Atoi(environment.GetVar(opts.Timeout))
+	if newTimeout == 0 {
+		newTimeout = defaultDockerTimeout
+	}
+	opts.Client = &http.Client{
+		Timeout:   time.Duration(newTimeout) * time.Second,
+		Transport: &http.Transport{DialContext: methods.UnixSocketDialer(opts.Socket)},
+	}
+
+	result.Method = method
+	result.Opts = opts
+	result.Manager = manager
+	return result, err
+}
+
+// DockerReloader reloads a manager which runs in a container, through the
+// Docker Engine API.
+type DockerReloader struct {
+	Manager string             `json:"-"`
+	Counter int                `json:"-"`
+	Method  string             `json:"method"`
+	Opts    DockerReloaderOpts `json:"opts"`
+}
+
+type DockerReloaderOpts struct {
+	Client *http.Client `json:"-"`
+	// Socket is the docker daemon socket.
+	Socket string `json:"socket,omitempty"`
+	// Container is the name or id of the container to reload. Label
+	// selects the running containers to reload by label instead, eg:
+	// "com.example.role=prometheus".
+	Container string `json:"container,omitempty"`
+	Label     string `json:"label,omitempty"`
+	// Action is one of signal, restart or exec.
+	Action  string   `json:"action"`
+	Signal  string   `json:"signal,omitempty"`
+	Command []string `json:"command,omitempty"`
+	Timeout string   `json:"timeout,omitempty"`
+}
+
+func (d DockerReloader) Reload(ctx context.Context) error {
+	logEntry(d.Counter, d.Manager, "docker").Debug("DockerReloader::Reload(): reloading manager using docker")
+	o := d.Opts
+	containers, err := d.containers(ctx)
+	if err != nil {
+		logEntry(d.Counter, d.Manager, "docker").Errorf("DockerReloader::Reload(): could not find the containers to reload. err=%v", err)
+		return toReloaderError(err)
+	}
+
+	for _, c := range containers {
+		switch o.Action {
+		case "signal":
+			err = d.call(ctx, "POST", fmt.Sprintf("/containers/%s/kill?signal=%s", c, url.QueryEscape(o.Signal)), nil, nil)
+		case "restart":
+			err = d.call(ctx, "POST", fmt.Sprintf("/containers/%s/restart", c), nil, nil)
+		case "exec":
+			err = d.exec(ctx, c)
+		}
+		if err != nil {
+			logEntry(d.Counter, d.Manager, "docker").Errorf("DockerReloader::Reload(): could not %v container %v. err=%v", o.Action, c, err)
+			return toReloaderError(err)
+		}
+		logEntry(d.Counter, d.Manager, "docker").Infof("DockerReloader::Reload(): successfully reloaded container %v using %v.", c, o.Action)
+	}
+	return nil
+}
+
+// containers returns the ids of the containers to reload.
+func (d DockerReloader) containers(ctx context.Context) ([]string, error) {
+	var res []string
+	if d.Opts.Container != "" {
+		var container struct {
+			ID string `json:"Id"`
+		}
+		err := d.call(ctx, "GET", fmt.Sprintf("/containers/%s/json", url.PathEscape(d.Opts.Container)), nil, &container)
+		if err != nil {
+			return res, err
+		}
+		return append(res, container.ID), nil
+	}
+
+	filters, err := json.Marshal(map[string][]string{"label": {d.Opts.Label}})
+	if err != nil {
+		return res, err
+	}
+	var containers []struct {
+		ID string `json:"Id"`
+	}
+	err = d.call(ctx, "GET", "/containers/json?filters="+url.QueryEscape(string(filters)), nil, &containers)
+	if err != nil {
+		return res, err
+	}
+	if len(containers) == 0 {
+		return res, NewReloaderError().WithMessage(fmt.Sprintf("no running container has the label %v", d.Opts.Label)).WithCode(http.StatusNotFound)
+	}
+	for _, c := range containers {
+		res = append(res, c.ID)
+	}
+	return res, nil
+}
+
+// exec runs the command in container, and waits for it to finish.
+func (d DockerReloader) exec(ctx context.Context, container string) error {
+	var exec struct {
+		ID string `json:"Id"`
+	}
+	err := d.call(ctx, "POST", fmt.Sprintf("/containers/%s/exec", container), map[string]interface{}{"Cmd": d.Opts.Command}, &exec)
+	if err != nil {
+		return err
+	}
+	err = d.call(ctx, "POST", fmt.Sprintf("/exec/%s/start", exec.ID), map[string]interface{}{"Detach": true}, nil)
+	if err != nil {
+		return err
+	}
+
+	for {
+		var state struct {
+			Running  bool
+			ExitCode int
+		}
+		err = d.call(ctx, "GET", fmt.Sprintf("/exec/%s/json", exec.ID), nil, &state)
+		if err != nil {
+			return err
+		}
+		if !state.Running {
+			if state.ExitCode != 0 {
+				return NewReloaderError().WithMessage(fmt.Sprintf("%v exited with %v", strings.Join(d.Opts.Command, " "), state.ExitCode)).WithExitCode(state.ExitCode)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(DockerExecPollInterval):
+		}
+	}
+}
+
+// call sends a request to the docker daemon, and decodes the response into
+// out. API errors are returned as a ReloaderError with the http status code.
+func (d DockerReloader) call(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	// The host is not used, as we always talk to the socket.
+	req, err := http.NewRequestWithContext(ctx, method, "http://docker"+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := d.Opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = "received bad response from docker"
+		}
+		return NewReloaderError().WithMessage(apiErr.Message).WithCode(resp.StatusCode)
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+func (d DockerReloader) GetMethod() string {
+	return d.Method
+}
+
+func (d DockerReloader) GetOpts() ReloaderOpts {
+	return d.Opts
+}
+
+func (d DockerReloader) SetOpts(opts ReloaderOpts) bool {
+	d.Opts = opts.(DockerReloaderOpts)
+	return true
+}
+
+func (d DockerReloader) SetCounter(c int) Reloader {
+	d.Counter = c
+	return d
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package reloaders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	. "gopkg.in/check.v1"
+)
+
+// fakeDocker is a small part of the Docker Engine API, listening on a unix
+// socket. It knows of the running containers prometheus (abc123) and
+// prometheus-2 (def456), which are labelled role=prometheus, and of the
+// stopped container grafana.
+type fakeDocker struct {
+	server *httptest.Server
+	socket string
+	lock   sync.Mutex
+	calls  []string
+	polls  int
+}
+
+func newFakeDocker(c *C) *fakeDocker {
+	d := &fakeDocker{socket: c.MkDir() + "/docker.sock"}
+	l, err := net.Listen("unix", d.socket)
+	c.Assert(err, IsNil)
+	d.server = httptest.NewUnstartedServer(http.HandlerFunc(d.serve))
+	d.server.Listener = l
+	d.server.Start()
+	return d
+}
+
+func (d *fakeDocker) serve(w http.ResponseWriter, r *http.Request) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.calls = append(d.calls, fmt.Sprintf("%v %v", r.Method, r.URL.RequestURI()))
+	ids := map[string]string{"prometheus": "abc123", "grafana": "0ff000"}
+
+	fail := func(code int, msg string) {
+		w.WriteHeader(code)
+		fmt.Fprintf(w, `{"message": %q}`, msg)
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/containers/json":
+		if r.URL.Query().Get("filters") == `{"label":["role=prometheus"]}` {
+			fmt.Fprintf(w, `[{"Id": "abc123"}, {"Id": "def456"}]`)
+		} else {
+			fmt.Fprintf(w, `[]`)
+		}
+	case len(parts) == 3 && parts[0] == "containers" && parts[2] == "json":
+		if id, ok := ids[parts[1]]; ok {
+			fmt.Fprintf(w, `{"Id": %q}`, id)
+		} else {
+			fail(http.StatusNotFound, "No such container: "+parts[1])
+		}
+	case len(parts) == 3 && parts[1] == "0ff000":
+		fail(http.StatusConflict, "Container 0ff000 is not running")
+	case len(parts) == 3 && parts[2] == "kill", len(parts) == 3 && parts[2] == "restart":
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 3 && parts[2] == "exec":
+		var create struct{ Cmd []string }
+		json.NewDecoder(r.Body).Decode(&create)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Id": %q}`, strings.Join(create.Cmd, "-"))
+	case len(parts) == 3 && parts[0] == "exec" && parts[2] == "start":
+		w.WriteHeader(http.StatusOK)
+	case len(parts) == 3 && parts[0] == "exec" && parts[2] == "json":
+		// The exec is running on the first poll
+		d.polls++
+		exitCode := 0
+		if strings.HasPrefix(parts[1], "false") {
+			exitCode = 1
+		}
+		fmt.Fprintf(w, `{"Running": %v, "ExitCode": %d}`, d.polls == 1, exitCode)
+	default:
+		fail(http.StatusNotFound, "page not found")
+	}
+}
+
+func (d *fakeDocker) reloader(c *C, opts string) Reloader {
+	reloader, err := NewDockerReloader("test-manager", "docker", []byte(fmt.Sprintf(`{"socket": %q, %v}`, d.socket, opts)))
+	c.Assert(err, IsNil)
+	return reloader
+}
+
+func (s *ReloaderTestSuite) TestNewDockerReloader(c *C) {
+	reloader, err := NewDockerReloader("test-manager", "docker", []byte(`{"container": "prometheus", "action": "signal"}`))
+	c.Assert(err, IsNil)
+	c.Assert(reloader.GetMethod(), Equals, "docker")
+	opts := reloader.GetOpts().(DockerReloaderOpts)
+	c.Assert(opts.Socket, Equals, "/var/run/docker.sock")
+	c.Assert(opts.Signal, Equals, "SIGHUP")
+
+	for _, entry := range []string{
+		`{"action": "restart"}`,
+		`{"container": "prometheus", "label": "role=prometheus", "action": "restart"}`,
+		`{"container": "prometheus", "action": "exec"}`,
+		`{"container": "prometheus", "action": "stop"}`,
+		`not json`,
+	} {
+		_, err = NewDockerReloader("test-manager", "docker", []byte(entry))
+		c.Assert(err, NotNil, Commentf("entry=%v", entry))
+	}
+}
+
+func (s *ReloaderTestSuite) TestNewDockerReloaderFromConfig(c *C) {
+	v := viper.New()
+	v.SetConfigType("toml")
+	c.Assert(v.ReadConfig(bytes.NewBufferString(`[test-manager.reloader]
+  method = "docker"
+  [test-manager.reloader.docker]
+    container = "prometheus"
+    action = "exec"
+    command = ["kill", "-HUP", "1"]
+`)), IsNil)
+	reloader, err := New(v, "test-manager")
+	c.Assert(err, IsNil)
+	c.Assert(reloader.GetOpts().(DockerReloaderOpts).Command, DeepEquals, []string{"kill", "-HUP", "1"})
+}
+
+func (s *ReloaderTestSuite) TestDockerReloaderSignal(c *C) {
+	d := newFakeDocker(c)
+	defer d.server.Close()
+
+	c.Assert(d.reloader(c, `"container": "prometheus", "action": "signal"`).Reload(context.Background()), IsNil)
+	c.Assert(d.reloader(c, `"label": "role=prometheus", "action": "signal", "signal": "SIGUSR1"`).Reload(context.Background()), IsNil)
+	c.Assert(d.calls, DeepEquals, []string{
+		"GET /containers/prometheus/json",
+		"POST /containers/abc123/kill?signal=SIGHUP",
+		"GET /containers/json?filters=%7B%22label%22%3A%5B%22role%3Dprometheus%22%5D%7D",
+		"POST /containers/abc123/kill?signal=SIGUSR1",
+		"POST /containers/def456/kill?signal=SIGUSR1",
+	})
+}
+
+func (s *ReloaderTestSuite) TestDockerReloaderRestart(c *C) {
+	d := newFakeDocker(c)
+	defer d.server.Close()
+
+	c.Assert(d.reloader(c, `"container": "prometheus", "action": "restart"`).Reload(context.Background()), IsNil)
+	c.Assert(d.calls, DeepEquals, []string{
+		"GET /containers/prometheus/json",
+		"POST /containers/abc123/restart",
+	})
+}
+
+func (s *ReloaderTestSuite) TestDockerReloaderExec(c *C) {
+	interval := DockerExecPollInterval
+	DockerExecPollInterval = time.Millisecond
+	defer func() { DockerExecPollInterval = interval }()
+	d := newFakeDocker(c)
+	defer d.server.Close()
+
+	c.Assert(d.reloader(c, `"container": "prometheus", "action": "exec", "command": ["kill", "-HUP", "1"]`).Reload(context.Background()), IsNil)
+	c.Assert(d.calls, DeepEquals, []string{
+		"GET /containers/prometheus/json",
+		"POST /containers/abc123/exec",
+		"POST /exec/kill--HUP-1/start",
+		"GET /exec/kill--HUP-1/json",
+		"GET /exec/kill--HUP-1/json",
+	})
+
+	err := d.reloader(c, `"container": "prometheus", "action": "exec", "command": ["false"]`).Reload(context.Background())
+	c.Assert(err, NotNil)
+	c.Assert(err.(*ReloaderError).Code, Equals, 1)
+	c.Assert(err.(*ReloaderError).Message, Equals, "false exited with 1")
+}
+
+func (s *ReloaderTestSuite) TestDockerReloaderErrors(c *C) {
+	d := newFakeDocker(c)
+	defer d.server.Close()
+
+	err := d.reloader(c, `"container": "alertmanager", "action": "signal"`).Reload(context.Background())
+	c.Assert(err, NotNil)
+	c.Assert(err.(*ReloaderError).Code, Equals, http.StatusNotFound)
+	c.Assert(err.(*ReloaderError).Message, Equals, "No such container: alertmanager")
+
+	err = d.reloader(c, `"container": "grafana", "action": "signal"`).Reload(context.Background())
+	c.Assert(err, NotNil)
+	c.Assert(err.(*ReloaderError).Code, Equals, http.StatusConflict)
+	c.Assert(err.(*ReloaderError).Message, Equals, "Container 0ff000 is not running")
+
+	err = d.reloader(c, `"label": "role=grafana", "action": "restart"`).Reload(context.Background())
+	c.Assert(err, NotNil)
+	c.Assert(err.(*ReloaderError).Code, Equals, http.StatusNotFound)
+
+	// The docker daemon is not there at all
+	d.server.Close()
+	err = d.reloader(c, `"container": "prometheus", "action": "restart"`).Reload(context.Background())
+	c.Assert(err, NotNil)
+	c.Assert(err.(*ReloaderError).Code, Equals, 1)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package reloaders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adobe/butler/internal/environment"
+)
+
+const defaultExecTimeout = 30
+
+// execOutputLimit is how much of the output of a failed command goes into the
+// error.
+const execOutputLimit = 512
+
+func NewExecReloader(manager string, method string, entry []byte) (Reloader, error) {
+	var (
+		err    error
+		result ExecReloader
+		opts   ExecReloaderOpts
+	)
+
+	err = json.Unmarshal(entry, &opts)
+	if err != nil {
+		return result, err
+	}
+
+	for i, arg := range opts.Command {
+		opts.Command[i] = environment.GetVar(arg)
+	}
+	if len(opts.Command) == 0 || opts.Command[0] == "" {
+		return result, errors.New("the exec reloader needs a command to run")
+	}
+	opts.Dir = environment.GetVar(opts.Dir)
+
+	newTimeout, _ := strconv.Atoi(environment.GetVar(opts.Timeout))
+	if newTimeout == 0 {
+		newTimeout = defaultExecTimeout
+	}
+	opts.timeout = time.Duration(newTimeout) * time.Second
+
+	result.Method = method
+	result.Opts = opts
+	result.Manager = manager
+	return result, err
+}
+
+// ExecReloader runs a command next to butler, eg: to validate the
+// configuration with promtool before the manager is reloaded.
+type ExecReloader struct {
+	Manager string           `json:"-"`
+	Counter int              `json:"-"`
+	Method  string           `json:"method"`
+	Opts    ExecReloaderOpts `json:"opts"`
+}
+
+type ExecReloaderOpts struct {
+	// Command is the command to run, and its arguments.
+	Command []string `json:"command"`
+	// Dir is the working directory of the command.
+	Dir     string `json:"dir,omitempty"`
+	Timeout string `json:"timeout,omitempty"`
+	timeout time.Duration
+}
+
+func (r ExecReloader) Reload(ctx context.Context) error {
+	o := r.Opts
+	logEntry(r.Counter, r.Manager, "exec").Debugf("ExecReloader::Reload(): running %v", strings.Join(o.Command, " "))
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, o.Command[0], o.Command[1:]...)
+	cmd.Dir = o.Dir
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		logEntry(r.Counter, r.Manager, "exec").Infof("ExecReloader::Reload(): successfully ran %v.", o.Command[0])
+		return nil
+	}
+
+	logEntry(r.Counter, r.Manager, "exec").Errorf("ExecReloader::Reload(): %v failed. err=%v output=%s", o.Command[0], err, out)
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		return toReloaderError(ctx.Err())
+	case errors.As(err, &exitErr):
+		msg := fmt.Sprintf("%v exited with %v", strings.Join(o.Command, " "), exitErr.ExitCode())
+		if output := strings.TrimSpace(string(out)); output != "" {
+			if len(output) > execOutputLimit {
+				output = output[:execOutputLimit] + "..."
+			}
+			msg = fmt.Sprintf("%v: %v", msg, output)
+		}
+		return NewReloaderError().WithMessage(msg).WithExitCode(exitErr.ExitCode())
+	default:
+		// The command could not be started, eg: it does not exist. The code is
+		// the one a shell would exit with.
+		return NewReloaderError().WithMessage(err.Error()).WithExitCode(127)
+	}
+}
+
+func (r ExecReloader) GetMethod() string {
+	return r.Method
+}
+
+func (r ExecReloader) GetOpts() ReloaderOpts {
+	return r.Opts
+}
+
+func (r ExecReloader) SetOpts(opts ReloaderOpts) bool {
+	r.Opts = opts.(ExecReloaderOpts)
+	return true
+}
+
+func (r ExecReloader) SetCounter(c int) Reloader {
+	r.Counter = c
+	return r
+}
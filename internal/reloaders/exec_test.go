@@ -0,0 +1,97 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package reloaders
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+	. "gopkg.in/check.v1"
+)
+
+func (s *ReloaderTestSuite) TestNewExecReloader(c *C) {
+	os.Setenv("BUTLER_TEST_EXEC_CONFIG", "/etc/prometheus/prometheus.yml")
+	defer os.Unsetenv("BUTLER_TEST_EXEC_CONFIG")
+
+	reloader, err := NewExecReloader("test-manager", "exec", []byte(`{"command": ["promtool", "check", "config", "env:BUTLER_TEST_EXEC_CONFIG"]}`))
+	c.Assert(err, IsNil)
+	c.Assert(reloader.GetMethod(), Equals, "exec")
+	opts := reloader.GetOpts().(ExecReloaderOpts)
+	c.Assert(opts.Command, DeepEquals, []string{"promtool", "check", "config", "/etc/prometheus/prometheus.yml"})
+	c.Assert(opts.timeout, Equals, 30*time.Second)
+
+	for _, entry := range []string{
+		`{}`,
+		`{"command": []}`,
+		`{"command": [""]}`,
+		`not json`,
+	} {
+		_, err = NewExecReloader("test-manager", "exec", []byte(entry))
+		c.Assert(err, NotNil, Commentf("entry=%v", entry))
+	}
+}
+
+func (s *ReloaderTestSuite) TestNewExecReloaderFromConfig(c *C) {
+	v := viper.New()
+	v.SetConfigType("toml")
+	c.Assert(v.ReadConfig(bytes.NewBufferString(`[test-manager.reloader]
+  method = "exec"
+  [test-manager.reloader.exec]
+    command = ["promtool", "check", "config", "prometheus.yml"]
+    dir = "/etc/prometheus"
+    timeout = "10"
+`)), IsNil)
+	reloader, err := New(v, "test-manager")
+	c.Assert(err, IsNil)
+	opts := reloader.GetOpts().(ExecReloaderOpts)
+	c.Assert(opts.Command, DeepEquals, []string{"promtool", "check", "config", "prometheus.yml"})
+	c.Assert(opts.Dir, Equals, "/etc/prometheus")
+	c.Assert(opts.timeout, Equals, 10*time.Second)
+}
+
+func (s *ReloaderTestSuite) TestExecReloaderReload(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(dir+"/prometheus.yml", []byte("global: {}\n"), 0644), IsNil)
+
+	reloader, err := NewExecReloader("test-manager", "exec", []byte(`{"command": ["test", "-f", "prometheus.yml"], "dir": "`+dir+`"}`))
+	c.Assert(err, IsNil)
+	c.Assert(reloader.SetCounter(2).Reload(context.Background()), IsNil)
+}
+
+func (s *ReloaderTestSuite) TestExecReloaderReloadFailure(c *C) {
+	reloader, err := NewExecReloader("test-manager", "exec", []byte(`{"command": ["sh", "-c", "echo bad config; exit 1"]}`))
+	c.Assert(err, IsNil)
+	err = reloader.Reload(context.Background())
+	c.Assert(err, ErrorMatches, "sh -c echo bad config; exit 1 exited with 1: bad config. code=1")
+	// The command ran, so it is not taken for a timeout
+	c.Assert(err.(*ReloaderError).IsTimeout(), Equals, false)
+
+	reloader, err = NewExecReloader("test-manager", "exec", []byte(`{"command": ["/nonexistent/promtool"]}`))
+	c.Assert(err, IsNil)
+	err = reloader.Reload(context.Background())
+	c.Assert(err, NotNil)
+	c.Assert(err.(*ReloaderError).Code, Equals, 127)
+}
+
+func (s *ReloaderTestSuite) TestExecReloaderReloadTimeout(c *C) {
+	reloader, err := NewExecReloader("test-manager", "exec", []byte(`{"command": ["sleep", "5"], "timeout": "1"}`))
+	c.Assert(err, IsNil)
+	start := time.Now()
+	err = reloader.Reload(context.Background())
+	c.Assert(time.Since(start) < 3*time.Second, Equals, true)
+	c.Assert(err, ErrorMatches, "context deadline exceeded. code=1")
+	c.Assert(err.(*ReloaderError).IsTimeout(), Equals, true)
+}
@@ -13,6 +13,7 @@ governing permissions and limitations under the License.
 package reloaders
 
 import (
+	"context"
 	"errors"
 )
 
@@ -31,7 +32,7 @@ type GenericReloader struct {
 type GenericReloaderOpts struct {
 }
 
-func (r GenericReloader) Reload() error {
+func (r GenericReloader) Reload(ctx context.Context) error {
 	var (
 		res error
 	)
@@ -14,7 +14,6 @@ package reloaders
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	//"errors"
 	"fmt"
@@ -23,8 +22,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/adobe/butler/internal/auth"
 	"github.com/adobe/butler/internal/environment"
+	"github.com/adobe/butler/internal/methods"
 	"github.com/adobe/butler/internal/metrics"
+	"github.com/adobe/butler/internal/tracing"
 
 	"github.com/hashicorp/go-retryablehttp"
 	log "github.com/sirupsen/logrus"
@@ -32,10 +34,9 @@ import (
 
 func NewHTTPReloader(manager string, method string, entry []byte) (Reloader, error) {
 	var (
-		err                error
-		result             HTTPReloader
-		opts               HTTPReloaderOpts
-		insecureSkipVerify bool
+		err    error
+		result HTTPReloader
+		opts   HTTPReloaderOpts
 	)
 
 	err = json.Unmarshal(entry, &opts)
@@ -63,10 +64,27 @@ func NewHTTPReloader(manager string, method string, entry []byte) (Reloader, err
 		log.Warnf("NewHttpReloader(): could not convert %v to integer for retry-wait-min, defaulting to 0. This is probably undesired.", opts.RetryWaitMin)
 	}
 
-	// ignore cert errors if defined
-	insecureSkipVerify = strings.ToLower(environment.GetVar(opts.InsecureSkipVerify)) == "true"
+	// TLS options for the reload endpoint, eg: to ignore cert errors
+	opts.CAFile = environment.GetVar(opts.CAFile)
+	opts.ClientCert = environment.GetVar(opts.ClientCert)
+	opts.ClientKey = environment.GetVar(opts.ClientKey)
+	opts.ServerName = environment.GetVar(opts.ServerName)
+	tlsConfig, err := methods.TLSOpts{
+		CAFile:             opts.CAFile,
+		ClientCert:         opts.ClientCert,
+		ClientKey:          opts.ClientKey,
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: strings.ToLower(environment.GetVar(opts.InsecureSkipVerify)) == "true",
+	}.TLSConfig()
+	if err != nil {
+		return result, err
+	}
 	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify},
+		TLSClientConfig: tlsConfig,
+	}
+	opts.Socket = environment.GetVar(opts.Socket)
+	if opts.Socket != "" {
+		transport.DialContext = methods.UnixSocketDialer(opts.Socket)
 	}
 
 	opts.Client = retryablehttp.NewClient()
@@ -85,6 +103,31 @@ func NewHTTPReloader(manager string, method string, entry []byte) (Reloader, err
 	opts.URI = environment.GetVar(opts.URI)
 	opts.Method = environment.GetVar(opts.Method)
 	opts.Payload = environment.GetVar(opts.Payload)
+	for k, v := range opts.Headers {
+		opts.Headers[k] = environment.GetVar(v)
+	}
+
+	switch strings.ToLower(environment.GetVar(opts.AuthType)) {
+	case "":
+	case "basic":
+		opts.Authorizer, err = auth.NewBasic(environment.GetVar(opts.AuthUser), environment.GetVar(opts.AuthToken))
+	case "bearer":
+		opts.Authorizer, err = auth.NewBearer(environment.GetVar(opts.AuthToken), environment.GetVar(opts.AuthTokenFile))
+	case "oauth2-client-credentials":
+		var client *http.Client
+		opts.TokenCAFile = environment.GetVar(opts.TokenCAFile)
+		client, err = methods.NewTokenClient(opts.Client.HTTPClient.Timeout, opts.TokenCAFile,
+			strings.ToLower(environment.GetVar(opts.TokenInsecureSkipVerify)) == "true")
+		if err == nil {
+			opts.Authorizer, err = auth.NewClientCredentials(environment.GetVar(opts.TokenURL), environment.GetVar(opts.ClientID),
+				environment.GetVar(opts.ClientSecret), opts.Scopes, client)
+		}
+	default:
+		err = fmt.Errorf("unsupported auth-type %v for the http reloader", opts.AuthType)
+	}
+	if err != nil {
+		return result, err
+	}
 
 	result.Method = method
 	result.Opts = opts
@@ -102,69 +145,128 @@ type HTTPReloader struct {
 }
 
 type HTTPReloaderOpts struct {
-	Client             *retryablehttp.Client `json:"-"`
-	ContentType        string                `json:"content-type"`
-	Host               string                `json:"host"`
-	InsecureSkipVerify string                `json:"insecure-skip-verify"`
-	Port               string                `mapstructure:"port" json:"port"`
-	URI                string                `json:"uri"`
-	Method             string                `json:"method"`
-	Payload            string                `json:"payload"`
-	Retries            string                `json:"retries"`
-	RetryWaitMax       string                `json:"retry-wait-max"`
-	RetryWaitMin       string                `json:"retry-wait-min"`
-	Timeout            string                `json:"timeout"`
+	Client                  *retryablehttp.Client `json:"-"`
+	ContentType             string                `json:"content-type"`
+	Host                    string                `json:"host"`
+	Socket                  string                `json:"socket,omitempty"`
+	InsecureSkipVerify      string                `json:"insecure-skip-verify"`
+	Port                    string                `mapstructure:"port" json:"port"`
+	URI                     string                `json:"uri"`
+	Method                  string                `json:"method"`
+	Payload                 string                `json:"payload"`
+	Retries                 string                `json:"retries"`
+	RetryWaitMax            string                `json:"retry-wait-max"`
+	RetryWaitMin            string                `json:"retry-wait-min"`
+	Timeout                 string                `json:"timeout"`
+	Headers                 map[string]string     `json:"headers,omitempty"`
+	AuthType                string                `json:"auth-type,omitempty"`
+	AuthUser                string                `json:"auth-user,omitempty"`
+	AuthToken               string                `json:"auth-token,omitempty"`
+	AuthTokenFile           string                `json:"auth-token-file,omitempty"`
+	TokenURL                string                `json:"token-url,omitempty"`
+	ClientID                string                `json:"client-id,omitempty"`
+	ClientSecret            string                `json:"client-secret,omitempty"`
+	Scopes                  []string              `json:"scopes,omitempty"`
+	TokenCAFile             string                `json:"token-ca-file,omitempty"`
+	TokenInsecureSkipVerify string                `json:"token-insecure-skip-verify,omitempty"`
+	Authorizer              auth.Authorizer       `json:"-"`
+	CAFile                  string                `json:"ca-file,omitempty"`
+	ClientCert              string                `json:"client-cert,omitempty"`
+	ClientKey               string                `json:"client-key,omitempty"`
+	ServerName              string                `json:"server-name,omitempty"`
 }
 
 func (h *HTTPReloaderOpts) GetClient() *retryablehttp.Client {
 	return h.Client
 }
 
-func (h HTTPReloader) Reload() error {
+// MarshalJSON hides the credentials of the reloader, which would otherwise
+// show up on /health-check.
+func (h HTTPReloaderOpts) MarshalJSON() ([]byte, error) {
+	type opts HTTPReloaderOpts
+	o := opts(h)
+	if o.AuthToken != "" {
+		o.AuthToken = "redacted"
+	}
+	if o.ClientSecret != "" {
+		o.ClientSecret = "redacted"
+	}
+	// Headers often carry tokens, eg: for Jenkins.
+	if len(o.Headers) > 0 {
+		o.Headers = make(map[string]string)
+		for k := range h.Headers {
+			o.Headers[k] = "redacted"
+		}
+	}
+	return json.Marshal(o)
+}
+
+func (h HTTPReloader) Reload(ctx context.Context) error {
 	var (
 		err  error
 		req  *retryablehttp.Request
 		resp *http.Response
 	)
 
-	log.Debugf("HTTPReloader::Reload()[count=%v][manager=%v]: reloading manager using http", h.Counter, h.Manager)
+	logEntry(h.Counter, h.Manager, "http").Debug("HTTPReloader::Reload(): reloading manager using http")
 	o := h.GetOpts().(HTTPReloaderOpts)
 	c := o.GetClient()
 	// Set the reloader retry policy
 	c.CheckRetry = h.ReloaderRetryPolicy
-	newPort, _ := strconv.Atoi(environment.GetVar(o.Port))
-	if newPort == 0 {
-		log.Warnf("HTTPReloader::Reload()[count=%v][manager=%v]: could not convert %v to integer for port, defaulting to 0. This is probably undesired.", h.Counter, h.Manager, o.Port)
+	var reloadURL string
+	if o.Socket != "" {
+		// The connection goes to the socket, the host is only used for the
+		// Host header.
+		host := o.Host
+		if host == "" {
+			host = "localhost"
+		}
+		reloadURL = fmt.Sprintf("%s://%s%s", h.Method, host, o.URI)
+	} else {
+		newPort, _ := strconv.Atoi(environment.GetVar(o.Port))
+		if newPort == 0 {
+			logEntry(h.Counter, h.Manager, "http").Warnf("HTTPReloader::Reload(): could not convert %v to integer for port, defaulting to 0. This is probably undesired.", o.Port)
+		}
+		reloadURL = fmt.Sprintf("%s://%s:%d%s", h.Method, o.Host, newPort, o.URI)
 	}
-	reloadURL := fmt.Sprintf("%s://%s:%d%s", h.Method, o.Host, newPort, o.URI)
 
 	switch o.Method {
 	case "post", "put", "patch":
-		req, err = retryablehttp.NewRequest(strings.ToUpper(o.Method), reloadURL, strings.NewReader(o.Payload))
+		req, err = retryablehttp.NewRequestWithContext(ctx, strings.ToUpper(o.Method), reloadURL, strings.NewReader(o.Payload))
 		req.Header.Add("Content-Type", o.ContentType)
 	default:
-		req, err = retryablehttp.NewRequest(strings.ToUpper(o.Method), reloadURL, nil)
+		req, err = retryablehttp.NewRequestWithContext(ctx, strings.ToUpper(o.Method), reloadURL, nil)
 	}
 
 	if err != nil {
-		msg := fmt.Sprintf("HTTPReloader::Reload()[count=%v][manager=%v]: err=%v", h.Counter, h.Manager, err.Error())
-		log.Errorf(msg)
+		logEntry(h.Counter, h.Manager, "http").Errorf("HTTPReloader::Reload(): err=%v", err.Error())
 		return NewReloaderError().WithMessage(err.Error()).WithCode(1)
 	}
 
-	log.Debugf("HTTPReloader::Reload()[count=%v][manager=%v]: %v'ing up!", h.Counter, h.Manager, o.Method)
+	for k, v := range o.Headers {
+		req.Header.Set(k, v)
+	}
+	tracing.Inject(ctx, req.Header)
+	if o.Authorizer != nil {
+		authorization, err := o.Authorizer.Authorization(ctx)
+		if err != nil {
+			logEntry(h.Counter, h.Manager, "http").Errorf("HTTPReloader::Reload(): could not authorize. err=%v", err.Error())
+			return NewReloaderError().WithMessage(err.Error()).WithCode(1)
+		}
+		req.Header.Set("Authorization", authorization)
+	}
+
+	logEntry(h.Counter, h.Manager, "http").Debugf("HTTPReloader::Reload(): %v'ing up!", o.Method)
 	resp, err = c.Do(req)
 	if err != nil {
-		msg := fmt.Sprintf("HTTPReloader::Reload()[count=%v][manager=%v]: err=%v", h.Counter, h.Manager, err.Error())
-		log.Errorf(msg)
+		logEntry(h.Counter, h.Manager, "http").Errorf("HTTPReloader::Reload(): err=%v", err.Error())
 		return NewReloaderError().WithMessage(err.Error()).WithCode(1)
 	}
 	if resp.StatusCode == 200 {
-		log.Infof("HTTPReloader::Reload()[count=%v][manager=%v]: successfully reloaded config. http_code=%d", h.Counter, h.Manager, int(resp.StatusCode))
+		logEntry(h.Counter, h.Manager, "http").Infof("HTTPReloader::Reload(): successfully reloaded config. http_code=%d", int(resp.StatusCode))
 		// at this point error should be nil, so things are OK
 	} else {
-		msg := fmt.Sprintf("HTTPReloader::Reload()[count=%v][manager=%v]: received bad response from server. http_code=%d", h.Counter, h.Manager, int(resp.StatusCode))
-		log.Errorf(msg)
+		logEntry(h.Counter, h.Manager, "http").Errorf("HTTPReloader::Reload(): received bad response from server. http_code=%d", int(resp.StatusCode))
 		// at this point we should raise an error
 		return NewReloaderError().WithMessage("received bad response from server").WithCode(resp.StatusCode)
 	}
@@ -173,6 +275,11 @@ func (h HTTPReloader) Reload() error {
 }
 
 func (h *HTTPReloader) ReloaderRetryPolicy(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	// Do not retry when butler is shutting down
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
 	if err != nil {
 		metrics.SetButlerReloaderRetry(metrics.SUCCESS, h.Manager)
 		return true, err
@@ -14,11 +14,26 @@ package reloaders
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"time"
 
+	"github.com/adobe/butler/internal/tracing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	. "gopkg.in/check.v1"
 )
 
@@ -111,7 +126,7 @@ func (s *ReloaderTestSuite) TestHTTPReloaderReloadSuccess(c *C) {
 	reloader, err := NewHTTPReloader("test-manager", "http", jsonOpts)
 	c.Assert(err, IsNil)
 
-	err = reloader.Reload()
+	err = reloader.Reload(context.Background())
 	c.Assert(err, IsNil)
 }
 
@@ -139,7 +154,7 @@ func (s *ReloaderTestSuite) TestHTTPReloaderReloadBadResponse(c *C) {
 	reloader, err := NewHTTPReloader("test-manager", "http", jsonOpts)
 	c.Assert(err, IsNil)
 
-	err = reloader.Reload()
+	err = reloader.Reload(context.Background())
 	c.Assert(err, NotNil)
 	reloaderErr := err.(*ReloaderError)
 	c.Assert(reloaderErr.Code, Equals, 500)
@@ -169,7 +184,7 @@ func (s *ReloaderTestSuite) TestHTTPReloaderReloadMethods(c *C) {
 
 		jsonOpts, _ := json.Marshal(opts)
 		reloader, _ := NewHTTPReloader("test-manager", "http", jsonOpts)
-		err := reloader.Reload()
+		err := reloader.Reload(context.Background())
 		c.Assert(err, IsNil, Commentf("Method %s failed", method))
 
 		server.Close()
@@ -209,6 +224,287 @@ func (s *ReloaderTestSuite) TestHTTPReloaderRetryPolicy(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *ReloaderTestSuite) TestHTTPReloaderReloadCanceled(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	opts := HTTPReloaderOpts{
+		Host:         "127.0.0.1",
+		Port:         fmt.Sprintf("%d", getPortFromURL(server.URL)),
+		URI:          "/",
+		Method:       "get",
+		Timeout:      "10",
+		Retries:      "5",
+		RetryWaitMin: "1",
+		RetryWaitMax: "2",
+	}
+	jsonOpts, err := json.Marshal(opts)
+	c.Assert(err, IsNil)
+	reloader, err := NewHTTPReloader("test-manager", "http", jsonOpts)
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = reloader.Reload(ctx)
+	c.Assert(err, NotNil)
+	c.Assert(err.(*ReloaderError).Code, Equals, 1)
+
+	// A canceled reload is not retried
+	httpReloader := reloader.(HTTPReloader)
+	shouldRetry, err := httpReloader.ReloaderRetryPolicy(ctx, nil, context.Canceled)
+	c.Assert(shouldRetry, Equals, false)
+	c.Assert(err, Equals, context.Canceled)
+}
+
+func (s *ReloaderTestSuite) TestHTTPReloaderReloadBearer(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer grafana-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	os.Setenv("BUTLER_TEST_RELOAD_TOKEN", "grafana-token")
+	defer os.Unsetenv("BUTLER_TEST_RELOAD_TOKEN")
+	jsonOpts := []byte(fmt.Sprintf(`{"host": "127.0.0.1", "port": "%d", "uri": "/api/admin/provisioning/dashboards/reload",
+		"method": "post", "timeout": "10", "retries": "1", "auth-type": "bearer", "auth-token": "env:BUTLER_TEST_RELOAD_TOKEN"}`,
+		getPortFromURL(server.URL)))
+	reloader, err := NewHTTPReloader("test-manager", "http", jsonOpts)
+	c.Assert(err, IsNil)
+	c.Assert(reloader.Reload(context.Background()), IsNil)
+
+	// The token is not shown on /health-check
+	out, err := json.Marshal(reloader)
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Not(Matches), ".*grafana-token.*")
+	c.Assert(string(out), Matches, `.*"auth-token":"redacted".*`)
+}
+
+func (s *ReloaderTestSuite) TestHTTPReloaderReloadOAuth2(c *C) {
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token": "oauth2-token", "token_type": "bearer", "expires_in": 3600}`)
+	}))
+	defer tokens.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer oauth2-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	jsonOpts := []byte(fmt.Sprintf(`{"host": "127.0.0.1", "port": "%d", "uri": "/", "method": "post", "timeout": "10",
+		"retries": "1", "auth-type": "oauth2-client-credentials", "token-url": "%s", "client-id": "butler",
+		"client-secret": "s3cr3t", "scopes": ["reload"]}`, getPortFromURL(server.URL), tokens.URL))
+	reloader, err := NewHTTPReloader("test-manager", "http", jsonOpts)
+	c.Assert(err, IsNil)
+	c.Assert(reloader.Reload(context.Background()), IsNil)
+
+	out, err := json.Marshal(reloader)
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Not(Matches), ".*s3cr3t.*")
+
+	// A token endpoint which is down fails the reload
+	tokens.Close()
+	reloader, err = NewHTTPReloader("test-manager", "http", jsonOpts)
+	c.Assert(err, IsNil)
+	err = reloader.Reload(context.Background())
+	c.Assert(err, NotNil)
+	c.Assert(err.(*ReloaderError).Code, Equals, 1)
+}
+
+func (s *ReloaderTestSuite) TestNewHTTPReloaderAuthErrors(c *C) {
+	_, err := NewHTTPReloader("test-manager", "http", []byte(`{"auth-type": "bearer"}`))
+	c.Assert(err, NotNil)
+	_, err = NewHTTPReloader("test-manager", "http", []byte(`{"auth-type": "oauth2-client-credentials", "client-id": "butler"}`))
+	c.Assert(err, NotNil)
+	_, err = NewHTTPReloader("test-manager", "http", []byte(`{"auth-type": "kerberos"}`))
+	c.Assert(err, ErrorMatches, "unsupported auth-type kerberos for the http reloader")
+}
+
+func (s *ReloaderTestSuite) TestHTTPReloaderReloadHeadersBasic(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if user != "admin" || pass != "s3cr3t" || r.Header.Get("Jenkins-Crumb") != "crumb" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	os.Setenv("BUTLER_TEST_CRUMB", "crumb")
+	defer os.Unsetenv("BUTLER_TEST_CRUMB")
+	jsonOpts := []byte(fmt.Sprintf(`{"host": "127.0.0.1", "port": "%d", "uri": "/configuration-as-code/reload", "method": "post",
+		"timeout": "10", "retries": "1", "headers": {"jenkins-crumb": "env:BUTLER_TEST_CRUMB"},
+		"auth-type": "basic", "auth-user": "admin", "auth-token": "s3cr3t"}`, getPortFromURL(server.URL)))
+	reloader, err := NewHTTPReloader("test-manager", "http", jsonOpts)
+	c.Assert(err, IsNil)
+	c.Assert(reloader.Reload(context.Background()), IsNil)
+
+	out, err := json.Marshal(reloader)
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Matches, `.*"headers":\{"jenkins-crumb":"redacted"\}.*`)
+	c.Assert(string(out), Not(Matches), ".*s3cr3t.*")
+
+	_, err = NewHTTPReloader("test-manager", "http", []byte(`{"auth-type": "basic", "auth-user": "admin"}`))
+	c.Assert(err, NotNil)
+}
+
+func (s *ReloaderTestSuite) TestHTTPReloaderReloadClientCert(c *C) {
+	dir := c.MkDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "butler"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	c.Assert(err, IsNil)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, IsNil)
+	c.Assert(os.WriteFile(dir+"/client.crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644), IsNil)
+	c.Assert(os.WriteFile(dir+"/client.key", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600), IsNil)
+	clientCert, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	c.Assert(os.WriteFile(dir+"/ca.crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644), IsNil)
+
+	var port int
+	fmt.Sscanf(server.URL, "https://127.0.0.1:%d", &port)
+	opts := fmt.Sprintf(`{"host": "127.0.0.1", "port": "%d", "uri": "/-/reload", "method": "post", "timeout": "10",
+		"ca-file": "%v/ca.crt", "server-name": "example.com"`, port, dir)
+
+	// Without the client certificate the reload is turned away
+	reloader, err := NewHTTPReloader("test-manager", "https", []byte(opts+"}"))
+	c.Assert(err, IsNil)
+	c.Assert(reloader.Reload(context.Background()), NotNil)
+
+	reloader, err = NewHTTPReloader("test-manager", "https", []byte(fmt.Sprintf(`%v, "client-cert": "%v/client.crt", "client-key": "%v/client.key"}`, opts, dir, dir)))
+	c.Assert(err, IsNil)
+	c.Assert(reloader.Reload(context.Background()), IsNil)
+
+	_, err = NewHTTPReloader("test-manager", "https", []byte(fmt.Sprintf(`%v, "client-cert": "%v/client.crt"}`, opts, dir)))
+	c.Assert(err, ErrorMatches, "client-cert and client-key must be set together")
+}
+
+func (s *ReloaderTestSuite) TestHTTPReloaderReloadTLSOAuth2(c *C) {
+	var serverName string
+	tokens := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverName = r.TLS.ServerName
+		fmt.Fprintf(w, `{"access_token": "oauth2-token", "token_type": "bearer", "expires_in": 3600}`)
+	}))
+	defer tokens.Close()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer oauth2-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(dir+"/ca.crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644), IsNil)
+
+	var port int
+	fmt.Sscanf(server.URL, "https://127.0.0.1:%d", &port)
+	opts := fmt.Sprintf(`{"host": "127.0.0.1", "port": "%d", "uri": "/-/reload", "method": "post", "timeout": "10",
+		"retries": "0", "ca-file": "%v/ca.crt", "server-name": "example.com", "auth-type": "oauth2-client-credentials",
+		"token-url": "%v", "client-id": "butler", "client-secret": "s3cr3t"`, port, dir, tokens.URL)
+
+	// The ca-file of the reload endpoint is not used for the token server
+	reloader, err := NewHTTPReloader("test-manager", "https", []byte(opts+"}"))
+	c.Assert(err, IsNil)
+	c.Assert(reloader.Reload(context.Background()), ErrorMatches, ".*certificate signed by unknown authority.*")
+
+	for _, tokenOpts := range []string{
+		fmt.Sprintf(`, "token-ca-file": "%v/ca.crt"}`, dir),
+		`, "token-insecure-skip-verify": "true"}`,
+	} {
+		reloader, err = NewHTTPReloader("test-manager", "https", []byte(opts+tokenOpts))
+		c.Assert(err, IsNil)
+		c.Assert(reloader.Reload(context.Background()), IsNil, Commentf("opts=%v", tokenOpts))
+		// The server-name of the reload endpoint is not sent to the token server
+		c.Assert(serverName, Equals, "")
+	}
+}
+
+func (s *ReloaderTestSuite) TestHTTPReloaderReloadSocketOAuth2(c *C) {
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token": "oauth2-token", "token_type": "bearer", "expires_in": 3600}`)
+	}))
+	defer tokens.Close()
+	socket := c.MkDir() + "/admin.sock"
+	l, err := net.Listen("unix", socket)
+	c.Assert(err, IsNil)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/reload" || r.Header.Get("Authorization") != "Bearer oauth2-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+
+	// The token is fetched from the token server, rather than the socket
+	jsonOpts := []byte(fmt.Sprintf(`{"socket": "%v", "uri": "/admin/reload", "method": "post", "timeout": "10", "retries": "1",
+		"auth-type": "oauth2-client-credentials", "token-url": "%v", "client-id": "butler", "client-secret": "s3cr3t"}`, socket, tokens.URL))
+	reloader, err := NewHTTPReloader("test-manager", "http", jsonOpts)
+	c.Assert(err, IsNil)
+	c.Assert(reloader.Reload(context.Background()), IsNil)
+}
+
+func (s *ReloaderTestSuite) TestHTTPReloaderReloadSocket(c *C) {
+	socket := c.MkDir() + "/admin.sock"
+	l, err := net.Listen("unix", socket)
+	c.Assert(err, IsNil)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/reload" || r.Host != "localhost" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+
+	os.Setenv("BUTLER_TEST_SOCKET", socket)
+	defer os.Unsetenv("BUTLER_TEST_SOCKET")
+	jsonOpts := []byte(`{"socket": "env:BUTLER_TEST_SOCKET", "uri": "/admin/reload", "method": "post", "timeout": "10"}`)
+	reloader, err := NewHTTPReloader("test-manager", "http", jsonOpts)
+	c.Assert(err, IsNil)
+	c.Assert(reloader.GetOpts().(HTTPReloaderOpts).Socket, Equals, socket)
+	c.Assert(reloader.Reload(context.Background()), IsNil)
+
+	// A socket nobody listens on fails the reload
+	jsonOpts = []byte(fmt.Sprintf(`{"socket": "%v.missing", "uri": "/admin/reload", "method": "post", "timeout": "10"}`, socket))
+	reloader, err = NewHTTPReloader("test-manager", "http", jsonOpts)
+	c.Assert(err, IsNil)
+	c.Assert(reloader.Reload(context.Background()), NotNil)
+}
+
 // Helper function to extract port from URL
 func getPortFromURL(urlStr string) int {
 	// Parse URL like "http://127.0.0.1:12345"
@@ -216,3 +512,31 @@ func getPortFromURL(urlStr string) int {
 	fmt.Sscanf(urlStr, "http://127.0.0.1:%d", &port)
 	return port
 }
+
+func (s *ReloaderTestSuite) TestHTTPReloaderReloadTraceContext(c *C) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+	}))
+	defer server.Close()
+	tracing.SetProvider(tracing.NewProvider(sdktrace.WithSyncer(tracetest.NewInMemoryExporter())))
+	defer tracing.SetProvider(nil)
+
+	jsonOpts, err := json.Marshal(HTTPReloaderOpts{
+		Host:    "127.0.0.1",
+		Port:    fmt.Sprintf("%d", getPortFromURL(server.URL)),
+		URI:     "/-/reload",
+		Method:  "post",
+		Timeout: "10",
+		Retries: "1",
+	})
+	c.Assert(err, IsNil)
+	reloader, err := NewHTTPReloader("test-manager", "http", jsonOpts)
+	c.Assert(err, IsNil)
+
+	// The manager carries on with the trace of the reload
+	ctx, span := tracing.Start(context.Background(), "Reloader.Reload")
+	defer span.End()
+	c.Assert(reloader.Reload(ctx), IsNil)
+	c.Assert(traceparent, Equals, "00-"+span.SpanContext().TraceID().String()+"-"+span.SpanContext().SpanID().String()+"-01")
+}
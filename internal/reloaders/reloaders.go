@@ -13,16 +13,26 @@ governing permissions and limitations under the License.
 package reloaders
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 
-	//log "github.com/sirupsen/logrus"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
+// logEntry returns the log entry for the reloads of manager, which carries the
+// run count, the manager and the reloader method as fields, the same as the
+// retrievals of the manager.
+func logEntry(count int, manager string, method string) *log.Entry {
+	return log.WithFields(log.Fields{"count": count, "manager": manager, "method": method})
+}
+
+// Reloader tells a manager to reload its configuration. The context is used to
+// abandon the reload when butler is shutting down.
 type Reloader interface {
-	Reload() error
+	Reload(context.Context) error
 	GetMethod() string
 	GetOpts() ReloaderOpts
 	SetOpts(ReloaderOpts) bool
@@ -32,15 +42,20 @@ type Reloader interface {
 type ReloaderOpts interface {
 }
 
-func New(entry string) (Reloader, error) {
+// New returns the Reloader configured under the reloader key of entry in v.
+func New(v *viper.Viper, entry string) (Reloader, error) {
 	var (
 		err    error
 		result map[string]interface{}
 	)
 
+	if v == nil {
+		return NewGenericReloaderWithCustomError(entry, "error", errors.New("no reloader has been defined for manager"))
+	}
+
 	key := fmt.Sprintf("%s.reloader", entry)
 
-	err = viper.UnmarshalKey(key, &result)
+	err = v.UnmarshalKey(key, &result)
 	if err != nil {
 		return NewGenericReloader(entry, "error", []byte(entry))
 	}
@@ -67,11 +82,22 @@ func New(entry string) (Reloader, error) {
 		return NewGenericReloaderWithCustomError(entry, "error", errors.New("no reloader configuration has been defined for manager"))
 	}
 
+	return newReloader(entry, method, jsonRes)
+}
+
+// newReloader returns the Reloader for method, with the json options in opts.
+func newReloader(manager string, method string, opts []byte) (Reloader, error) {
 	switch method {
 	case "http", "https":
-		return NewHTTPReloader(entry, method, jsonRes)
+		return NewHTTPReloader(manager, method, opts)
+	case "docker":
+		return NewDockerReloader(manager, method, opts)
+	case "exec":
+		return NewExecReloader(manager, method, opts)
+	case "chain":
+		return NewChainReloader(manager, method, opts)
 	default:
-		return NewGenericReloader(entry, method, jsonRes)
+		return NewGenericReloader(manager, method, opts)
 	}
 }
 
@@ -89,12 +115,48 @@ func (r *ReloaderError) WithMessage(m string) *ReloaderError {
 	return r
 }
 
+// toReloaderError turns err into a ReloaderError, if it is not one already.
+func toReloaderError(err error) *ReloaderError {
+	var rerr *ReloaderError
+	if errors.As(err, &rerr) {
+		return rerr
+	}
+	return NewReloaderError().WithMessage(err.Error()).WithCode(1)
+}
+
+// WithStep records the step of a reloader chain which failed.
+func (r *ReloaderError) WithStep(s string) *ReloaderError {
+	r.Step = s
+	return r
+}
+
+// WithExitCode records the exit code of a command which failed, as the code
+// of the error.
+func (r *ReloaderError) WithExitCode(c int) *ReloaderError {
+	r.Code = c
+	r.exited = true
+	return r
+}
+
+// IsTimeout returns whether or not the reloader timed out, or could not be
+// reached, which manager-timeout-ok lets through. A command which exited with
+// 1 is not, and neither is a failed step of a reloader chain, since its
+// on-failure policy decides whether or not the configuration is rolled back.
+func (r *ReloaderError) IsTimeout() bool {
+	return r.Code == 1 && !r.exited && r.Step == ""
+}
+
 func (r *ReloaderError) Error() string {
 	msg := fmt.Sprintf("%v. code=%v", r.Message, r.Code)
+	if r.Step != "" {
+		msg = fmt.Sprintf("step %v failed: %v", r.Step, msg)
+	}
 	return msg
 }
 
 type ReloaderError struct {
 	Code    int
 	Message string
+	Step    string
+	exited  bool
 }
@@ -13,8 +13,12 @@ governing permissions and limitations under the License.
 package reloaders
 
 import (
+	"bytes"
+	"context"
 	"testing"
 
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	. "gopkg.in/check.v1"
 )
 
@@ -53,6 +57,19 @@ func (s *ReloaderTestSuite) TestReloaderErrorErrorMethod(c *C) {
 	c.Assert(errStr, Equals, "internal server error. code=500")
 }
 
+func (s *ReloaderTestSuite) TestReloaderErrorWithStep(c *C) {
+	err := NewReloaderError().WithCode(500).WithMessage("internal server error").WithStep("2/3 (reload)")
+	c.Assert(err.Step, Equals, "2/3 (reload)")
+	c.Assert(err.Error(), Equals, "step 2/3 (reload) failed: internal server error. code=500")
+}
+
+func (s *ReloaderTestSuite) TestReloaderErrorIsTimeout(c *C) {
+	c.Assert(NewReloaderError().WithCode(1).WithMessage("connection refused").IsTimeout(), Equals, true)
+	c.Assert(NewReloaderError().WithCode(500).WithMessage("internal server error").IsTimeout(), Equals, false)
+	c.Assert(NewReloaderError().WithExitCode(1).WithMessage("promtool exited with 1").IsTimeout(), Equals, false)
+	c.Assert(NewReloaderError().WithCode(1).WithMessage("connection refused").WithStep("2/3 (reload)").IsTimeout(), Equals, false)
+}
+
 func (s *ReloaderTestSuite) TestGenericReloader(c *C) {
 	reloader, err := NewGenericReloader("test-manager", "generic", []byte("test"))
 	c.Assert(err, NotNil)
@@ -60,7 +77,7 @@ func (s *ReloaderTestSuite) TestGenericReloader(c *C) {
 
 	// Test the interface methods
 	c.Assert(reloader.GetMethod(), Equals, "none")
-	c.Assert(reloader.Reload(), IsNil)
+	c.Assert(reloader.Reload(context.Background()), IsNil)
 }
 
 func (s *ReloaderTestSuite) TestGenericReloaderWithCustomError(c *C) {
@@ -88,3 +105,40 @@ func (s *ReloaderTestSuite) TestGenericReloaderSetOpts(c *C) {
 	result := reloader.SetOpts(GenericReloaderOpts{})
 	c.Assert(result, Equals, true)
 }
+
+func (s *ReloaderTestSuite) TestNew(c *C) {
+	v := viper.New()
+	v.SetConfigType("toml")
+	err := v.ReadConfig(bytes.NewBuffer([]byte(`[test-manager.reloader]
+  method = "http"
+  [test-manager.reloader.http]
+    host = "localhost"
+    port = "9090"
+    uri = "/-/reload"
+    method = "post"
+    payload = "{}"
+    content-type = "application/json"
+    retries = "1"
+    retry-wait-min = "1"
+    retry-wait-max = "2"
+    timeout = "5"
+    headers = { "X-Scope-OrgID" = "tenant" }
+`)))
+	c.Assert(err, IsNil)
+
+	reloader, err := New(v, "test-manager")
+	c.Assert(err, IsNil)
+	c.Assert(reloader.GetMethod(), Equals, "http")
+	c.Assert(reloader.GetOpts().(HTTPReloaderOpts).Headers, DeepEquals, map[string]string{"x-scope-orgid": "tenant"})
+
+	// another source knows nothing of test-manager
+	_, err = New(viper.New(), "test-manager")
+	c.Assert(err, NotNil)
+
+	_, err = New(nil, "test-manager")
+	c.Assert(err, NotNil)
+}
+
+func (s *ReloaderTestSuite) TestLogEntry(c *C) {
+	c.Assert(logEntry(3, "prometheus", "http").Data, DeepEquals, log.Fields{"count": 3, "manager": "prometheus", "method": "http"})
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+// Package tracing traces the butler configuration pipeline as OpenTelemetry
+// spans, and exports them over OTLP. Spans are dropped until a provider is
+// set.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// The OTLP protocols which spans are exported over.
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// Name is the name of the butler tracer.
+const Name = "github.com/adobe/butler"
+
+// The attributes of the butler spans.
+const (
+	CountKey    = attribute.Key("butler.count")
+	ManagerKey  = attribute.Key("butler.manager")
+	RepoKey     = attribute.Key("butler.repo")
+	FileKey     = attribute.Key("butler.file")
+	MethodKey   = attribute.Key("butler.method")
+	URLKey      = attribute.Key("butler.url")
+	ReloaderKey = attribute.Key("butler.reloader")
+	ChangedKey  = attribute.Key("butler.changed")
+)
+
+var (
+	lock       sync.Mutex
+	provider   trace.TracerProvider = noop.NewTracerProvider()
+	propagator                      = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+)
+
+// Opts are the options of the OTLP exporter.
+type Opts struct {
+	// Endpoint is the host:port of the collector, or its url, eg:
+	// http://collector:4318. An http url implies Insecure.
+	Endpoint string
+	// Protocol is either ProtocolGRPC, the default, or ProtocolHTTP.
+	Protocol string
+	// Insecure turns off TLS towards the collector.
+	Insecure bool
+}
+
+// IsValidProtocol returns whether or not p is a supported OTLP protocol.
+func IsValidProtocol(p string) bool {
+	return p == ProtocolGRPC || p == ProtocolHTTP
+}
+
+// New returns a provider which exports its spans over OTLP, in batches, as
+// per the opts. Nothing is sent until the first spans are exported.
+func New(opts Opts) (*sdktrace.TracerProvider, error) {
+	if opts.Endpoint == "" {
+		return nil, fmt.Errorf("no tracing endpoint has been defined")
+	}
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	isURL := strings.Contains(opts.Endpoint, "://")
+	switch opts.Protocol {
+	case ProtocolGRPC, "":
+		var o []otlptracegrpc.Option
+		if isURL {
+			o = append(o, otlptracegrpc.WithEndpointURL(opts.Endpoint))
+		} else {
+			o = append(o, otlptracegrpc.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			o = append(o, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(context.Background(), o...)
+	case ProtocolHTTP:
+		var o []otlptracehttp.Option
+		if isURL {
+			o = append(o, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		} else {
+			o = append(o, otlptracehttp.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			o = append(o, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), o...)
+	default:
+		return nil, fmt.Errorf("unknown tracing protocol %v", opts.Protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewProvider(sdktrace.WithBatcher(exporter)), nil
+}
+
+// NewProvider returns a provider of butler spans, which are processed as per
+// the opts, eg: sdktrace.WithSyncer(tracetest.NewInMemoryExporter()).
+func NewProvider(opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	host, _ := os.Hostname()
+	res := resource.NewSchemaless(attribute.String("service.name", "butler"), attribute.String("host.name", host))
+	return sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{sdktrace.WithResource(res)}, opts...)...)
+}
+
+// providerKey is the context key of the provider of WithProvider.
+type providerKey struct{}
+
+// SetProvider makes p the default provider of the butler spans, for the
+// spans started from a context without a provider of its own, and returns
+// the previous one, so that it can be shut down. A nil p drops the spans.
+func SetProvider(p *sdktrace.TracerProvider) *sdktrace.TracerProvider {
+	lock.Lock()
+	defer lock.Unlock()
+	prev, _ := provider.(*sdktrace.TracerProvider)
+	if p == nil {
+		provider = noop.NewTracerProvider()
+	} else {
+		provider = p
+	}
+	return prev
+}
+
+// WithProvider returns ctx along with p, which the spans started from ctx
+// then belong to, rather than to the default provider. This keeps the spans
+// of several butlers in one process apart. A nil p leaves ctx as it is.
+func WithProvider(ctx context.Context, p *sdktrace.TracerProvider) context.Context {
+	if p == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, providerKey{}, p)
+}
+
+// Start starts a span, as a child of the span of ctx if there is one. The span
+// belongs to the provider of ctx, if it has one, or else to the default
+// provider.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	p, ok := ctx.Value(providerKey{}).(trace.TracerProvider)
+	if !ok {
+		lock.Lock()
+		p = provider
+		lock.Unlock()
+	}
+	return p.Tracer(Name).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it as failed with err if it is set.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject adds the trace context of ctx to the headers of an outgoing
+// request, so that the server can carry on with the trace.
+func Inject(ctx context.Context, h http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// Extract returns ctx along with the trace context of the headers of an
+// incoming request.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(h))
+}
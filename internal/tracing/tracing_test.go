@@ -0,0 +1,196 @@
+/*
+Copyright 2017-2026 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&TracingTestSuite{})
+
+type TracingTestSuite struct{}
+
+func (s *TracingTestSuite) TearDownTest(c *C) {
+	SetProvider(nil)
+}
+
+// collector is an in-memory OTLP collector, which keeps the spans exported
+// to it.
+type collector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	lock  sync.Mutex
+	spans []*tracepb.Span
+}
+
+func (col *collector) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	col.lock.Lock()
+	defer col.lock.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			col.spans = append(col.spans, ss.Spans...)
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func (col *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := &coltracepb.ExportTraceServiceRequest{}
+	if r.URL.Path != "/v1/traces" || proto.Unmarshal(body, req) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	col.Export(r.Context(), req)
+	data, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(data)
+}
+
+func (col *collector) Spans() []*tracepb.Span {
+	col.lock.Lock()
+	defer col.lock.Unlock()
+	return col.spans
+}
+
+// exportSpan exports a single span, with a manager attribute, through p.
+func exportSpan(c *C, p *sdktrace.TracerProvider) {
+	SetProvider(p)
+	_, span := Start(context.Background(), "Reloader.Reload", ManagerKey.String("prometheus"))
+	End(span, nil)
+	c.Assert(p.ForceFlush(context.Background()), IsNil)
+}
+
+func (s *TracingTestSuite) TestNew(c *C) {
+	_, err := New(Opts{})
+	c.Assert(err, ErrorMatches, "no tracing endpoint has been defined")
+	_, err = New(Opts{Endpoint: "localhost:4317", Protocol: "udp"})
+	c.Assert(err, ErrorMatches, "unknown tracing protocol udp")
+	c.Assert(IsValidProtocol(ProtocolGRPC), Equals, true)
+	c.Assert(IsValidProtocol(ProtocolHTTP), Equals, true)
+	c.Assert(IsValidProtocol("udp"), Equals, false)
+}
+
+func (s *TracingTestSuite) TestNewHTTP(c *C) {
+	col := &collector{}
+	server := httptest.NewServer(col)
+	defer server.Close()
+
+	p, err := New(Opts{Endpoint: server.URL, Protocol: ProtocolHTTP})
+	c.Assert(err, IsNil)
+	defer p.Shutdown(context.Background())
+	exportSpan(c, p)
+
+	c.Assert(col.Spans(), HasLen, 1)
+	c.Assert(col.Spans()[0].Name, Equals, "Reloader.Reload")
+	c.Assert(col.Spans()[0].Attributes[0].Key, Equals, "butler.manager")
+	c.Assert(col.Spans()[0].Attributes[0].Value.GetStringValue(), Equals, "prometheus")
+}
+
+func (s *TracingTestSuite) TestNewGRPC(c *C) {
+	col := &collector{}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	server := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(server, col)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	p, err := New(Opts{Endpoint: listener.Addr().String(), Insecure: true})
+	c.Assert(err, IsNil)
+	defer p.Shutdown(context.Background())
+	exportSpan(c, p)
+
+	c.Assert(col.Spans(), HasLen, 1)
+	c.Assert(col.Spans()[0].Name, Equals, "Reloader.Reload")
+}
+
+func (s *TracingTestSuite) TestStart(c *C) {
+	// Spans are dropped without a provider
+	_, span := Start(context.Background(), "ButlerConfig.Handler")
+	c.Assert(span.IsRecording(), Equals, false)
+
+	exporter := tracetest.NewInMemoryExporter()
+	SetProvider(NewProvider(sdktrace.WithSyncer(exporter)))
+	ctx, parent := Start(context.Background(), "ButlerConfig.RunCMHandler", CountKey.Int(3))
+	_, child := Start(ctx, "Reloader.Reload")
+	End(child, errors.New("connection refused"))
+	End(parent, nil)
+
+	spans := exporter.GetSpans()
+	c.Assert(spans, HasLen, 2)
+	c.Assert(spans[0].Name, Equals, "Reloader.Reload")
+	c.Assert(spans[0].Parent.SpanID(), Equals, spans[1].SpanContext.SpanID())
+	c.Assert(spans[0].Status.Code, Equals, codes.Error)
+	c.Assert(spans[0].Status.Description, Equals, "connection refused")
+	c.Assert(spans[0].Events[0].Name, Equals, "exception")
+	c.Assert(spans[1].Status.Code, Equals, codes.Unset)
+	c.Assert(spans[1].Attributes, HasLen, 1)
+	c.Assert(spans[1].Attributes[0], Equals, CountKey.Int(3))
+}
+
+func (s *TracingTestSuite) TestWithProvider(c *C) {
+	def, own := tracetest.NewInMemoryExporter(), tracetest.NewInMemoryExporter()
+	SetProvider(NewProvider(sdktrace.WithSyncer(def)))
+	ctx := WithProvider(context.Background(), NewProvider(sdktrace.WithSyncer(own)))
+	c.Assert(WithProvider(ctx, nil), Equals, ctx)
+
+	// The children of a span of ctx belong to the provider of ctx too
+	ctx, parent := Start(ctx, "ButlerConfig.RunCMHandler")
+	_, child := Start(ctx, "Reloader.Reload")
+	child.End()
+	parent.End()
+	_, span := Start(context.Background(), "ButlerConfig.Handler")
+	span.End()
+
+	c.Assert(own.GetSpans(), HasLen, 2)
+	c.Assert(def.GetSpans(), HasLen, 1)
+	c.Assert(def.GetSpans()[0].Name, Equals, "ButlerConfig.Handler")
+}
+
+func (s *TracingTestSuite) TestInject(c *C) {
+	exporter := tracetest.NewInMemoryExporter()
+	SetProvider(NewProvider(sdktrace.WithSyncer(exporter)))
+	ctx, span := Start(context.Background(), "Method.Get")
+	defer span.End()
+
+	h := http.Header{}
+	Inject(ctx, h)
+	c.Assert(h.Get("Traceparent"), Matches, "00-"+span.SpanContext().TraceID().String()+"-.*")
+	sc := trace.SpanContextFromContext(Extract(context.Background(), h))
+	c.Assert(sc.TraceID(), Equals, span.SpanContext().TraceID())
+
+	// There is nothing to propagate without a span
+	h = http.Header{}
+	Inject(context.Background(), h)
+	c.Assert(h, HasLen, 0)
+}